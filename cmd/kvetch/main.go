@@ -12,6 +12,7 @@ import (
 	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
 	services "github.com/syncromatics/kvetch/internal/sevices"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/syncromatics/go-kit/grpc"
 	"golang.org/x/sync/errgroup"
 )
@@ -27,7 +28,18 @@ func main() {
 		log.Fatal(err)
 	}
 
-	service := services.NewAPIService(kvstore)
+	prometheus.MustRegister(kvstore.Metrics())
+
+	var service apiv1.APIServer = services.NewAPIService(kvstore)
+
+	var authorizer *services.Authorizer
+	if settings.AuthConfigFile != "" {
+		authorizer, err = services.NewAuthorizer(settings.AuthConfigFile, kvstore)
+		if err != nil {
+			log.Fatal(err)
+		}
+		service = services.Intercept(service, authorizer.UnaryServerInterceptor(), authorizer.StreamServerInterceptor())
+	}
 
 	server := grpc.CreateServer(&grpc.Settings{
 		ServerName: "kvetch",
@@ -38,7 +50,19 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 	group, ctx := errgroup.WithContext(ctx)
 
-	group.Go(grpc.HostServer(ctx, server, settings.Port))
+	if settings.TLS != nil {
+		reloader, err := newCertificateReloader(settings.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		group.Go(watchFiles(ctx, "certificates", reloader.files, reloader.load))
+		group.Go(hostServer(ctx, server, settings.Port, reloader.config()))
+	} else {
+		group.Go(hostServer(ctx, server, settings.Port, nil))
+	}
+	if authorizer != nil {
+		group.Go(watchFiles(ctx, "auth config", authorizer.Files, authorizer.Load))
+	}
 	group.Go(grpc.HostMetrics(ctx, settings.PrometheusPort))
 
 	if !settings.KVStoreOptions.InMemory.GetValue() {
@@ -46,7 +70,10 @@ func main() {
 		group.Go(garbageCollector.Run(ctx))
 	}
 
-	eventChan := make(chan os.Signal)
+	expirySweeper := services.NewExpirySweeperService(kvstore, settings.ExpirySweepInterval)
+	group.Go(expirySweeper.Run(ctx))
+
+	eventChan := make(chan os.Signal, 1)
 	signal.Notify(eventChan, syscall.SIGINT, syscall.SIGTERM)
 
 	fmt.Println("kvetch started...")
@@ -63,4 +90,8 @@ func main() {
 	if err := group.Wait(); err != nil {
 		log.Fatal(err)
 	}
+
+	if err := kvstore.Close(); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	kvstore "github.com/syncromatics/kvetch/internal/datastore"
 )
@@ -16,7 +17,52 @@ type settings struct {
 	PrometheusPort            int
 	Datastore                 string
 	GarbageCollectionInterval time.Duration
+	ExpirySweepInterval       time.Duration
 	KVStoreOptions            *kvstore.KVStoreOptions
+	TLS                       *tlsSettings
+	AuthConfigFile            string
+}
+
+// getTLSSettings returns the tls settings, or nil when the server should not serve tls.
+func getTLSSettings() (*tlsSettings, error) {
+	allErrors := []string{}
+	settings := &tlsSettings{}
+	settings.CertFile, _ = os.LookupEnv("TLS_CERT_FILE")
+	settings.KeyFile, _ = os.LookupEnv("TLS_KEY_FILE")
+	settings.ClientCAFile, _ = os.LookupEnv("TLS_CLIENT_CA_FILE")
+	requireClientCertString, ok := os.LookupEnv("TLS_REQUIRE_CLIENT_CERT")
+	if ok {
+		requireClientCert, err := strconv.ParseBool(requireClientCertString)
+		if err != nil {
+			allErrors = append(allErrors, fmt.Sprintf("TLS_REQUIRE_CLIENT_CERT is not a valid bool '%s'", requireClientCertString))
+		}
+		settings.RequireClientCert = requireClientCert
+	}
+
+	if settings.CertFile == "" && settings.KeyFile == "" {
+		if settings.ClientCAFile != "" || settings.RequireClientCert {
+			allErrors = append(allErrors, "TLS_CERT_FILE and TLS_KEY_FILE are required for client certificates")
+		}
+		if len(allErrors) > 0 {
+			return nil, fmt.Errorf("Failed configuring TLS: %s", strings.Join(allErrors, ", "))
+		}
+		return nil, nil
+	}
+
+	if settings.CertFile == "" {
+		allErrors = append(allErrors, "TLS_CERT_FILE is required with TLS_KEY_FILE")
+	}
+	if settings.KeyFile == "" {
+		allErrors = append(allErrors, "TLS_KEY_FILE is required with TLS_CERT_FILE")
+	}
+	if settings.RequireClientCert && settings.ClientCAFile == "" {
+		allErrors = append(allErrors, "TLS_CLIENT_CA_FILE is required with TLS_REQUIRE_CLIENT_CERT")
+	}
+
+	if len(allErrors) > 0 {
+		return nil, fmt.Errorf("Failed configuring TLS: %s", strings.Join(allErrors, ", "))
+	}
+	return settings, nil
 }
 
 func getKVStoreOptions() (*kvstore.KVStoreOptions, error) {
@@ -97,6 +143,50 @@ func getKVStoreOptions() (*kvstore.KVStoreOptions, error) {
 		}
 	}
 
+	changeLogSizeString, ok := os.LookupEnv("CHANGE_LOG_SIZE")
+	if ok {
+		changeLogSize, err := strconv.ParseInt(changeLogSizeString, 10, 64)
+		if err != nil || changeLogSize <= 0 {
+			allErrors = append(allErrors, fmt.Sprintf("CHANGE_LOG_SIZE is not a valid positive int64 '%s'", changeLogSizeString))
+		} else {
+			kvStoreOptions.ChangeLogSize = &wrappers.Int64Value{Value: changeLogSize}
+		}
+	}
+
+	subscriberBufferSizeString, ok := os.LookupEnv("SUBSCRIBER_BUFFER_SIZE")
+	if ok {
+		subscriberBufferSize, err := strconv.ParseInt(subscriberBufferSizeString, 10, 32)
+		if err != nil || subscriberBufferSize <= 0 {
+			allErrors = append(allErrors, fmt.Sprintf("SUBSCRIBER_BUFFER_SIZE is not a valid positive int32 '%s'", subscriberBufferSizeString))
+		} else {
+			kvStoreOptions.SubscriberBufferSize = &wrappers.Int32Value{Value: int32(subscriberBufferSize)}
+		}
+	}
+
+	historyVersionsString, ok := os.LookupEnv("HISTORY_VERSIONS")
+	if ok {
+		historyVersions, err := strconv.ParseInt(historyVersionsString, 10, 32)
+		if err != nil || historyVersions <= 0 {
+			allErrors = append(allErrors, fmt.Sprintf("HISTORY_VERSIONS is not a valid positive int32 '%s'", historyVersionsString))
+		} else {
+			kvStoreOptions.HistoryVersions = &wrappers.Int32Value{Value: int32(historyVersions)}
+		}
+	}
+
+	historyAgeString, ok := os.LookupEnv("HISTORY_AGE")
+	if ok {
+		historyAge, err := time.ParseDuration(historyAgeString)
+		if err != nil || historyAge <= 0 {
+			allErrors = append(allErrors, fmt.Sprintf("HISTORY_AGE is not a valid positive time.Duration '%s'", historyAgeString))
+		} else {
+			kvStoreOptions.HistoryAge = ptypes.DurationProto(historyAge)
+		}
+		if kvStoreOptions.HistoryVersions == nil || kvStoreOptions.HistoryVersions.Value <= 1 {
+			// only the newest version is retained, so there is no history for the age to limit
+			allErrors = append(allErrors, "HISTORY_AGE requires HISTORY_VERSIONS greater than 1")
+		}
+	}
+
 	if len(allErrors) > 0 {
 		return nil, fmt.Errorf("Failed configuring KVStore: %s", strings.Join(allErrors, ", "))
 	}
@@ -135,6 +225,17 @@ func getSettingsFromEnv() (*settings, error) {
 		allErrors = append(allErrors, err.Error())
 	}
 
+	tlsOptions, err := getTLSSettings()
+	if err != nil {
+		allErrors = append(allErrors, err.Error())
+	}
+
+	authConfigFile, _ := os.LookupEnv("AUTH_CONFIG_FILE")
+	if authConfigFile != "" && tlsOptions == nil && err == nil {
+		// bearer tokens would be sent in the clear
+		allErrors = append(allErrors, "TLS_CERT_FILE and TLS_KEY_FILE are required with AUTH_CONFIG_FILE")
+	}
+
 	datastore, ok := os.LookupEnv("DATASTORE")
 	if !ok && !kvStoreOptions.InMemory.Value {
 		allErrors = append(allErrors, "DATASTORE")
@@ -149,6 +250,15 @@ func getSettingsFromEnv() (*settings, error) {
 		}
 	}
 
+	expirySweepInterval := 1 * time.Second
+	sweep, ok := os.LookupEnv("EXPIRY_SWEEP_INTERVAL")
+	if ok {
+		expirySweepInterval, err = time.ParseDuration(sweep)
+		if err != nil || expirySweepInterval <= 0 {
+			allErrors = append(allErrors, fmt.Sprintf("EXPIRY_SWEEP_INTERVAL is not a valid positive time.Duration '%s'", sweep))
+		}
+	}
+
 	if len(allErrors) > 0 {
 		return nil, fmt.Errorf("Missing required environment variables: %s", strings.Join(allErrors, ", "))
 	}
@@ -158,6 +268,9 @@ func getSettingsFromEnv() (*settings, error) {
 		PrometheusPort:            prometheusPortInt,
 		Datastore:                 datastore,
 		GarbageCollectionInterval: duration,
+		ExpirySweepInterval:       expirySweepInterval,
 		KVStoreOptions:            kvStoreOptions,
+		TLS:                       tlsOptions,
+		AuthConfigFile:            authConfigFile,
 	}, nil
 }
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/assert"
+)
+
+func Test_AuthRequiresTLS(t *testing.T) {
+	t.Setenv("DATASTORE", t.TempDir())
+	t.Setenv("AUTH_CONFIG_FILE", "auth.json")
+
+	_, err := getSettingsFromEnv()
+	assert.ErrorContains(t, err, "TLS_CERT_FILE and TLS_KEY_FILE are required with AUTH_CONFIG_FILE")
+
+	t.Setenv("TLS_CERT_FILE", "cert.pem")
+	t.Setenv("TLS_KEY_FILE", "key.pem")
+	settings, err := getSettingsFromEnv()
+	assert.NilError(t, err)
+	assert.Equal(t, settings.AuthConfigFile, "auth.json")
+}
+
+func Test_ExpirySweepInterval(t *testing.T) {
+	t.Setenv("DATASTORE", t.TempDir())
+
+	for _, interval := range []string{"0s", "-1s", "soon"} {
+		t.Setenv("EXPIRY_SWEEP_INTERVAL", interval)
+		_, err := getSettingsFromEnv()
+		assert.ErrorContains(t, err, "EXPIRY_SWEEP_INTERVAL is not a valid positive time.Duration")
+	}
+
+	t.Setenv("EXPIRY_SWEEP_INTERVAL", "5s")
+	settings, err := getSettingsFromEnv()
+	assert.NilError(t, err)
+	assert.Equal(t, settings.ExpirySweepInterval, 5*time.Second)
+}
+
+func Test_HistoryAgeRequiresVersions(t *testing.T) {
+	t.Setenv("DATASTORE", t.TempDir())
+	t.Setenv("HISTORY_AGE", "1h")
+
+	_, err := getSettingsFromEnv()
+	assert.ErrorContains(t, err, "HISTORY_AGE requires HISTORY_VERSIONS greater than 1")
+
+	t.Setenv("HISTORY_VERSIONS", "1")
+	_, err = getSettingsFromEnv()
+	assert.ErrorContains(t, err, "HISTORY_AGE requires HISTORY_VERSIONS greater than 1")
+
+	t.Setenv("HISTORY_VERSIONS", "5")
+	settings, err := getSettingsFromEnv()
+	assert.NilError(t, err)
+	assert.Equal(t, settings.KVStoreOptions.HistoryAge.Seconds, int64(3600))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/syncromatics/go-kit/grpc"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// tlsSettings are the files the grpc server serves TLS with.
+type tlsSettings struct {
+	CertFile          string
+	KeyFile           string
+	ClientCAFile      string
+	RequireClientCert bool
+}
+
+// certificateReloader serves the certificate and client certificate authorities from their files,
+// and loads them again whenever the files change.
+type certificateReloader struct {
+	settings *tlsSettings
+
+	mtx         sync.RWMutex
+	certificate *tls.Certificate
+	clientCAs   *x509.CertPool
+}
+
+func newCertificateReloader(settings *tlsSettings) (*certificateReloader, error) {
+	reloader := &certificateReloader{
+		settings: settings,
+	}
+	err := reloader.load()
+	if err != nil {
+		return nil, err
+	}
+	return reloader, nil
+}
+
+// files are the files the certificates are loaded from.
+func (r *certificateReloader) files() []string {
+	files := []string{r.settings.CertFile, r.settings.KeyFile}
+	if r.settings.ClientCAFile != "" {
+		files = append(files, r.settings.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certificateReloader) load() error {
+	certificate, err := tls.LoadX509KeyPair(r.settings.CertFile, r.settings.KeyFile)
+	if err != nil {
+		return errors.Wrap(err, "failed to load certificate")
+	}
+
+	var clientCAs *x509.CertPool
+	if r.settings.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.settings.ClientCAFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read client ca")
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return errors.Errorf("no certificates found in client ca %s", r.settings.ClientCAFile)
+		}
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	r.certificate = &certificate
+	r.clientCAs = clientCAs
+	return nil
+}
+
+// config returns a tls config that uses the certificates loaded when each connection is made.
+func (r *certificateReloader) config() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2"},
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mtx.RLock()
+			defer r.mtx.RUnlock()
+
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				NextProtos:   []string{"h2"},
+				Certificates: []tls.Certificate{*r.certificate},
+			}
+			if r.clientCAs != nil {
+				config.ClientCAs = r.clientCAs
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+				if r.settings.RequireClientCert {
+					config.ClientAuth = tls.RequireAndVerifyClientCert
+				}
+			}
+			return config, nil
+		},
+	}
+}
+
+// hostServer hosts the grpc server with go-kit, serving TLS with the config when it is set. go-kit
+// only listens without TLS, so with a config the server is served on a TLS listener instead and
+// stopped gracefully like go-kit does once the context is done.
+func hostServer(ctx context.Context, server *grpcgo.Server, port int, config *tls.Config) func() error {
+	if config == nil {
+		return grpc.HostServer(ctx, server, port)
+	}
+
+	return func() error {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+		if err != nil {
+			return errors.Wrap(err, "failed to listen")
+		}
+
+		served := make(chan struct{})
+		defer close(served)
+		go func() {
+			select {
+			case <-ctx.Done():
+				time.AfterFunc(10*time.Second, server.Stop)
+				server.GracefulStop()
+			case <-served:
+			}
+		}()
+
+		return serveTLS(server, lis, config)
+	}
+}
+
+// serveTLS serves the grpc server on the listener with TLS.
+func serveTLS(server *grpcgo.Server, lis net.Listener, config *tls.Config) error {
+	reflection.Register(server)
+
+	err := server.Serve(tls.NewListener(lis, config))
+	if err != nil {
+		return errors.Wrap(err, "failed to serve")
+	}
+	return nil
+}
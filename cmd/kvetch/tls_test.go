@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/syncromatics/kvetch/internal/testcerts"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthv1 "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"gotest.tools/assert"
+)
+
+// startTLSServer serves a grpc server with the health service on a local port with the TLS
+// settings, and returns its address.
+func startTLSServer(t *testing.T, reloader *certificateReloader) string {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	server := grpc.NewServer()
+	healthv1.RegisterHealthServer(server, health.NewServer())
+	go serveTLS(server, lis, reloader.config())
+	t.Cleanup(server.Stop)
+
+	return lis.Addr().String()
+}
+
+// check calls the health service with the client TLS config, and returns the serial number of the
+// certificate the server presented.
+func check(address string, config *tls.Config) (*big.Int, error) {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(credentials.NewTLS(config)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	p := &peer.Peer{}
+	_, err = healthv1.NewHealthClient(conn).Check(ctx, &healthv1.HealthCheckRequest{}, grpc.Peer(p))
+	if err != nil {
+		return nil, err
+	}
+	info := p.AuthInfo.(credentials.TLSInfo)
+	return info.State.PeerCertificates[0].SerialNumber, nil
+}
+
+func Test_CertificateReload(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_CertificateReload")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	authority, err := testcerts.NewAuthority("ca")
+	assert.NilError(t, err)
+	first, err := authority.Server()
+	assert.NilError(t, err)
+	second, err := authority.Server()
+	assert.NilError(t, err)
+
+	settings := &tlsSettings{
+		CertFile: filepath.Join(tmpDir, "cert.pem"),
+		KeyFile:  filepath.Join(tmpDir, "key.pem"),
+	}
+	err = first.Write(settings.CertFile, settings.KeyFile)
+	assert.NilError(t, err)
+
+	reloader, err := newCertificateReloader(settings)
+	assert.NilError(t, err)
+	address := startTLSServer(t, reloader)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watched := make(chan error, 1)
+	go func() {
+		watched <- watchFiles(ctx, "certificates", reloader.files, reloader.load)()
+	}()
+	// give the watcher time to start watching the directory
+	time.Sleep(100 * time.Millisecond)
+
+	client := &tls.Config{
+		RootCAs:    authority.Pool(),
+		ServerName: "localhost",
+	}
+	serial, err := check(address, client)
+	assert.NilError(t, err)
+	assert.Equal(t, serial.Cmp(first.Serial), 0)
+
+	// new connections get the new certificate once it is written
+	err = second.Write(settings.CertFile, settings.KeyFile)
+	assert.NilError(t, err)
+	now := time.Now()
+	for {
+		serial, err = check(address, client)
+		assert.NilError(t, err)
+		if serial.Cmp(second.Serial) == 0 {
+			break
+		}
+		if time.Now().Sub(now) > 10*time.Second {
+			t.Fatal("timed out waiting for the certificate to reload")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	// a certificate that fails to load keeps the one loaded before
+	err = ioutil.WriteFile(settings.KeyFile, []byte("broken"), 0600)
+	assert.NilError(t, err)
+	time.Sleep(500 * time.Millisecond)
+
+	assert.ErrorContains(t, reloader.load(), "failed to load certificate")
+	serial, err = check(address, client)
+	assert.NilError(t, err)
+	assert.Equal(t, serial.Cmp(second.Serial), 0)
+
+	cancel()
+	assert.NilError(t, <-watched)
+}
+
+func Test_ClientCertificates(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_ClientCertificates")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	authority, err := testcerts.NewAuthority("ca")
+	assert.NilError(t, err)
+	other, err := testcerts.NewAuthority("other")
+	assert.NilError(t, err)
+
+	server, err := authority.Server()
+	assert.NilError(t, err)
+	certFile, keyFile := filepath.Join(tmpDir, "cert.pem"), filepath.Join(tmpDir, "key.pem")
+	err = server.Write(certFile, keyFile)
+	assert.NilError(t, err)
+	clientCAFile := filepath.Join(tmpDir, "ca.pem")
+	err = ioutil.WriteFile(clientCAFile, authority.PEM(), 0600)
+	assert.NilError(t, err)
+
+	certificate := func(authority *testcerts.Authority) []tls.Certificate {
+		client, err := authority.Client("client")
+		assert.NilError(t, err)
+		certificate, err := tls.X509KeyPair(client.Cert, client.Key)
+		assert.NilError(t, err)
+		return []tls.Certificate{certificate}
+	}
+	trusted := certificate(authority)
+	untrusted := certificate(other)
+
+	tests := []struct {
+		name         string
+		require      bool
+		certificates []tls.Certificate
+		ok           bool
+	}{
+		{"required without certificate", true, nil, false},
+		{"required with trusted certificate", true, trusted, true},
+		{"required with untrusted certificate", true, untrusted, false},
+		{"optional without certificate", false, nil, true},
+		{"optional with trusted certificate", false, trusted, true},
+		{"optional with untrusted certificate", false, untrusted, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reloader, err := newCertificateReloader(&tlsSettings{
+				CertFile:          certFile,
+				KeyFile:           keyFile,
+				ClientCAFile:      clientCAFile,
+				RequireClientCert: test.require,
+			})
+			assert.NilError(t, err)
+			address := startTLSServer(t, reloader)
+
+			_, err = check(address, &tls.Config{
+				RootCAs:    authority.Pool(),
+				ServerName: "localhost",
+				// the certificate is presented even when the server does not ask for its authority
+				GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					if len(test.certificates) == 0 {
+						return &tls.Certificate{}, nil
+					}
+					return &test.certificates[0], nil
+				},
+			})
+			if test.ok {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, err != nil)
+			}
+		})
+	}
+}
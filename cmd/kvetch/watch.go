@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+)
+
+// versions identifies the versions of the files by their modification times and sizes.
+func versions(files []string) string {
+	versions := ""
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			versions += "missing;"
+			continue
+		}
+		versions += fmt.Sprintf("%d/%d;", info.ModTime().UnixNano(), info.Size())
+	}
+	return versions
+}
+
+// watchFiles loads the named files again when they change. The directories they are in are
+// watched, so replacing them with a rename or a symlink swap is noticed too. Files that fail to
+// load, such as while only some of them have been written, are logged and the previous ones are
+// kept.
+func watchFiles(ctx context.Context, name string, files func() []string, load func() error) func() error {
+	return func() error {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %s watcher", name)
+		}
+		defer watcher.Close()
+
+		add := func() error {
+			for _, file := range files() {
+				dir := filepath.Dir(file)
+				err := watcher.Add(dir)
+				if err != nil {
+					return errors.Wrapf(err, "failed to watch %s", dir)
+				}
+			}
+			return nil
+		}
+		err = add()
+		if err != nil {
+			return err
+		}
+
+		// a change is usually several events in a row, so they are loaded once things settle, and
+		// only when the files are different from the last time they were loaded
+		loaded := versions(files())
+		var reload <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case <-watcher.Events:
+				reload = time.After(100 * time.Millisecond)
+
+			case <-reload:
+				reload = nil
+				current := versions(files())
+				if current == loaded {
+					continue
+				}
+				loaded = current
+				err := load()
+				if err != nil {
+					log.Printf("failed to reload %s: %v", name, err)
+					continue
+				}
+				// the files loaded may have changed too
+				loaded = versions(files())
+				err = add()
+				if err != nil {
+					return err
+				}
+				fmt.Printf("reloaded %s\n", name)
+
+			case err := <-watcher.Errors:
+				return errors.Wrapf(err, "failed to watch %s", name)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	services "github.com/syncromatics/kvetch/internal/sevices"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+func Test_AuthConfigReload(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_AuthConfigReload")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "auth.json")
+	writeConfig := func(token string) {
+		config := fmt.Sprintf(`{
+			"tokens": [{ "token": %q, "subject": "reader", "roles": ["reader"] }],
+			"roles": { "reader": [{ "prefix": "app/", "permissions": ["read"] }] }
+		}`, token)
+		err := ioutil.WriteFile(path, []byte(config), 0600)
+		assert.NilError(t, err)
+	}
+	writeConfig("first")
+
+	authorizer, err := services.NewAuthorizer(path, nil)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watched := make(chan error, 1)
+	go func() {
+		watched <- watchFiles(ctx, "auth config", authorizer.Files, authorizer.Load)()
+	}()
+	// give the watcher time to start watching the directory
+	time.Sleep(100 * time.Millisecond)
+
+	authorize := func(token string) codes.Code {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+		_, err := authorizer.UnaryServerInterceptor()(ctx, &apiv1.GetValuesRequest{
+			Requests: []*apiv1.GetValuesRequest_GetValue{
+				&apiv1.GetValuesRequest_GetValue{Key: "app/a"},
+			},
+		}, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return status.Code(err)
+	}
+	assert.Equal(t, authorize("first"), codes.OK)
+
+	// requests are authorized with the new config once it is written
+	writeConfig("second")
+	now := time.Now()
+	for authorize("second") != codes.OK {
+		if time.Now().Sub(now) > 10*time.Second {
+			t.Fatal("timed out waiting for the auth config to reload")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, authorize("first"), codes.Unauthenticated)
+
+	// a config that fails to load keeps the one loaded before
+	err = ioutil.WriteFile(path, []byte("{ broken"), 0600)
+	assert.NilError(t, err)
+	time.Sleep(500 * time.Millisecond)
+
+	assert.ErrorContains(t, authorizer.Load(), "failed to parse auth config")
+	assert.Equal(t, authorize("second"), codes.OK)
+
+	cancel()
+	assert.NilError(t, <-watched)
+}
@@ -4,8 +4,10 @@ go 1.14
 
 require (
 	github.com/dgraph-io/badger/v2 v2.2007.2
+	github.com/fsnotify/fsnotify v1.4.7
 	github.com/golang/protobuf v1.4.2
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v0.9.3
 	github.com/spf13/cobra v1.0.0
 	github.com/spf13/viper v1.7.1
 	github.com/syncromatics/go-kit v1.5.1
@@ -2,9 +2,12 @@ package kvetchctl
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 
 	"github.com/pkg/errors"
@@ -14,6 +17,7 @@ import (
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 var (
@@ -44,6 +48,12 @@ func bindCommonFlags(command *cobra.Command) {
 	command.Flags().StringP("endpoint", "e", "", "Kvetch instance to connect to (required)")
 	command.Flags().StringP("output", "o", "simple", "Set the output format (simple, json)")
 	command.Flags().StringP("value-type", "t", "string", "Set the type of value in the output (string, bytes, json)")
+	command.Flags().Bool("tls", false, "Connect with TLS, verifying the server certificate with the system certificate authorities (optional)")
+	command.Flags().String("cacert", "", "Verify the server certificate with the certificate authorities in the file (optional)")
+	command.Flags().String("cert", "", "Present the client certificate in the file to the server (optional)")
+	command.Flags().String("key", "", "Key file for the client certificate (optional)")
+	command.Flags().Bool("insecure", false, "Connect with TLS without verifying the server certificate (optional)")
+	command.Flags().String("token", "", "Authenticate with the bearer token or json web token, requires TLS (optional)")
 }
 
 // Execute executes the command line interface
@@ -89,7 +99,15 @@ func setupClient(command *cobra.Command, args []string) error {
 	if endpoint == "" {
 		return errors.New(`required flag "endpoint" not set`)
 	}
-	conn, err := grpc.Dial(endpoint, grpc.WithInsecure())
+	transport, err := transportOption()
+	if err != nil {
+		return err
+	}
+	options := []grpc.DialOption{transport}
+	if token := viper.GetString("token"); token != "" {
+		options = append(options, grpc.WithPerRPCCredentials(bearerToken(token)))
+	}
+	conn, err := grpc.Dial(endpoint, options...)
 	if err != nil {
 		return errors.Wrap(err, "failed to connect to endpoint")
 	}
@@ -98,6 +116,68 @@ func setupClient(command *cobra.Command, args []string) error {
 	return nil
 }
 
+// transportOption connects with TLS when any of the TLS flags are set, and without it otherwise.
+func transportOption() (grpc.DialOption, error) {
+	config, err := tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return grpc.WithInsecure(), nil
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(config)), nil
+}
+
+// tlsConfig returns the TLS config from the TLS flags, or nil when none of them are set.
+func tlsConfig() (*tls.Config, error) {
+	caFile := viper.GetString("cacert")
+	certFile := viper.GetString("cert")
+	keyFile := viper.GetString("key")
+	insecure := viper.GetBool("insecure")
+	if !viper.GetBool("tls") && caFile == "" && certFile == "" && keyFile == "" && !insecure {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		InsecureSkipVerify: insecure,
+	}
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read ca certificate")
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, errors.New(`flags "cert" and "key" must be set together`)
+		}
+		certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load client certificate")
+		}
+		config.Certificates = []tls.Certificate{certificate}
+	}
+	return config, nil
+}
+
+// bearerToken sends the token in the authorization metadata of every request.
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{
+		"authorization": "Bearer " + string(t),
+	}, nil
+}
+
+// RequireTransportSecurity keeps the token from being sent in the clear.
+func (t bearerToken) RequireTransportSecurity() bool {
+	return true
+}
+
 func writeOutput(ctx context.Context, messages []*apiv1.KeyValue, output *os.File) error {
 	outputFormat := viper.GetString("output")
 	valueType := viper.GetString("value-type")
@@ -149,3 +229,80 @@ func writeOutput(ctx context.Context, messages []*apiv1.KeyValue, output *os.Fil
 	}
 	return nil
 }
+
+func writeKeys(ctx context.Context, messages []*apiv1.KeyValue, output *os.File) error {
+	outputFormat := viper.GetString("output")
+	for _, message := range messages {
+		select {
+		case <-ctx.Done():
+			return io.ErrClosedPipe
+		default:
+		}
+
+		switch outputFormat {
+		case "simple":
+			output.WriteString(message.Key)
+			output.WriteString("\n")
+			break
+		case "json":
+			bytes, err := json.Marshal(message.Key)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal key")
+			}
+
+			output.Write(bytes)
+			output.WriteString("\n")
+			break
+		default:
+			return errors.New("not implemented")
+		}
+	}
+	return nil
+}
+
+func writeEvents(ctx context.Context, events []*apiv1.Event, output *os.File) error {
+	outputFormat := viper.GetString("output")
+	for _, event := range events {
+		if event.Type == apiv1.Event_TYPE_PUT {
+			err := writeOutput(ctx, []*apiv1.KeyValue{event.Kv}, output)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return io.ErrClosedPipe
+		default:
+		}
+
+		switch outputFormat {
+		case "simple":
+			output.WriteString(event.Kv.Key)
+			switch event.Type {
+			case apiv1.Event_TYPE_DELETE:
+				output.WriteString(" (deleted)\n")
+			case apiv1.Event_TYPE_EXPIRE:
+				output.WriteString(" (expired)\n")
+			default:
+				return errors.New("not implemented")
+			}
+			break
+		case "json":
+			bytes, err := json.Marshal(map[string]interface{}{
+				event.Kv.Key: nil,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal value")
+			}
+
+			output.Write(bytes)
+			output.WriteString("\n")
+			break
+		default:
+			return errors.New("not implemented")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package kvetchctl
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/syncromatics/kvetch/internal/testcerts"
+
+	"github.com/spf13/viper"
+	"gotest.tools/assert"
+)
+
+// handshake connects to the TLS listener with the client config and reports whether the handshake
+// succeeded on both ends.
+func handshake(lis net.Listener, config *tls.Config) error {
+	accepted := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			accepted <- err
+			return
+		}
+		defer conn.Close()
+		accepted <- conn.(*tls.Conn).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), config)
+	if err != nil {
+		<-accepted
+		return err
+	}
+	defer conn.Close()
+	return <-accepted
+}
+
+func Test_TLSFlags(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_TLSFlags")
+	assert.NilError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	authority, err := testcerts.NewAuthority("ca")
+	assert.NilError(t, err)
+	server, err := authority.Server()
+	assert.NilError(t, err)
+	client, err := authority.Client("client")
+	assert.NilError(t, err)
+
+	caFile := filepath.Join(tmpDir, "ca.pem")
+	err = ioutil.WriteFile(caFile, authority.PEM(), 0600)
+	assert.NilError(t, err)
+	certFile, keyFile := filepath.Join(tmpDir, "cert.pem"), filepath.Join(tmpDir, "key.pem")
+	err = client.Write(certFile, keyFile)
+	assert.NilError(t, err)
+	emptyFile := filepath.Join(tmpDir, "empty.pem")
+	err = ioutil.WriteFile(emptyFile, []byte{}, 0600)
+	assert.NilError(t, err)
+
+	// the server requires a client certificate from the authority
+	certificate, err := tls.X509KeyPair(server.Cert, server.Key)
+	assert.NilError(t, err)
+	lis, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		ClientCAs:    authority.Pool(),
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	})
+	assert.NilError(t, err)
+	defer lis.Close()
+
+	tests := []struct {
+		name      string
+		flags     map[string]interface{}
+		plaintext bool
+		err       string
+		connects  bool
+	}{
+		{"no flags", map[string]interface{}{}, true, "", false},
+		{"tls with system roots", map[string]interface{}{"tls": true}, false, "", false},
+		{"ca", map[string]interface{}{"cacert": caFile}, false, "", true},
+		{"ca with client certificate", map[string]interface{}{"cacert": caFile, "cert": certFile, "key": keyFile}, false, "", true},
+		{"insecure", map[string]interface{}{"insecure": true}, false, "", true},
+		{"insecure with client certificate", map[string]interface{}{"insecure": true, "cert": certFile, "key": keyFile}, false, "", true},
+		{"client certificate without ca", map[string]interface{}{"cert": certFile, "key": keyFile}, false, "", false},
+		{"cert without key", map[string]interface{}{"cacert": caFile, "cert": certFile}, false, `flags "cert" and "key" must be set together`, false},
+		{"key without cert", map[string]interface{}{"cacert": caFile, "key": keyFile}, false, `flags "cert" and "key" must be set together`, false},
+		{"missing ca", map[string]interface{}{"cacert": filepath.Join(tmpDir, "missing.pem")}, false, "failed to read ca certificate", false},
+		{"empty ca", map[string]interface{}{"cacert": emptyFile}, false, "no certificates found", false},
+		{"mismatched key", map[string]interface{}{"cacert": caFile, "cert": certFile, "key": caFile}, false, "failed to load client certificate", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			viper.Reset()
+			defer viper.Reset()
+			for flag, value := range test.flags {
+				viper.Set(flag, value)
+			}
+
+			config, err := tlsConfig()
+			if test.err != "" {
+				assert.ErrorContains(t, err, test.err)
+				return
+			}
+			assert.NilError(t, err)
+			if test.plaintext {
+				assert.Assert(t, config == nil)
+				return
+			}
+			assert.Assert(t, config != nil)
+
+			config.ServerName = "localhost"
+			err = handshake(lis, config)
+			if test.connects {
+				assert.NilError(t, err)
+			} else {
+				assert.Assert(t, err != nil)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package kvetchctl
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/syncromatics/go-kit/cmd"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	deleteCmd = &cobra.Command{
+		Use:     "delete [flags] [keys/prefix]",
+		Short:   "Delete values by key or prefix",
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: setupClient,
+		RunE: func(_ *cobra.Command, args []string) error {
+			isPrefix := viper.GetBool("prefix")
+			group := cmd.NewProcessGroup(context.Background())
+			group.Go(func() error {
+				logger := log.With(
+					"keys", args,
+					"isPrefix", isPrefix,
+				)
+				logger.Info("deleting keys")
+				requests := []*apiv1.DeleteValuesRequest_DeleteValue{}
+				for _, key := range args {
+					requests = append(requests, &apiv1.DeleteValuesRequest_DeleteValue{
+						Key:      key,
+						IsPrefix: isPrefix,
+					})
+				}
+				response, err := client.DeleteValues(group.Context(), &apiv1.DeleteValuesRequest{
+					Requests: requests,
+				})
+				s, ok := status.FromError(err)
+				if ok && s.Code() == codes.Canceled {
+					return nil
+				}
+				if err != nil {
+					logger.Error(err, "failed to delete keys")
+					return errors.Wrap(err, fmt.Sprintf("failed to delete value(s) by key %s", args))
+				}
+				fmt.Printf("deleted %d key(s)\n", response.DeletedCount)
+				return nil
+			})
+
+			return group.Wait()
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(deleteCmd)
+	deleteCmd.Flags().BoolP("prefix", "p", false, "Treat the given keys as prefixes")
+	bindCommonFlags(deleteCmd)
+}
@@ -3,8 +3,12 @@ package kvetchctl
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -22,6 +26,10 @@ var (
 		PreRunE: setupClient,
 		RunE: func(_ *cobra.Command, args []string) error {
 			isPrefix := viper.GetBool("prefix")
+			at, err := parseAt(viper.GetString("at"))
+			if err != nil {
+				return err
+			}
 			group := cmd.NewProcessGroup(context.Background())
 			for _, k := range args {
 				key := k
@@ -31,14 +39,21 @@ var (
 						"isPrefix", isPrefix,
 					)
 					logger.Info("getting key")
-					response, err := client.GetValues(group.Context(), &apiv1.GetValuesRequest{
-						Requests: []*apiv1.GetValuesRequest_GetValue{
-							{
-								Key:      key,
-								IsPrefix: isPrefix,
-							},
-						},
-					})
+					request := &apiv1.GetValuesRequest_GetValue{
+						Key:        key,
+						IsPrefix:   isPrefix,
+						Limit:      viper.GetInt64("limit"),
+						StartAfter: viper.GetString("start-after"),
+						KeysOnly:   viper.GetBool("keys-only"),
+						RangeEnd:   viper.GetString("range-end"),
+						Reverse:    viper.GetBool("reverse"),
+					}
+					var err error
+					if at == nil && (isPrefix || request.RangeEnd != "") {
+						err = scanValues(group.Context(), request)
+					} else {
+						err = getValues(group.Context(), request, at)
+					}
 					s, ok := status.FromError(err)
 					if ok && s.Code() == codes.Canceled {
 						return nil
@@ -47,7 +62,7 @@ var (
 						logger.Error(err, "failed to get key")
 						return errors.Wrap(err, fmt.Sprintf("failed to get value(s) by key %s", key))
 					}
-					return writeOutput(group.Context(), response.Messages, os.Stdout)
+					return nil
 				})
 			}
 
@@ -59,5 +74,92 @@ var (
 func init() {
 	RootCmd.AddCommand(getCmd)
 	getCmd.Flags().BoolP("prefix", "p", false, "Treat the given keys as prefixes")
+	getCmd.Flags().Int64("limit", 0, "Limit the number of keys returned for each prefix or range (optional)")
+	getCmd.Flags().String("start-after", "", "Only return the keys of each prefix or range after the given key (optional)")
+	getCmd.Flags().Bool("keys-only", false, "Only return keys without their values (optional)")
+	getCmd.Flags().String("range-end", "", "Return every key from the given key up to but not including this one (optional)")
+	getCmd.Flags().Bool("reverse", false, "Return the keys of each prefix or range from last to first (optional)")
+	getCmd.Flags().String("at", "", "Return the values as they were at a revision, a RFC3339 time or a duration ago, when the server retains history with HISTORY_VERSIONS (optional)")
 	bindCommonFlags(getCmd)
 }
+
+// getValues gets the values for a request in a single message, as they were at the point in time
+// when it is set.
+func getValues(ctx context.Context, request *apiv1.GetValuesRequest_GetValue, at *apiv1.GetValuesRequest) error {
+	getRequest := &apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{request},
+	}
+	if at != nil {
+		getRequest.Revision = at.Revision
+		getRequest.AsOf = at.AsOf
+	}
+	response, err := client.GetValues(ctx, getRequest)
+	if err != nil {
+		return err
+	}
+	return writeValues(ctx, response.Messages, request.KeysOnly)
+}
+
+// scanValues streams the values for a prefix a page at a time so large prefixes are not limited by
+// the message size.
+func scanValues(ctx context.Context, request *apiv1.GetValuesRequest_GetValue) error {
+	stream, err := client.ScanValues(ctx, &apiv1.ScanValuesRequest{
+		Request: request,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		response, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		err = writeValues(ctx, response.Messages, request.KeysOnly)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func writeValues(ctx context.Context, messages []*apiv1.KeyValue, keysOnly bool) error {
+	if keysOnly {
+		return writeKeys(ctx, messages, os.Stdout)
+	}
+	return writeOutput(ctx, messages, os.Stdout)
+}
+
+// parseAt parses a point in time given as a revision, a RFC3339 time or a duration before now into
+// the fields of a get request that read at it. It returns nil for an empty point in time.
+func parseAt(at string) (*apiv1.GetValuesRequest, error) {
+	if at == "" {
+		return nil, nil
+	}
+
+	revision, err := strconv.ParseUint(at, 10, 64)
+	if err == nil {
+		return &apiv1.GetValuesRequest{
+			Revision: revision,
+		}, nil
+	}
+
+	asOf, err := time.Parse(time.RFC3339, at)
+	if err != nil {
+		ago, durationErr := time.ParseDuration(at)
+		if durationErr != nil {
+			return nil, fmt.Errorf("at %s is not a revision, time or duration", at)
+		}
+		asOf = time.Now().Add(-ago)
+	}
+
+	timestamp, err := ptypes.TimestampProto(asOf)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to serialize at")
+	}
+	return &apiv1.GetValuesRequest{
+		AsOf: timestamp,
+	}, nil
+}
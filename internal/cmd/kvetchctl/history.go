@@ -0,0 +1,137 @@
+package kvetchctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/syncromatics/go-kit/cmd"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	historyCmd = &cobra.Command{
+		Use:   "history [flags] [key]",
+		Short: "Show the previous values of a key",
+		Long: `Prints the revision, write time and value of each version of the key that kvetch has retained, newest first.
+
+How many versions are retained is configured on the kvetch instance.`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: setupClient,
+		RunE: func(_ *cobra.Command, args []string) error {
+			key := args[0]
+			group := cmd.NewProcessGroup(context.Background())
+			group.Go(func() error {
+				logger := log.With(
+					"key", key,
+				)
+				logger.Info("getting history")
+				response, err := client.GetHistory(group.Context(), &apiv1.GetHistoryRequest{
+					Key:   key,
+					Limit: viper.GetInt64("limit"),
+				})
+				s, ok := status.FromError(err)
+				if ok && s.Code() == codes.Canceled {
+					return nil
+				}
+				if err != nil {
+					logger.Error(err, "failed to get history")
+					return errors.Wrap(err, fmt.Sprintf("failed to get history of key %s", key))
+				}
+				if len(response.Versions) == 0 {
+					return errors.New("no versions found")
+				}
+				return writeHistory(group.Context(), response.Versions, os.Stdout)
+			})
+
+			return group.Wait()
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().Int64("limit", 0, "Show at most this many versions (optional)")
+	bindCommonFlags(historyCmd)
+}
+
+func writeHistory(ctx context.Context, versions []*apiv1.KeyVersion, output *os.File) error {
+	outputFormat := viper.GetString("output")
+	valueType := viper.GetString("value-type")
+	for _, version := range versions {
+		select {
+		case <-ctx.Done():
+			return io.ErrClosedPipe
+		default:
+		}
+
+		var modTime *time.Time
+		if version.ModTime != nil {
+			t, err := ptypes.Timestamp(version.ModTime)
+			if err != nil {
+				return errors.Wrap(err, "failed to deserialize mod time")
+			}
+			modTime = &t
+		}
+
+		switch outputFormat {
+		case "simple":
+			output.WriteString(fmt.Sprintf("%d ", version.Kv.ModRevision))
+			if modTime == nil {
+				output.WriteString("unknown")
+			} else {
+				output.WriteString(modTime.Local().Format(time.RFC3339))
+			}
+			output.WriteString(": ")
+			output.Write(version.Kv.Value)
+			output.WriteString("\n")
+			break
+		case "json":
+			m := map[string]interface{}{
+				"revision": version.Kv.ModRevision,
+				"mod_time": nil,
+			}
+			if modTime != nil {
+				m["mod_time"] = modTime.Format(time.RFC3339Nano)
+			}
+			switch valueType {
+			case "string":
+				m["value"] = string(version.Kv.Value)
+				break
+			case "bytes":
+				m["value"] = version.Kv.Value
+				break
+			case "json":
+				var j interface{}
+				err := json.Unmarshal(version.Kv.Value, &j)
+				m["value"] = j
+				if err != nil {
+					return errors.Wrap(err, fmt.Sprintf("failed to unmarshal value at revision %d", version.Kv.ModRevision))
+				}
+			default:
+				return errors.New("not implemented")
+			}
+
+			bytes, err := json.Marshal(m)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal version")
+			}
+
+			output.Write(bytes)
+			output.WriteString("\n")
+			break
+		default:
+			return errors.New("not implemented")
+		}
+	}
+	return nil
+}
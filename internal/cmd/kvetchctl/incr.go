@@ -0,0 +1,93 @@
+package kvetchctl
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/syncromatics/go-kit/cmd"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	incrCmd = &cobra.Command{
+		Use:   "incr [flags] [key] [delta]",
+		Short: "Increment a counter",
+		Long: `Atomically adds the delta to the integer value of the key and prints the new value.
+
+The delta defaults to 1 and may be negative, in which case it must follow --. A missing key is created as if its value
+were 0.`,
+		Args:    cobra.RangeArgs(1, 2),
+		PreRunE: setupClient,
+		RunE: func(_ *cobra.Command, args []string) error {
+			key := args[0]
+			delta := int64(1)
+			if len(args) == 2 {
+				var err error
+				delta, err = strconv.ParseInt(args[1], 10, 64)
+				if err != nil {
+					return fmt.Errorf("delta %s is not an integer", args[1])
+				}
+			}
+
+			request := &apiv1.IncrementRequest{
+				Key:   key,
+				Delta: delta,
+			}
+			if ttl := viper.GetDuration("ttl"); ttl != 0 {
+				request.TtlDuration = ptypes.DurationProto(ttl)
+			}
+			if viper.IsSet("min") {
+				request.Min = &wrappers.Int64Value{Value: viper.GetInt64("min")}
+			}
+			if viper.IsSet("max") {
+				request.Max = &wrappers.Int64Value{Value: viper.GetInt64("max")}
+			}
+
+			group := cmd.NewProcessGroup(context.Background())
+			group.Go(func() error {
+				logger := log.With(
+					"key", key,
+					"delta", delta,
+				)
+				logger.Info("incrementing key")
+				response, err := client.Increment(group.Context(), request)
+				s, ok := status.FromError(err)
+				if ok && s.Code() == codes.Canceled {
+					return nil
+				}
+				if err != nil {
+					logger.Error(err, "failed to increment key")
+					return errors.Wrap(err, fmt.Sprintf("failed to increment key %s", key))
+				}
+				if !response.Succeeded {
+					return fmt.Errorf("incrementing key %s by %d is out of bounds, the value is %d", key, delta, response.Value)
+				}
+				return writeOutput(group.Context(), []*apiv1.KeyValue{
+					{
+						Key:   key,
+						Value: []byte(strconv.FormatInt(response.Value, 10)),
+					},
+				}, os.Stdout)
+			})
+
+			return group.Wait()
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(incrCmd)
+	incrCmd.Flags().Duration("ttl", 0, "Set the time-to-live of the key when the increment creates it (optional)")
+	incrCmd.Flags().Int64("min", 0, "Fail instead of going below the given value (optional)")
+	incrCmd.Flags().Int64("max", 0, "Fail instead of going above the given value (optional)")
+	bindCommonFlags(incrCmd)
+}
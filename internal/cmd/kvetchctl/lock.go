@@ -0,0 +1,149 @@
+package kvetchctl
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/syncromatics/go-kit/cmd"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	lockCmd = &cobra.Command{
+		Use:   "lock [flags] [name] -- [command]",
+		Short: "Run a command while holding a lock",
+		Long: `Acquires the named lock, runs the command while holding it and releases the lock when the command exits.
+
+The lock is owned by a lease that is kept alive while the command runs. If the lease is lost the command is killed.`,
+		Args:    cobra.MinimumNArgs(2),
+		PreRunE: setupClient,
+		RunE: func(command *cobra.Command, args []string) error {
+			if command.ArgsLenAtDash() != 1 {
+				return errors.New("expected a lock name followed by -- and a command")
+			}
+
+			group := cmd.NewProcessGroup(context.Background())
+			group.Go(func() error {
+				return runLocked(group.Context(), args[0], viper.GetDuration("ttl"), args[1:])
+			})
+
+			return group.Wait()
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(lockCmd)
+	lockCmd.Flags().Duration("ttl", 10*time.Second, "Set the time-to-live of the lease that owns the lock")
+	bindCommonFlags(lockCmd)
+}
+
+func runLocked(ctx context.Context, name string, ttl time.Duration, args []string) error {
+	lease, err := client.GrantLease(ctx, &apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(ttl),
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to grant lease")
+	}
+	defer client.RevokeLease(context.Background(), &apiv1.RevokeLeaseRequest{
+		Id: lease.Id,
+	})
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	keepAliveErrs := make(chan error, 1)
+	go func() {
+		keepAliveErrs <- keepAlive(ctx, lease.Id, ttl/3)
+		cancel()
+	}()
+
+	logger := log.With(
+		"name", name,
+		"lease", lease.Id,
+	)
+	logger.Info("waiting for lock")
+	lock, err := client.Lock(ctx, &apiv1.LockRequest{
+		Name:  name,
+		Lease: lease.Id,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire lock")
+	}
+	logger.Info("acquired lock")
+
+	process := exec.CommandContext(ctx, args[0], args[1:]...)
+	process.Stdin = os.Stdin
+	process.Stdout = os.Stdout
+	process.Stderr = os.Stderr
+	runErr := process.Run()
+
+	cancel()
+	keepAliveErr := <-keepAliveErrs
+
+	_, err = client.Unlock(context.Background(), &apiv1.UnlockRequest{
+		Key:   lock.Key,
+		Lease: lease.Id,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to release lock")
+	}
+	logger.Info("released lock")
+
+	if keepAliveErr != nil {
+		return keepAliveErr
+	}
+	if runErr != nil {
+		return errors.Wrap(runErr, "command failed")
+	}
+	return nil
+}
+
+// keepAlive refreshes the lease every interval until the context is cancelled. It returns an error
+// if the lease is lost.
+func keepAlive(ctx context.Context, id int64, interval time.Duration) error {
+	stream, err := client.KeepAlive(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to keep lease alive")
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err = stream.Send(&apiv1.KeepAliveRequest{
+			Id: id,
+		})
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to keep lease alive")
+		}
+
+		_, err = stream.Recv()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if status.Code(err) == codes.NotFound {
+			return errors.New("lease expired")
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to keep lease alive")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/duration"
@@ -28,7 +30,9 @@ var (
 
 If both a key and value are specified as arguments, the key will be set with the given value.
 If only a key is specified as an argument, the key will be set with the value read from STDIN.
-If neither a key nor value is specified as an argument, the keys and values will be read from JSON objects from STDIN.`,
+If neither a key nor value is specified as an argument, the keys and values will be read from JSON objects from STDIN.
+With --with-ttl, each key in the JSON objects maps to an object with a "value" and an optional "ttl" (like "30s") or
+"expires_at" (RFC 3339) that overrides --ttl for that key.`,
 		Args:    cobra.RangeArgs(0, 2),
 		PreRunE: setupClient,
 		RunE: func(_ *cobra.Command, args []string) error {
@@ -60,11 +64,17 @@ If neither a key nor value is specified as an argument, the keys and values will
 func init() {
 	RootCmd.AddCommand(setCmd)
 	setCmd.Flags().Duration("ttl", 0, "Set the time-to-live for each key (optional)")
+	setCmd.Flags().Bool("if-not-exists", false, "Only set the keys if none of them exist (optional)")
+	setCmd.Flags().String("if-value", "", "Only set the keys if each currently has the given value (optional)")
+	setCmd.Flags().Uint64("if-mod-revision", 0, "Only set the keys if each was last written at the given revision (optional)")
+	setCmd.Flags().Bool("with-ttl", false, "Read a value and an optional ttl or expires_at for each key from STDIN (optional)")
+	setCmd.Flags().Int64("lease", 0, "Attach the keys to a lease so they are deleted when it expires or is revoked (optional)")
 	bindCommonFlags(setCmd)
 }
 
 func setWithZeroArgs(ctx context.Context, ttlDuration *duration.Duration) error {
 	valueType := viper.GetString("value-type")
+	withTTL := viper.GetBool("with-ttl")
 	decoder := json.NewDecoder(os.Stdin)
 	for count := 0; ; count++ {
 		select {
@@ -87,35 +97,20 @@ func setWithZeroArgs(ctx context.Context, ttlDuration *duration.Duration) error
 
 		messages := []*apiv1.KeyValue{}
 		for key, valueObject := range valueObjects {
-			var value []byte
-			switch valueType {
-			case "json":
-				value, err = json.Marshal(valueObject)
+			message := &apiv1.KeyValue{
+				Key: key,
+			}
+			if withTTL {
+				valueObject, err = decodeEntry(message, valueObject)
 				if err != nil {
-					return errors.Wrap(err, fmt.Sprintf("failed to marshal value for key %s", key))
-				}
-				break
-			case "string":
-				switch valueString := valueObject.(type) {
-				case string:
-					value = []byte(valueString)
-				default:
-					return fmt.Errorf("value for key %s is not a string", key)
+					return err
 				}
-			case "bytes":
-				switch valueString := valueObject.(type) {
-				case string:
-					value, err = base64.StdEncoding.DecodeString(valueString)
-				default:
-					return fmt.Errorf("value for key %s is not a base64-encoded string", key)
-				}
-			default:
-				return errors.New("not implemented")
 			}
-			messages = append(messages, &apiv1.KeyValue{
-				Key:   key,
-				Value: value,
-			})
+			message.Value, err = decodeValueObject(valueType, key, valueObject)
+			if err != nil {
+				return err
+			}
+			messages = append(messages, message)
 		}
 		err = setValues(ctx, ttlDuration, messages)
 		if err != nil {
@@ -124,6 +119,78 @@ func setWithZeroArgs(ctx context.Context, ttlDuration *duration.Duration) error
 	}
 }
 
+// decodeEntry reads the ttl or expires at of an entry into the message and returns its value.
+func decodeEntry(message *apiv1.KeyValue, entryObject interface{}) (interface{}, error) {
+	entry, ok := entryObject.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("entry for key %s is not an object", message.Key)
+	}
+	valueObject, ok := entry["value"]
+	if !ok {
+		return nil, fmt.Errorf("entry for key %s has no value", message.Key)
+	}
+
+	switch ttl := entry["ttl"].(type) {
+	case nil:
+	case string:
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to parse ttl for key %s", message.Key))
+		}
+		message.SetTtlDuration = ptypes.DurationProto(d)
+	default:
+		return nil, fmt.Errorf("ttl for key %s is not a string", message.Key)
+	}
+
+	switch expiresAt := entry["expires_at"].(type) {
+	case nil:
+	case string:
+		t, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to parse expires_at for key %s", message.Key))
+		}
+		message.SetExpiresAt, err = ptypes.TimestampProto(t)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("invalid expires_at for key %s", message.Key))
+		}
+	default:
+		return nil, fmt.Errorf("expires_at for key %s is not a string", message.Key)
+	}
+
+	return valueObject, nil
+}
+
+func decodeValueObject(valueType, key string, valueObject interface{}) ([]byte, error) {
+	switch valueType {
+	case "json":
+		value, err := json.Marshal(valueObject)
+		if err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to marshal value for key %s", key))
+		}
+		return value, nil
+	case "string":
+		switch valueString := valueObject.(type) {
+		case string:
+			return []byte(valueString), nil
+		default:
+			return nil, fmt.Errorf("value for key %s is not a string", key)
+		}
+	case "bytes":
+		switch valueString := valueObject.(type) {
+		case string:
+			value, err := base64.StdEncoding.DecodeString(valueString)
+			if err != nil {
+				return nil, fmt.Errorf("value for key %s is not a base64-encoded string", key)
+			}
+			return value, nil
+		default:
+			return nil, fmt.Errorf("value for key %s is not a base64-encoded string", key)
+		}
+	default:
+		return nil, errors.New("not implemented")
+	}
+}
+
 func setWithOneArg(ctx context.Context, ttlDuration *duration.Duration, key string) error {
 	valueType := viper.GetString("value-type")
 	value, err := ioutil.ReadAll(os.Stdin)
@@ -175,12 +242,46 @@ func setWithTwoArgs(ctx context.Context, ttlDuration *duration.Duration, key, va
 }
 
 func setValues(ctx context.Context, ttlDuration *duration.Duration, messages []*apiv1.KeyValue) error {
-	_, err := client.SetValues(ctx, &apiv1.SetValuesRequest{
-		TtlDuration: ttlDuration,
-		Messages:    messages,
+	response, err := client.SetValues(ctx, &apiv1.SetValuesRequest{
+		TtlDuration:   ttlDuration,
+		Messages:      messages,
+		Preconditions: getPreconditions(messages),
+		Lease:         viper.GetInt64("lease"),
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed to set values")
 	}
+	if !response.Succeeded {
+		keys := []string{}
+		for _, precondition := range response.FailedPreconditions {
+			keys = append(keys, precondition.Key)
+		}
+		return fmt.Errorf("precondition failed for key(s) %s", strings.Join(keys, ", "))
+	}
 	return writeOutput(ctx, messages, os.Stdout)
 }
+
+func getPreconditions(messages []*apiv1.KeyValue) []*apiv1.Precondition {
+	preconditions := []*apiv1.Precondition{}
+	for _, message := range messages {
+		if viper.GetBool("if-not-exists") {
+			preconditions = append(preconditions, &apiv1.Precondition{
+				Key:       message.Key,
+				Condition: &apiv1.Precondition_Exists{Exists: false},
+			})
+		}
+		if viper.IsSet("if-value") {
+			preconditions = append(preconditions, &apiv1.Precondition{
+				Key:       message.Key,
+				Condition: &apiv1.Precondition_ValueEquals{ValueEquals: []byte(viper.GetString("if-value"))},
+			})
+		}
+		if viper.IsSet("if-mod-revision") {
+			preconditions = append(preconditions, &apiv1.Precondition{
+				Key:       message.Key,
+				Condition: &apiv1.Precondition_ModRevisionEquals{ModRevisionEquals: viper.GetUint64("if-mod-revision")},
+			})
+		}
+	}
+	return preconditions
+}
@@ -0,0 +1,123 @@
+package kvetchctl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/syncromatics/go-kit/cmd"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	ttlCmd = &cobra.Command{
+		Use:     "ttl [flags] [keys]",
+		Short:   "Show when keys expire",
+		Args:    cobra.MinimumNArgs(1),
+		PreRunE: setupClient,
+		RunE: func(_ *cobra.Command, args []string) error {
+			group := cmd.NewProcessGroup(context.Background())
+			group.Go(func() error {
+				logger := log.With(
+					"keys", args,
+				)
+				logger.Info("getting ttls")
+				requests := []*apiv1.GetValuesRequest_GetValue{}
+				for _, key := range args {
+					requests = append(requests, &apiv1.GetValuesRequest_GetValue{
+						Key: key,
+					})
+				}
+				response, err := client.GetValues(group.Context(), &apiv1.GetValuesRequest{
+					Requests: requests,
+				})
+				s, ok := status.FromError(err)
+				if ok && s.Code() == codes.Canceled {
+					return nil
+				}
+				if err != nil {
+					logger.Error(err, "failed to get ttls")
+					return errors.Wrap(err, fmt.Sprintf("failed to get ttl(s) by key %s", args))
+				}
+				if len(response.Messages) == 0 {
+					return errors.New("no keys found")
+				}
+				return writeTTLs(group.Context(), response.Messages, os.Stdout)
+			})
+
+			return group.Wait()
+		},
+	}
+)
+
+func init() {
+	RootCmd.AddCommand(ttlCmd)
+	bindCommonFlags(ttlCmd)
+}
+
+func writeTTLs(ctx context.Context, messages []*apiv1.KeyValue, output *os.File) error {
+	outputFormat := viper.GetString("output")
+	for _, message := range messages {
+		select {
+		case <-ctx.Done():
+			return io.ErrClosedPipe
+		default:
+		}
+
+		var expiresAt *time.Time
+		var remaining time.Duration
+		if message.ExpiresAt != nil {
+			at, err := ptypes.Timestamp(message.ExpiresAt)
+			if err != nil {
+				return errors.Wrap(err, "failed to deserialize expiry")
+			}
+			remaining, err = ptypes.Duration(message.TtlDuration)
+			if err != nil {
+				return errors.Wrap(err, "failed to deserialize ttl")
+			}
+			expiresAt = &at
+		}
+
+		switch outputFormat {
+		case "simple":
+			output.WriteString(message.Key)
+			if expiresAt == nil {
+				output.WriteString(": no ttl\n")
+				break
+			}
+			output.WriteString(fmt.Sprintf(": %s (expires at %s)\n", remaining.Round(time.Second), expiresAt.Local().Format(time.RFC3339)))
+			break
+		case "json":
+			m := map[string]interface{}{}
+			if expiresAt == nil {
+				m[message.Key] = nil
+			} else {
+				m[message.Key] = map[string]interface{}{
+					"expires_at": expiresAt.Format(time.RFC3339),
+					"ttl":        remaining.Round(time.Second).String(),
+				}
+			}
+
+			bytes, err := json.Marshal(m)
+			if err != nil {
+				return errors.Wrap(err, "failed to marshal ttl")
+			}
+
+			output.Write(bytes)
+			output.WriteString("\n")
+			break
+		default:
+			return errors.New("not implemented")
+		}
+	}
+	return nil
+}
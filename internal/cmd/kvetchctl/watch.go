@@ -4,50 +4,77 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync/atomic"
+	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/syncromatics/go-kit/cmd"
 	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+const (
+	// missedHeartbeats is how many heartbeats in a row watch waits for before reconnecting.
+	missedHeartbeats = 3
+	// maxReconnectDelay caps the delay between reconnects, which doubles from the heartbeat
+	// interval each time a watch ends without having lasted that long.
+	maxReconnectDelay = 30 * time.Second
+)
+
 var (
+	errMissedHeartbeats = errors.New("missed heartbeats")
+
+	slowConsumerPolicies = map[string]apiv1.SubscribeRequest_SlowConsumerPolicy{
+		"disconnect":  apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DISCONNECT,
+		"drop-oldest": apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST,
+		"coalesce":    apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_COALESCE,
+	}
+
 	watchCmd = &cobra.Command{
 		Use:     "watch [flags] [key prefixes]",
-		Short:   "Watch values by prefix",
-		Args:    cobra.MinimumNArgs(1),
+		Short:   "Watch values by prefix or key pattern",
 		PreRunE: setupClient,
 		RunE: func(_ *cobra.Command, prefixes []string) error {
+			interval := viper.GetDuration("heartbeat-interval")
+			request := &apiv1.SubscribeRequest{
+				Prefixes:      prefixes,
+				StartRevision: viper.GetUint64("start-revision"),
+				SkipSnapshot:  viper.GetBool("skip-snapshot"),
+			}
+			for _, glob := range viper.GetStringSlice("glob") {
+				request.Patterns = append(request.Patterns, &apiv1.KeyPattern{Glob: glob})
+			}
+			for _, regex := range viper.GetStringSlice("regex") {
+				request.Patterns = append(request.Patterns, &apiv1.KeyPattern{Regex: regex})
+			}
+			if len(request.Prefixes) == 0 && len(request.Patterns) == 0 {
+				return errors.New("at least one key prefix, glob or regex is required")
+			}
+			if interval != 0 {
+				request.ProgressInterval = ptypes.DurationProto(interval)
+			}
+			if window := viper.GetDuration("coalesce-window"); window != 0 {
+				request.CoalesceWindow = ptypes.DurationProto(window)
+			}
+			policy, ok := slowConsumerPolicies[viper.GetString("slow-consumer-policy")]
+			if !ok {
+				return errors.Errorf("unknown slow consumer policy %q", viper.GetString("slow-consumer-policy"))
+			}
+			request.SlowConsumerPolicy = policy
+
 			group := cmd.NewProcessGroup(context.Background())
 			group.Go(func() error {
 				logger := log.With(
 					"prefixes", prefixes,
+					"globs", viper.GetStringSlice("glob"),
+					"regexes", viper.GetStringSlice("regex"),
 				)
-				logger.Info("watching prefixes")
-				stream, err := client.Subscribe(group.Context(), &apiv1.SubscribeRequest{
-					Prefixes: prefixes,
-				})
-				if err != nil {
-					logger.Error(err, "failed to watch prefixes")
-					return errors.Wrap(err, fmt.Sprintf("failed to get value(s) by key %s", prefixes))
-				}
-				for {
-					response, err := stream.Recv()
-					s, ok := status.FromError(err)
-					if ok && s.Code() == codes.Canceled {
-						return nil
-					}
-					if err != nil {
-						return errors.Wrap(err, "failed to read response")
-					}
-
-					err = writeOutput(stream.Context(), response.Messages, os.Stdout)
-					if err != nil {
-						return err
-					}
-				}
+				return watchAndReconnect(group.Context(), logger, request, interval)
 			})
 
 			return group.Wait()
@@ -57,5 +84,119 @@ var (
 
 func init() {
 	RootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().Uint64("start-revision", 0, "Replay every change after the revision instead of the current values (optional)")
+	watchCmd.Flags().Bool("skip-snapshot", false, "Only watch for changes without getting the current values first (optional)")
+	watchCmd.Flags().StringSlice("glob", nil, "Also watch keys matching the glob, e.g. devices/*/status (optional)")
+	watchCmd.Flags().StringSlice("regex", nil, "Also watch keys matching the RE2 regular expression (optional)")
+	watchCmd.Flags().Duration("coalesce-window", 0, "Only write the latest change to each key within the window (optional)")
+	watchCmd.Flags().String("slow-consumer-policy", "disconnect", "What the server does when watch falls behind (disconnect, drop-oldest, coalesce)")
+	watchCmd.Flags().Duration("heartbeat-interval", 0, fmt.Sprintf("Ask for a heartbeat at the interval and reconnect after missing %d in a row (optional)", missedHeartbeats))
 	bindCommonFlags(watchCmd)
 }
+
+// watchAndReconnect watches until the context is done, reconnecting when heartbeats are missed or
+// the server is unavailable. A reconnected watch resumes after the last change it saw, or gets the
+// current values again when those changes were already compacted away.
+func watchAndReconnect(ctx context.Context, logger *zap.SugaredLogger, request *apiv1.SubscribeRequest, interval time.Duration) error {
+	delay := interval
+	resumed := false
+	for {
+		logger.Info("watching keys")
+		started := time.Now()
+		revision, err := watch(ctx, request, interval)
+		s, ok := status.FromError(errors.Cause(err))
+		if resumed && ok && s.Code() == codes.OutOfRange {
+			logger.Warnw("getting the current values, the changes since the last revision seen were compacted", "revision", request.StartRevision)
+			request.StartRevision = 0
+			request.SkipSnapshot = false
+			resumed = false
+			continue
+		}
+		reconnect := err == errMissedHeartbeats || (interval != 0 && ok && s.Code() == codes.Unavailable)
+		if !reconnect {
+			return err
+		}
+
+		if time.Since(started) > maxReconnectDelay {
+			delay = interval
+		}
+		logger.Warnw("reconnecting", "reason", err.Error(), "revision", revision, "delay", delay)
+		if revision != 0 {
+			// resume after the last change seen instead of getting the current values again
+			request.StartRevision = revision
+			request.SkipSnapshot = false
+			resumed = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+		delay *= 2
+		if delay > maxReconnectDelay {
+			delay = maxReconnectDelay
+		}
+	}
+}
+
+// watch writes the events of a subscription until it ends. It returns the revision every change
+// before has been written for, so a new subscription can resume from it, and errMissedHeartbeats
+// when heartbeats were asked for and stopped arriving.
+func watch(ctx context.Context, request *apiv1.SubscribeRequest, interval time.Duration) (uint64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// the stream is only timed out when heartbeats were asked for
+	var missed int32
+	heartbeat := func() {}
+	if interval > 0 {
+		timeout := missedHeartbeats * interval
+		timer := time.AfterFunc(timeout, func() {
+			atomic.StoreInt32(&missed, 1)
+			cancel()
+		})
+		defer timer.Stop()
+		heartbeat = func() { timer.Reset(timeout) }
+	}
+
+	stream, err := client.Subscribe(ctx, request)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to watch keys")
+	}
+
+	var revision uint64
+	synced := false
+	for {
+		response, err := stream.Recv()
+		if atomic.LoadInt32(&missed) == 1 {
+			return revision, errMissedHeartbeats
+		}
+		s, ok := status.FromError(err)
+		if ok && s.Code() == codes.Canceled {
+			return revision, nil
+		}
+		if ok && s.Code() == codes.Unavailable {
+			return revision, err
+		}
+		if err != nil {
+			return revision, errors.Wrap(err, "failed to read response")
+		}
+		heartbeat()
+
+		if response.Dropped > 0 {
+			log.Warnw("the server left out changes watch fell behind on", "dropped", response.Dropped, "revision", response.Header.GetRevision())
+		}
+
+		err = writeEvents(stream.Context(), response.Events, os.Stdout)
+		if err != nil {
+			return revision, err
+		}
+
+		// the current values are only all written once the subscription is synced
+		synced = synced || response.Sync
+		if synced && response.Header != nil {
+			revision = response.Header.Revision
+		}
+	}
+}
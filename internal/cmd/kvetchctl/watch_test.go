@@ -0,0 +1,102 @@
+package kvetchctl
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	"github.com/pkg/errors"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gotest.tools/assert"
+)
+
+// fakeWatchClient answers each subscription with the next of its streams, and records the start
+// of each subscription.
+type fakeWatchClient struct {
+	apiv1.APIClient
+	streams [][]interface{}
+	starts  []watchStart
+}
+
+// watchStart is where a subscription started from.
+type watchStart struct {
+	revision     uint64
+	skipSnapshot bool
+}
+
+func (c *fakeWatchClient) Subscribe(ctx context.Context, request *apiv1.SubscribeRequest, _ ...grpc.CallOption) (apiv1.API_SubscribeClient, error) {
+	c.starts = append(c.starts, watchStart{request.StartRevision, request.SkipSnapshot})
+	stream := &fakeSubscribeStream{ctx: ctx}
+	if len(c.streams) > 0 {
+		stream.messages = c.streams[0]
+		c.streams = c.streams[1:]
+	}
+	return stream, nil
+}
+
+// fakeSubscribeStream receives its messages in order, which are either responses or the error the
+// stream ends with.
+type fakeSubscribeStream struct {
+	grpc.ClientStream
+	ctx      context.Context
+	messages []interface{}
+}
+
+func (s *fakeSubscribeStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSubscribeStream) Recv() (*apiv1.SubscribeResponse, error) {
+	if len(s.messages) == 0 {
+		return nil, status.Error(codes.Canceled, "done")
+	}
+	message := s.messages[0]
+	s.messages = s.messages[1:]
+	if err, ok := message.(error); ok {
+		return nil, err
+	}
+	return message.(*apiv1.SubscribeResponse), nil
+}
+
+func Test_WatchReconnectAfterCompaction(t *testing.T) {
+	fake := &fakeWatchClient{
+		streams: [][]interface{}{
+			[]interface{}{
+				&apiv1.SubscribeResponse{Header: &apiv1.ResponseHeader{Revision: 5}, Sync: true},
+				status.Error(codes.Unavailable, "server went away"),
+			},
+			[]interface{}{
+				status.Error(codes.OutOfRange, "requested revision has been compacted"),
+			},
+		},
+	}
+	previous := client
+	client = fake
+	defer func() { client = previous }()
+
+	// the resumed watch gets the current values again once its revision was compacted
+	request := &apiv1.SubscribeRequest{Prefixes: []string{"watch/"}}
+	err := watchAndReconnect(context.Background(), zap.NewNop().Sugar(), request, 10*time.Millisecond)
+	assert.NilError(t, err)
+	assert.Equal(t, len(fake.starts), 3)
+	assert.Equal(t, fake.starts[0], watchStart{})
+	assert.Equal(t, fake.starts[1], watchStart{revision: 5})
+	assert.Equal(t, fake.starts[2], watchStart{})
+
+	// a start revision that was asked for ends the watch once it is compacted
+	fake.streams = [][]interface{}{
+		[]interface{}{
+			status.Error(codes.OutOfRange, "requested revision has been compacted"),
+		},
+	}
+	fake.starts = nil
+	request = &apiv1.SubscribeRequest{Prefixes: []string{"watch/"}, StartRevision: 3}
+	err = watchAndReconnect(context.Background(), zap.NewNop().Sugar(), request, 10*time.Millisecond)
+	assert.Equal(t, status.Code(errors.Cause(err)), codes.OutOfRange)
+	assert.Equal(t, len(fake.starts), 1)
+}
@@ -0,0 +1,279 @@
+package datastore
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrCompacted is returned when a subscription asks to resume from a revision that is no longer
+	// in the change log.
+	ErrCompacted = errors.New("requested revision has been compacted")
+	// ErrSlowConsumer is returned when a subscription falls further behind than its buffer allows.
+	ErrSlowConsumer = errors.New("subscriber fell too far behind")
+
+	errChangeLogStopped = errors.New("change log stopped")
+)
+
+// changeLogStartKey is written when the datastore opens, so the change log knows it is seeing every
+// change once the write reaches it.
+const changeLogStartKey = internalPrefix + "change-log-start"
+
+// changeLog keeps a bounded, in order record of recent changes so subscriptions can resume from a
+// revision instead of replaying the current values. It is the single subscription to the datastore
+// every listener is fed from.
+type changeLog struct {
+	mtx        sync.Mutex
+	size       int
+	bufferSize int
+	changes    []*pb.KV
+	compacted  uint64
+	observed   bool
+	// appended is the revision of the last change appended.
+	appended  uint64
+	listeners map[*changeListener]struct{}
+	index     *listenerIndex
+	// previousListeners counts the listeners that want the values changes replaced.
+	previousListeners int
+	// disconnected and dropped count the listeners that fell too far behind and the changes left out
+	// for listeners that could not keep up.
+	disconnected uint64
+	dropped      uint64
+
+	// subscribing is closed right before subscribing to the datastore, started once the start key
+	// is seen, and stopped once the subscription to the datastore ends with err.
+	subscribing chan struct{}
+	started     chan struct{}
+	startOnce   sync.Once
+	stopped     chan struct{}
+	err         error
+}
+
+func newChangeLog(size, bufferSize int) *changeLog {
+	return &changeLog{
+		size:        size,
+		bufferSize:  bufferSize,
+		changes:     []*pb.KV{},
+		listeners:   map[*changeListener]struct{}{},
+		index:       newListenerIndex(),
+		subscribing: make(chan struct{}),
+		started:     make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+}
+
+// run subscribes to every change in the datastore until the context is done or the subscription
+// fails. Listeners are ended with the reason once it stops.
+func (l *changeLog) run(ctx context.Context, db *badger.DB) {
+	close(l.subscribing)
+	err := db.Subscribe(ctx, func(list *badger.KVList) error {
+		kvs := list.Kv
+		// the values changes replaced are only read while a listener wants them, since they are
+		// looked up again when they are missing from a change that is sent with them
+		if l.wantsPrevious() {
+			withPrevious, err := withPrevious(db, kvs)
+			if err != nil {
+				log.Printf("failed to read previous values: %v", err)
+			} else {
+				kvs = withPrevious
+			}
+		}
+		l.append(kvs)
+		return nil
+	}, []byte{})
+	if err == nil || err == context.Canceled {
+		err = errChangeLogStopped
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.err = errors.Wrap(err, "change log subscription ended")
+	for listener := range l.listeners {
+		listener.fail(l.err)
+	}
+	close(l.stopped)
+}
+
+// start waits until the subscription to the datastore is seeing changes, by writing the start key
+// until it is seen. Every change committed after it returns is in the log. The subscription is
+// registered as soon as it is made, so the key is written once unless that is delayed for long.
+func (l *changeLog) start(db *badger.DB) error {
+	<-l.subscribing
+	for {
+		err := db.Update(func(txn *badger.Txn) error {
+			return txn.SetEntry(&badger.Entry{
+				Key:       []byte(changeLogStartKey),
+				Value:     []byte{},
+				ExpiresAt: 1,
+			})
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to write change log start")
+		}
+
+		select {
+		case <-l.started:
+			return nil
+		case <-l.stopped:
+			return l.err
+		case <-time.After(time.Second):
+			// the subscription was not registered yet when the key was written
+		}
+	}
+}
+
+// append records changes and queues them for the listeners that match them. A listener that cannot
+// keep up has its overflow policy applied instead of holding up the others.
+func (l *changeLog) append(kvs []*pb.KV) {
+	if len(kvs) == 0 {
+		return
+	}
+	for _, kv := range kvs {
+		if bytes.Equal(kv.Key, []byte(changeLogStartKey)) {
+			l.startOnce.Do(func() { close(l.started) })
+		}
+	}
+
+	matched := l.record(kvs)
+	for listener, changes := range matched {
+		dropped, disconnected := listener.push(changes)
+		atomic.AddUint64(&l.dropped, uint64(dropped))
+		if disconnected {
+			atomic.AddUint64(&l.disconnected, 1)
+		}
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	for _, kv := range kvs {
+		if kv.Version > l.appended {
+			l.appended = kv.Version
+		}
+	}
+}
+
+// record adds changes to the log and returns the changes for each listener that matches them.
+// Listeners registered afterwards replay the changes from the log instead.
+func (l *changeLog) record(kvs []*pb.KV) map[*changeListener][]*pb.KV {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if !l.observed {
+		// changes before the first one observed may have been missed while subscribing
+		l.compacted = kvs[0].Version - 1
+		l.observed = true
+	}
+
+	l.changes = append(l.changes, kvs...)
+	if excess := len(l.changes) - l.size; excess > 0 {
+		l.compacted = l.changes[excess-1].Version
+		l.changes = append([]*pb.KV{}, l.changes[excess:]...)
+	}
+
+	matched := map[*changeListener][]*pb.KV{}
+	for _, kv := range kvs {
+		key := kv.Key
+		if target, ok := previousTarget(key); ok {
+			key = target
+		}
+		if isInternal(key) {
+			continue
+		}
+		for _, listener := range l.index.lookup(key) {
+			if listener.filter.matches(key) {
+				matched[listener] = append(matched[listener], kv)
+			}
+		}
+	}
+	return matched
+}
+
+// listen returns the recorded changes after the revision for the filter and registers a listener
+// for every change appended afterwards, with the policy for when it falls behind and whether it
+// wants the values changes replaced.
+func (l *changeLog) listen(revision, current uint64, filter keyFilter, policy overflowPolicy, previous bool) ([]*pb.KV, *changeListener, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.err != nil {
+		return nil, nil, l.err
+	}
+
+	compacted := l.compacted
+	if !l.observed {
+		compacted = current
+	}
+	if revision < compacted {
+		return nil, nil, ErrCompacted
+	}
+
+	listener := newChangeListener(filter, policy, l.bufferSize)
+	listener.previous = previous
+
+	replay := []*pb.KV{}
+	for _, kv := range l.changes {
+		if kv.Version > revision && listener.matches(kv.Key) {
+			replay = append(replay, kv)
+		}
+	}
+
+	l.add(listener)
+	return replay, listener, nil
+}
+
+// revision returns the revision of the last change appended. Every change up to it has been handed
+// to the listeners that match it.
+func (l *changeLog) revision() uint64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.appended
+}
+
+// watch registers a listener that is notified of every change appended from now on, without
+// queueing the changes themselves.
+func (l *changeLog) watch(prefixes [][]byte) *changeListener {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	listener := newChangeListener(keyFilter{prefixes: prefixes}, overflowNotify, 0)
+	l.add(listener)
+	return listener
+}
+
+func (l *changeLog) remove(listener *changeListener) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.listeners, listener)
+	l.index.remove(listener)
+	if listener.previous {
+		l.previousListeners--
+	}
+}
+
+// wantsPrevious reports whether any listener wants the values changes replaced.
+func (l *changeLog) wantsPrevious() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.previousListeners > 0
+}
+
+func (l *changeLog) add(listener *changeListener) {
+	l.listeners[listener] = struct{}{}
+	l.index.add(listener)
+	if listener.previous {
+		l.previousListeners++
+	}
+}
@@ -0,0 +1,64 @@
+package datastore
+
+import (
+	"sort"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+)
+
+// coalescer collapses the events for each key into the latest one until they are sent.
+type coalescer struct {
+	events []*apiv1.Event
+	keys   map[string]int
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{
+		events: []*apiv1.Event{},
+		keys:   map[string]int{},
+	}
+}
+
+// add queues the events, replacing the queued event for the same key. The replacing event keeps the
+// value from before the first change it was collapsed with.
+func (c *coalescer) add(events []*apiv1.Event) {
+	for _, event := range events {
+		i, ok := c.keys[event.Kv.Key]
+		if !ok {
+			c.keys[event.Kv.Key] = len(c.events)
+			c.events = append(c.events, event)
+			continue
+		}
+
+		replaced := c.events[i]
+		event.PrevKv = replaced.PrevKv
+		event.Coalesced = replaced.Coalesced + 1
+		c.events[i] = event
+	}
+}
+
+// response builds a response from the queued events in revision order and empties the queue.
+func (c *coalescer) response() *apiv1.SubscribeResponse {
+	events := c.events
+	c.events = []*apiv1.Event{}
+	c.keys = map[string]int{}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Kv.ModRevision < events[j].Kv.ModRevision
+	})
+
+	response := &apiv1.SubscribeResponse{
+		Messages: []*apiv1.KeyValue{},
+		Events:   events,
+		Header:   &apiv1.ResponseHeader{},
+	}
+	for _, event := range events {
+		if event.Type == apiv1.Event_TYPE_PUT {
+			response.Messages = append(response.Messages, event.Kv)
+		}
+		if event.Kv.ModRevision > response.Header.Revision {
+			response.Header.Revision = event.Kv.ModRevision
+		}
+	}
+	return response
+}
@@ -0,0 +1,78 @@
+package datastore
+
+import (
+	"strconv"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// Increment atomically adds a delta to the integer value of a key, creating the key when it is
+// missing. Values are stored as decimal text so they read naturally with Get.
+func (s *KVStore) Increment(request *apiv1.IncrementRequest) (*apiv1.IncrementResponse, error) {
+	createExpire, err := ttlExpiry(request.TtlDuration)
+	if err != nil {
+		return nil, err
+	}
+
+	key := []byte(request.Key)
+	var value int64
+	var expire uint64
+	var succeeded, created bool
+	err = s.update(func(txn *badger.Txn) error {
+		var current, lease int64
+		expire = createExpire
+		created = false
+
+		item, err := txn.Get(key)
+		switch {
+		case err == badger.ErrKeyNotFound:
+			created = true
+		case err != nil:
+			return errors.Wrap(err, "failed to get key")
+		default:
+			kv, err := itemKeyValue(item)
+			if err != nil {
+				return err
+			}
+			current, err = strconv.ParseInt(string(kv.Value), 10, 64)
+			if err != nil {
+				return errors.Wrapf(ErrFailedPrecondition, "value of key %q is not an integer", request.Key)
+			}
+			expire = item.ExpiresAt()
+			lease = kv.Lease
+		}
+
+		next := current + request.Delta
+		if (request.Delta > 0 && next < current) || (request.Delta < 0 && next > current) {
+			return errors.Wrapf(ErrFailedPrecondition, "incrementing key %q overflows", request.Key)
+		}
+		if (request.Min != nil && next < request.Min.Value) || (request.Max != nil && next > request.Max.Value) {
+			succeeded = false
+			value = current
+			return nil
+		}
+
+		succeeded = true
+		value = next
+		return putValue(txn, &apiv1.KeyValue{
+			Key:   request.Key,
+			Value: []byte(strconv.FormatInt(next, 10)),
+		}, expire, lease)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to increment")
+	}
+
+	if succeeded && created && expire != 0 {
+		s.expirations.add(request.Key, expire)
+	}
+
+	return &apiv1.IncrementResponse{
+		Header:    s.responseHeader(),
+		Succeeded: succeeded,
+		Value:     value,
+	}, nil
+}
@@ -0,0 +1,50 @@
+package datastore
+
+import (
+	"container/heap"
+	"sync"
+)
+
+type expiration struct {
+	key       string
+	expiresAt uint64
+}
+
+type expirationHeap []expiration
+
+func (h expirationHeap) Len() int            { return len(h) }
+func (h expirationHeap) Less(i, j int) bool  { return h[i].expiresAt < h[j].expiresAt }
+func (h expirationHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *expirationHeap) Push(x interface{}) { *h = append(*h, x.(expiration)) }
+func (h *expirationHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// expirations tracks keys written with a ttl so the sweeper can report them when they lapse.
+type expirations struct {
+	mtx  sync.Mutex
+	heap expirationHeap
+}
+
+func (e *expirations) add(key string, expiresAt uint64) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	heap.Push(&e.heap, expiration{key, expiresAt})
+}
+
+// popExpired removes and returns every tracked key that expires at or before now.
+func (e *expirations) popExpired(now uint64) []expiration {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+
+	expired := []expiration{}
+	for e.heap.Len() > 0 && e.heap[0].expiresAt <= now {
+		expired = append(expired, heap.Pop(&e.heap).(expiration))
+	}
+	return expired
+}
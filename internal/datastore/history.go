@@ -0,0 +1,107 @@
+package datastore
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// GetHistory retrieves the retained versions of a key, newest first. Badger keeps as many versions
+// of a key as the history versions option allows, counting deletes and expirations, and drops the
+// versions before a delete when it compacts them. Versions past that count are left out even before
+// they are compacted, as are versions written longer ago than the history age except for the newest.
+func (s *KVStore) GetHistory(request *apiv1.GetHistoryRequest) (*apiv1.GetHistoryResponse, error) {
+	response := &apiv1.GetHistoryResponse{
+		Versions: []*apiv1.KeyVersion{},
+	}
+
+	key := []byte(request.Key)
+	if isInternal(key) {
+		return nil, errors.Errorf("key %q is reserved", request.Key)
+	}
+
+	var oldest time.Time
+	if s.historyAge > 0 {
+		oldest = time.Now().Add(-s.historyAge)
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		response.Header = &apiv1.ResponseHeader{
+			Revision: txn.ReadTs(),
+		}
+
+		opts := badger.DefaultIteratorOptions
+		opts.AllVersions = true
+		opts.Prefix = key
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		retained := 0
+		for it.Seek(key); it.Valid() && retained < s.historyVersions; it.Next() {
+			item := it.Item()
+			if !bytes.Equal(item.Key(), key) {
+				break
+			}
+			retained++
+			if item.UserMeta()&(userMetaDelete|userMetaExpire) != 0 {
+				continue
+			}
+			if request.Limit > 0 && int64(len(response.Versions)) == request.Limit {
+				break
+			}
+
+			version, modTime, err := itemKeyVersion(item)
+			if err != nil {
+				return err
+			}
+			if len(response.Versions) > 0 && modTime.Before(oldest) {
+				break
+			}
+			response.Versions = append(response.Versions, version)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get history")
+	}
+
+	return response, nil
+}
+
+// itemKeyVersion reads the version of a key stored in an item along with the time it was written,
+// which is the zero time for versions written before write times were recorded.
+func itemKeyVersion(item *badger.Item) (*apiv1.KeyVersion, time.Time, error) {
+	raw, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed to get value")
+	}
+
+	kv, err := decodeValue(item.KeyCopy(nil), item.UserMeta(), item.Version(), raw)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	setExpiry(kv, item.ExpiresAt())
+
+	version := &apiv1.KeyVersion{
+		Kv: kv,
+	}
+	if item.UserMeta()&userMetaTime == 0 {
+		return version, time.Time{}, nil
+	}
+
+	header, _, err := decodeHeader(item.UserMeta(), raw)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	modTime := time.Unix(0, header.modTime)
+	version.ModTime, err = ptypes.TimestampProto(modTime)
+	if err != nil {
+		return nil, time.Time{}, errors.Wrap(err, "failed to serialize mod time")
+	}
+	return version, modTime, nil
+}
@@ -0,0 +1,28 @@
+package datastore
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// internalPrefix is the keyspace kvetch keeps its own state in. Keys under it are never returned
+// to clients and cannot be written by them.
+const internalPrefix = "\x00kvetch/"
+
+// badgerPrefix is the keyspace badger keeps its own state in, such as the transaction markers that
+// subscriptions to every key see.
+const badgerPrefix = "!badger!"
+
+func isInternal(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(internalPrefix)) || bytes.HasPrefix(key, []byte(badgerPrefix))
+}
+
+// validateKey rejects client writes to the internal keyspace.
+func validateKey(key string) error {
+	if strings.HasPrefix(key, internalPrefix) {
+		return errors.Errorf("key %q is reserved", key)
+	}
+	return nil
+}
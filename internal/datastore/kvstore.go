@@ -1,18 +1,33 @@
 package datastore
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"time"
 
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
 	"github.com/golang/protobuf/ptypes/wrappers"
 	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
 
 	badger "github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
 	"github.com/pkg/errors"
 )
 
+// ErrBatchTooLarge is returned when writes with preconditions or the operations of a transaction do
+// not fit in a single transaction.
+var ErrBatchTooLarge = errors.New("batch is too large for a single transaction")
+
+// ErrInvalidRequest is returned when a request is missing a field it needs or has one that is out of range.
+var ErrInvalidRequest = errors.New("invalid request")
+
+// ErrFailedPrecondition is returned when a request cannot be applied to the keys as they are, such as
+// incrementing a key that does not hold an integer.
+var ErrFailedPrecondition = errors.New("failed precondition")
+
 //KVStoreOptions represent environment variable configurable options related to the KV Store.
 type KVStoreOptions struct {
 	EnableTruncate                              *wrappers.BoolValue
@@ -23,12 +38,29 @@ type KVStoreOptions struct {
 	NumberOfLevelZeroTablesUntilForceCompaction *wrappers.Int32Value
 	GarbageCollectionDiscardRatio               *wrappers.FloatValue
 	InMemory                                    *wrappers.BoolValue
+	ChangeLogSize                               *wrappers.Int64Value
+	HistoryVersions                             *wrappers.Int32Value
+	HistoryAge                                  *duration.Duration
+	SubscriberBufferSize                        *wrappers.Int32Value
 }
 
+const (
+	// userMetaDelete marks an entry that records the deletion of a key.
+	userMetaDelete byte = 1 << 0
+	// userMetaExpire marks an entry that records the expiration of a key.
+	userMetaExpire byte = 1 << 1
+)
+
 // KVStore is the key value datastore
 type KVStore struct {
 	db                            *badger.DB
 	garbageCollectionDiscardRatio float64
+	expirations                   *expirations
+	changes                       *changeLog
+	leases                        *leases
+	historyVersions               int
+	historyAge                    time.Duration
+	stopChanges                   context.CancelFunc
 }
 
 func getBadgerOptions(path string, options *KVStoreOptions) badger.Options {
@@ -61,6 +93,10 @@ func getBadgerOptions(path string, options *KVStoreOptions) badger.Options {
 		fmt.Printf("Configuring with NumberOfLevelZeroTablesUntilForceCompaction: %d \n", options.NumberOfLevelZeroTablesUntilForceCompaction.Value)
 		opts = opts.WithNumLevelZeroTablesStall(int(options.NumberOfLevelZeroTablesUntilForceCompaction.Value))
 	}
+	if options.HistoryVersions != nil {
+		fmt.Printf("Configuring with HistoryVersions: %d \n", options.HistoryVersions.Value)
+		opts = opts.WithNumVersionsToKeep(int(options.HistoryVersions.Value))
+	}
 
 	return opts
 }
@@ -72,16 +108,71 @@ func NewKVStore(path string, options *KVStoreOptions) (*KVStore, error) {
 		fmt.Printf("Configuring with GarbageCollectionDiscardRatio: %f \n", options.GarbageCollectionDiscardRatio.Value)
 		garbageCollectionDiscardRatio = float64(options.GarbageCollectionDiscardRatio.Value)
 	}
+	changeLogSize := 10000
+	if options.ChangeLogSize != nil {
+		fmt.Printf("Configuring with ChangeLogSize: %d \n", options.ChangeLogSize.Value)
+		changeLogSize = int(options.ChangeLogSize.Value)
+	}
+	subscriberBufferSize := 1000
+	if options.SubscriberBufferSize != nil {
+		fmt.Printf("Configuring with SubscriberBufferSize: %d \n", options.SubscriberBufferSize.Value)
+		subscriberBufferSize = int(options.SubscriberBufferSize.Value)
+	}
+	historyVersions := 1
+	if options.HistoryVersions != nil {
+		historyVersions = int(options.HistoryVersions.Value)
+	}
+	var historyAge time.Duration
+	if options.HistoryAge != nil {
+		var err error
+		historyAge, err = ptypes.Duration(options.HistoryAge)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to deserialize history age")
+		}
+		fmt.Printf("Configuring with HistoryAge: %s \n", historyAge)
+		if historyVersions <= 1 {
+			return nil, errors.New("history age requires more than one history version")
+		}
+	}
 
 	opts := getBadgerOptions(path, options)
 	db, err := badger.Open(opts)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to open datastore")
 	}
-	return &KVStore{
-		db,
-		garbageCollectionDiscardRatio,
-	}, nil
+	store := &KVStore{
+		db:                            db,
+		garbageCollectionDiscardRatio: garbageCollectionDiscardRatio,
+		expirations:                   &expirations{},
+		changes:                       newChangeLog(changeLogSize, subscriberBufferSize),
+		leases:                        newLeases(),
+		historyVersions:               historyVersions,
+		historyAge:                    historyAge,
+	}
+
+	err = store.loadExpirations()
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to load expirations")
+	}
+
+	err = store.loadLeases()
+	if err != nil {
+		db.Close()
+		return nil, errors.Wrap(err, "failed to load leases")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.stopChanges = cancel
+	go store.changes.run(ctx, db)
+	err = store.changes.start(db)
+	if err != nil {
+		cancel()
+		db.Close()
+		return nil, errors.Wrap(err, "failed to start change log")
+	}
+
+	return store, nil
 }
 
 // Get retrieves key values from the datastore.
@@ -89,35 +180,29 @@ func (s *KVStore) Get(request *apiv1.GetValuesRequest) (*apiv1.GetValuesResponse
 	response := &apiv1.GetValuesResponse{
 		Messages: []*apiv1.KeyValue{},
 	}
+	nextStartAfter := []string{}
+	limited := false
 
 	err := s.db.View(func(txn *badger.Txn) error {
-		for _, key := range request.Requests {
-			if key.IsPrefix {
-				values, err := s.prefixScan(txn, key.Key)
-				if err != nil {
-					return errors.Wrap(err, "failed prefix scan")
-				}
-				response.Messages = append(response.Messages, values...)
-				continue
-			}
-			value, err := txn.Get([]byte(key.Key))
-			if err == badger.ErrKeyNotFound {
-				continue
-			}
-			if err != nil {
-				return errors.Wrap(err, "failed to get key")
-			}
+		at, err := s.getPointInTime(txn, request)
+		if err != nil {
+			return err
+		}
 
-			err = value.Value(func(v []byte) error {
-				response.Messages = append(response.Messages, &apiv1.KeyValue{
-					Key:   string(key.Key),
-					Value: append([]byte{}, v...),
-				})
-				return nil
-			})
+		response.Header = &apiv1.ResponseHeader{
+			Revision: txn.ReadTs(),
+		}
+		if request.Revision != 0 {
+			response.Header.Revision = request.Revision
+		}
+		for _, key := range request.Requests {
+			values, next, err := s.getValue(txn, key, at)
 			if err != nil {
-				return errors.Wrap(err, "failed to get value")
+				return err
 			}
+			response.Messages = append(response.Messages, values...)
+			nextStartAfter = append(nextStartAfter, next)
+			limited = limited || next != ""
 		}
 		return nil
 	})
@@ -125,60 +210,248 @@ func (s *KVStore) Get(request *apiv1.GetValuesRequest) (*apiv1.GetValuesResponse
 		return nil, errors.Wrap(err, "failed to get from db")
 	}
 
+	if limited {
+		response.NextStartAfter = nextStartAfter
+	}
+
 	return response, nil
 }
 
-// Set sets key values in the datastore.
-func (s *KVStore) Set(request *apiv1.SetValuesRequest) error {
-	wb := s.db.NewWriteBatch()
-	defer wb.Cancel()
-
-	if request.TtlDuration != nil {
-		ttl, err := ptypes.Duration(request.TtlDuration)
+// Set sets key values in the datastore. Nothing is written unless every precondition holds. Writes
+// with preconditions are made in a single transaction, so they fail with ErrBatchTooLarge when they
+// do not fit in one. Writes without them are split into as many transactions as they need.
+func (s *KVStore) Set(request *apiv1.SetValuesRequest) (*apiv1.SetValuesResponse, error) {
+	expire, err := ttlExpiry(request.TtlDuration)
+	if err != nil {
+		return nil, err
+	}
+	expires := []uint64{}
+	for _, value := range request.Messages {
+		e, err := messageExpiry(value, expire)
 		if err != nil {
-			return errors.Wrap(err, "failed to deserialize ttl")
-		}
-		expire := uint64(time.Now().Add(ttl).Unix())
-		for _, value := range request.Messages {
-			entry := &badger.Entry{
-				Key:       []byte(value.Key),
-				Value:     []byte(value.Value),
-				ExpiresAt: expire,
-			}
-			err = wb.SetEntry(entry)
+			return nil, errors.Wrapf(err, "invalid expiry for key %q", value.Key)
+		}
+		expires = append(expires, e)
+	}
+
+	failed := []*apiv1.Precondition{}
+	written := 0
+	for {
+		count := 0
+		err = s.update(func(txn *badger.Txn) error {
+			count = 0
+			var err error
+			failed, err = checkPreconditions(txn, request.Preconditions)
 			if err != nil {
-				return errors.Wrap(err, "failed to set key")
+				return errors.Wrap(err, "failed to check preconditions")
 			}
+			if len(failed) > 0 {
+				return nil
+			}
+
+			for i, value := range request.Messages[written:] {
+				err = putValue(txn, value, expires[written+i], request.Lease)
+				if errors.Cause(err) == badger.ErrTxnTooBig && count > 0 && len(request.Preconditions) == 0 {
+					// commit what fits and write the rest in the next transaction
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		})
+		if errors.Cause(err) == badger.ErrTxnTooBig {
+			return nil, errors.Wrapf(ErrBatchTooLarge, "failed to set %d keys", len(request.Messages))
 		}
-	} else {
-		for _, value := range request.Messages {
-			err := wb.Set([]byte(value.Key), []byte(value.Value))
-			if err != nil {
-				return errors.Wrap(err, "failed to set key")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to set in db")
+		}
+
+		if len(failed) > 0 {
+			return &apiv1.SetValuesResponse{
+				Header:              s.responseHeader(),
+				FailedPreconditions: failed,
+			}, nil
+		}
+
+		for i, value := range request.Messages[written : written+count] {
+			if expires[written+i] != 0 {
+				s.expirations.add(value.Key, expires[written+i])
 			}
 		}
+
+		written += count
+		if written == len(request.Messages) {
+			break
+		}
 	}
 
-	err := wb.Flush()
+	return &apiv1.SetValuesResponse{
+		Header:    s.responseHeader(),
+		Succeeded: true,
+	}, nil
+}
+
+// ttlExpiry converts a ttl into the expiry badger stores, which is zero for no ttl.
+func ttlExpiry(ttlDuration *duration.Duration) (uint64, error) {
+	if ttlDuration == nil {
+		return 0, nil
+	}
+	ttl, err := ptypes.Duration(ttlDuration)
 	if err != nil {
-		return errors.Wrap(err, "failed to flush")
+		return 0, errors.Wrap(err, "failed to deserialize ttl")
 	}
+	return uint64(time.Now().Add(ttl).Unix()), nil
+}
 
-	return nil
+// messageExpiry returns the expiry of a message. An expires at or ttl set on the message overrides
+// the expiry of its request, while the expiry it was read with is ignored.
+func messageExpiry(value *apiv1.KeyValue, expire uint64) (uint64, error) {
+	if value.SetExpiresAt != nil {
+		expiresAt, err := ptypes.Timestamp(value.SetExpiresAt)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to deserialize expires at")
+		}
+		if !expiresAt.After(time.Now()) {
+			return 0, errors.New("expires at is in the past")
+		}
+		return uint64(expiresAt.Unix()), nil
+	}
+	if value.SetTtlDuration != nil {
+		return ttlExpiry(value.SetTtlDuration)
+	}
+	return expire, nil
+}
+
+// Delete removes keys and prefixes from the datastore and returns how many keys were removed. The
+// keys are found and deleted in the same transaction, which is split into several when it grows too
+// big for one.
+func (s *KVStore) Delete(request *apiv1.DeleteValuesRequest) (*apiv1.DeleteValuesResponse, error) {
+	deleted := 0
+	for done := false; !done; {
+		count := 0
+		err := s.update(func(txn *badger.Txn) error {
+			count = 0
+			done = false
+			for _, key := range request.Requests {
+				// keys deleted earlier in the transaction are no longer matched
+				matched, err := s.matchingKeys(txn, key)
+				if err != nil {
+					return err
+				}
+				for _, k := range matched {
+					err = setMarker(txn, k, userMetaDelete)
+					if errors.Cause(err) == badger.ErrTxnTooBig && count > 0 {
+						// commit what fits and delete the rest in the next transaction
+						return nil
+					}
+					if err != nil {
+						return errors.Wrap(err, "failed to delete key")
+					}
+					count++
+				}
+			}
+			done = true
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to delete keys")
+		}
+		deleted += count
+	}
+
+	return &apiv1.DeleteValuesResponse{
+		DeletedCount: int64(deleted),
+		Header:       s.responseHeader(),
+	}, nil
 }
 
 // Subscribe will subscribe to prefixes in the key value store. This will block until there is an error
 // or the context is cancelled
 func (s *KVStore) Subscribe(ctx context.Context, subscription *apiv1.SubscribeRequest, cb func(*apiv1.SubscribeResponse) error) error {
+	opts, err := getSubscriptionOptions(subscription)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case subscription.StartRevision != 0:
+		err := s.resume(ctx, subscription, opts, subscription.StartRevision, s.currentRevision(), cb)
+		if err != nil {
+			return errors.Wrap(err, "failed to resume")
+		}
+		return nil
+
+	case subscription.SkipSnapshot:
+		current := s.currentRevision()
+		err := s.resume(ctx, subscription, opts, current, current, cb)
+		if err != nil {
+			return errors.Wrap(err, "failed to subscribe")
+		}
+		return nil
+	}
+
+	for {
+		revision, err := s.snapshot(subscription, opts, cb)
+		if err != nil {
+			return errors.Wrap(err, "failed to get")
+		}
+
+		// the changes since the snapshot are still in the change log unless a lot of them were made
+		// while it was being sent, in which case a new snapshot is sent
+		err = s.resume(ctx, subscription, opts, revision, revision, cb)
+		if errors.Cause(err) == ErrCompacted {
+			continue
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to subscribe")
+		}
+		return nil
+	}
+}
+
+// snapshot sends the current values of the keys a subscription is for and returns the revision
+// they are current as of.
+func (s *KVStore) snapshot(subscription *apiv1.SubscribeRequest, opts subscriptionOptions, cb func(*apiv1.SubscribeResponse) error) (uint64, error) {
+	var revision uint64
 	err := s.db.View(func(txn *badger.Txn) error {
+		revision = txn.ReadTs()
+		header := &apiv1.ResponseHeader{
+			Revision: revision,
+		}
+		scans := []scanOptions{}
 		for _, key := range subscription.Prefixes {
-			values, err := s.prefixScan(txn, key)
+			prefix := []byte(key)
+			scans = append(scans, scanOptions{start: prefix, end: prefixEnd(prefix), prefix: prefix})
+		}
+		for _, pattern := range opts.filter.patterns {
+			scans = append(scans, scanOptions{start: pattern.prefix, end: prefixEnd(pattern.prefix), prefix: pattern.prefix, pattern: pattern})
+		}
+
+		for _, opts := range scans {
+			values := []*apiv1.KeyValue{}
+			_, err := scan(txn, opts, func(value *apiv1.KeyValue) error {
+				values = append(values, value)
+				return nil
+			})
 			if err != nil {
 				return errors.Wrap(err, "failed prefix scan")
 			}
 
+			events := []*apiv1.Event{}
+			for _, value := range values {
+				events = append(events, &apiv1.Event{
+					Type: apiv1.Event_TYPE_PUT,
+					Kv:   value,
+				})
+			}
+
 			err = cb(&apiv1.SubscribeResponse{
 				Messages: values,
+				Events:   events,
+				Header:   header,
 			})
 			if err != nil {
 				return errors.Wrap(err, "failed callback")
@@ -186,38 +459,303 @@ func (s *KVStore) Subscribe(ctx context.Context, subscription *apiv1.SubscribeRe
 		}
 		return nil
 	})
+	return revision, err
+}
+
+// subscriptionOptions are the parsed settings of a subscription request.
+type subscriptionOptions struct {
+	filter           keyFilter
+	progressInterval time.Duration
+	coalesceWindow   time.Duration
+	overflow         overflowPolicy
+	previous         bool
+}
+
+func getSubscriptionOptions(subscription *apiv1.SubscribeRequest) (subscriptionOptions, error) {
+	opts := subscriptionOptions{
+		previous: subscription.PrevKv,
+	}
+	if subscription.ProgressInterval != nil {
+		var err error
+		opts.progressInterval, err = ptypes.Duration(subscription.ProgressInterval)
+		if err != nil {
+			return opts, errors.Wrapf(ErrInvalidRequest, "invalid progress interval: %v", err)
+		}
+		if opts.progressInterval < time.Second {
+			return opts, errors.Wrap(ErrInvalidRequest, "progress interval must be at least one second")
+		}
+	}
+	if subscription.CoalesceWindow != nil {
+		var err error
+		opts.coalesceWindow, err = ptypes.Duration(subscription.CoalesceWindow)
+		if err != nil {
+			return opts, errors.Wrapf(ErrInvalidRequest, "invalid coalesce window: %v", err)
+		}
+		if opts.coalesceWindow < 0 {
+			return opts, errors.Wrap(ErrInvalidRequest, "coalesce window cannot be negative")
+		}
+	}
+
+	switch subscription.SlowConsumerPolicy {
+	case apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_UNSPECIFIED, apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DISCONNECT:
+		opts.overflow = overflowDisconnect
+	case apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST:
+		opts.overflow = overflowDropOldest
+	case apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_COALESCE:
+		opts.overflow = overflowCoalesce
+	default:
+		return opts, errors.Wrapf(ErrInvalidRequest, "unknown slow consumer policy %d", subscription.SlowConsumerPolicy)
+	}
+
+	for _, prefix := range subscription.Prefixes {
+		opts.filter.prefixes = append(opts.filter.prefixes, []byte(prefix))
+	}
+	for _, p := range subscription.Patterns {
+		pattern, err := compilePattern(p)
+		if err != nil {
+			return opts, err
+		}
+		opts.filter.patterns = append(opts.filter.patterns, pattern)
+	}
+	return opts, nil
+}
+
+// resume replays the changes after the revision from the change log, one response per revision,
+// followed by a sync response, and then sends changes as they happen along with progress responses
+// at the progress interval when it is set. Changes that happen within the coalesce window, when it
+// is set, are collapsed into one response. Current is the latest revision the subscription could
+// have seen.
+func (s *KVStore) resume(ctx context.Context, subscription *apiv1.SubscribeRequest, opts subscriptionOptions, revision, current uint64, cb func(*apiv1.SubscribeResponse) error) error {
+	replay, listener, err := s.changes.listen(revision, current, opts.filter, opts.overflow, opts.previous)
 	if err != nil {
-		return errors.Wrap(err, "failed to get")
+		return err
 	}
+	defer s.changes.remove(listener)
 
-	prefixes := [][]byte{}
-	for _, p := range subscription.Prefixes {
-		prefixes = append(prefixes, []byte(p))
+	// changes appended while no listener wanted the values they replaced are sent without them, so
+	// those are read now
+	buildResponse := func(kvs []*pb.KV) (*apiv1.SubscribeResponse, error) {
+		if opts.previous {
+			var err error
+			kvs, err = withPrevious(s.db, kvs)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to read previous values")
+			}
+		}
+		return subscribeResponse(kvs, opts.previous)
 	}
 
-	err = s.db.Subscribe(ctx, func(kv *badger.KVList) error {
-		values := []*apiv1.KeyValue{}
-		for _, kv := range kv.Kv {
-			values = append(values, &apiv1.KeyValue{
-				Key:   string(kv.Key),
-				Value: kv.Value,
-			})
+	synced := revision
+
+	for len(replay) > 0 {
+		n := 1
+		for n < len(replay) && replay[n].Version == replay[0].Version {
+			n++
 		}
 
-		err := cb(&apiv1.SubscribeResponse{
-			Messages: values,
-		})
+		response, err := buildResponse(replay[:n])
+		if err != nil {
+			return err
+		}
+		synced = replay[0].Version
+		replay = replay[n:]
+		if len(response.Events) == 0 {
+			continue
+		}
+		err = cb(response)
 		if err != nil {
 			return errors.Wrap(err, "failed callback")
 		}
+	}
 
-		return nil
-	}, prefixes...)
+	err = cb(&apiv1.SubscribeResponse{
+		Messages: []*apiv1.KeyValue{},
+		Events:   []*apiv1.Event{},
+		Header: &apiv1.ResponseHeader{
+			Revision: synced,
+		},
+		Sync: true,
+	})
 	if err != nil {
-		return errors.Wrap(err, "failed to subscribe")
+		return errors.Wrap(err, "failed callback")
 	}
 
-	return nil
+	pending := newCoalescer()
+	var flush <-chan time.Time
+
+	send := func(kvs []*pb.KV) error {
+		// changes committed before the snapshot may be appended to the log after listening
+		newer := []*pb.KV{}
+		for _, kv := range kvs {
+			if kv.Version > synced {
+				newer = append(newer, kv)
+			}
+		}
+		response, err := buildResponse(newer)
+		if err != nil {
+			return err
+		}
+		if len(response.Events) == 0 {
+			return nil
+		}
+		if opts.coalesceWindow > 0 {
+			if len(pending.events) == 0 {
+				flush = time.After(opts.coalesceWindow)
+			}
+			pending.add(response.Events)
+			return nil
+		}
+		err = cb(response)
+		if err != nil {
+			return errors.Wrap(err, "failed callback")
+		}
+		return nil
+	}
+
+	sendPending := func() error {
+		flush = nil
+		if len(pending.events) == 0 {
+			return nil
+		}
+		err := cb(pending.response())
+		if err != nil {
+			return errors.Wrap(err, "failed callback")
+		}
+		return nil
+	}
+
+	sendQueued := func() error {
+		batches, dropped, err := listener.take()
+		if err != nil {
+			return err
+		}
+		if dropped.count > 0 {
+			// changes still waiting in the coalesce window came before the ones left out
+			err = sendPending()
+			if err != nil {
+				return err
+			}
+			err = cb(&apiv1.SubscribeResponse{
+				Messages: []*apiv1.KeyValue{},
+				Events:   []*apiv1.Event{},
+				Header: &apiv1.ResponseHeader{
+					Revision: dropped.revision,
+				},
+				Dropped: uint64(dropped.count),
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed callback")
+			}
+		}
+		for _, kvs := range batches {
+			err = send(kvs)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var progress <-chan time.Time
+	if opts.progressInterval > 0 {
+		ticker := time.NewTicker(opts.progressInterval)
+		defer ticker.Stop()
+		progress = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case <-listener.ready:
+			err := sendQueued()
+			if err != nil {
+				return err
+			}
+
+		case <-flush:
+			err := sendPending()
+			if err != nil {
+				return err
+			}
+
+		case <-progress:
+			// every change up to the revision of the log has been queued for the listener by now
+			progressed := s.changes.revision()
+			err := sendQueued()
+			if err != nil {
+				return err
+			}
+			err = sendPending()
+			if err != nil {
+				return err
+			}
+			if progressed < synced {
+				progressed = synced
+			}
+
+			err = cb(&apiv1.SubscribeResponse{
+				Messages: []*apiv1.KeyValue{},
+				Events:   []*apiv1.Event{},
+				Header: &apiv1.ResponseHeader{
+					Revision: progressed,
+				},
+				Progress: true,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed callback")
+			}
+		}
+	}
+}
+
+// SweepExpired records the expiration of keys whose ttl has lapsed so subscribers are notified, and
+// revokes leases that were not kept alive. Expirations that fail to be recorded are tried again on
+// the next sweep, and the first error is returned once everything else has been swept.
+func (s *KVStore) SweepExpired() error {
+	var sweepErr error
+	for _, e := range s.expirations.popExpired(uint64(time.Now().Unix())) {
+		key := []byte(e.key)
+		err := s.db.Update(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.AllVersions = true
+			opts.PrefetchValues = false
+			opts.Prefix = key
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			it.Seek(key)
+			if !it.Valid() {
+				return nil
+			}
+			item := it.Item()
+			if !bytes.Equal(item.Key(), key) || item.ExpiresAt() != e.expiresAt {
+				return nil // the key has been written again since
+			}
+			if item.UserMeta()&(userMetaDelete|userMetaExpire) != 0 {
+				return nil
+			}
+
+			return txn.SetEntry(markerEntry(key, userMetaExpire))
+		})
+		if err == badger.ErrConflict { // the key was written while sweeping, so it did not expire
+			continue
+		}
+		if err != nil {
+			s.expirations.add(e.key, e.expiresAt)
+			if sweepErr == nil {
+				sweepErr = errors.Wrapf(err, "failed to record expiration of %q", e.key)
+			}
+		}
+	}
+
+	err := s.sweepExpiredLeases()
+	if err != nil && sweepErr == nil {
+		sweepErr = errors.Wrap(err, "failed to sweep leases")
+	}
+
+	return sweepErr
 }
 
 // GarbageCollect cleans up old values in log files
@@ -233,27 +771,290 @@ func (s *KVStore) GarbageCollect() error {
 	return nil
 }
 
+// Close closes the datastore. It returns why the change log stopped when it did before being closed.
+func (s *KVStore) Close() error {
+	s.stopChanges()
+	<-s.changes.stopped
+
+	err := s.db.Close()
+	if err != nil {
+		return err
+	}
+	if errors.Cause(s.changes.err) != errChangeLogStopped {
+		return s.changes.err
+	}
+	return nil
+}
+
+// getValue retrieves the key values for a request, along with the start after that continues it
+// when it was limited.
+func (s *KVStore) getValue(txn *badger.Txn, key *apiv1.GetValuesRequest_GetValue, at *pointInTime) ([]*apiv1.KeyValue, string, error) {
+	values := []*apiv1.KeyValue{}
+	more, err := s.visitValues(txn, key, at, func(value *apiv1.KeyValue) error {
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	if !more || len(values) == 0 {
+		return values, "", nil
+	}
+	return values, values[len(values)-1].Key, nil
+}
+
+// visitValues calls fn with each key value for a request, as of the point in time when it is set.
+// It returns true when the request was limited before the keys ran out.
+func (s *KVStore) visitValues(txn *badger.Txn, key *apiv1.GetValuesRequest_GetValue, at *pointInTime, fn func(*apiv1.KeyValue) error) (bool, error) {
+	return s.visitItems(txn, key, key.KeysOnly, at, func(item *badger.Item) error {
+		value, err := scanKeyValue(item, key.KeysOnly)
+		if err != nil {
+			return errors.Wrap(err, "failed to get value")
+		}
+		return fn(value)
+	})
+}
+
+// visitItems calls fn with each item for a request, as of the point in time when it is set. Values
+// are not prefetched when keysOnly is set.
+func (s *KVStore) visitItems(txn *badger.Txn, key *apiv1.GetValuesRequest_GetValue, keysOnly bool, at *pointInTime, fn func(*badger.Item) error) (bool, error) {
+	if key.IsPrefix || key.RangeEnd != "" || (key.Pattern != nil && key.Key == "") {
+		opts, err := getScanOptions(key)
+		if err != nil {
+			return false, err
+		}
+		opts.keysOnly = keysOnly
+		opts.at = at
+		more, err := scanItems(txn, opts, fn)
+		if err != nil {
+			return false, errors.Wrap(err, "failed prefix scan")
+		}
+		return more, nil
+	}
+
+	if isInternal([]byte(key.Key)) {
+		return false, nil
+	}
+	if key.Pattern != nil {
+		pattern, err := compilePattern(key.Pattern)
+		if err != nil {
+			return false, err
+		}
+		if !pattern.matches([]byte(key.Key)) {
+			return false, nil
+		}
+	}
+	if at != nil {
+		_, err := at.visit(txn, []byte(key.Key), fn)
+		return false, err
+	}
+	item, err := txn.Get([]byte(key.Key))
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get key")
+	}
+
+	return false, fn(item)
+}
+
+func putValue(txn *badger.Txn, value *apiv1.KeyValue, expire uint64, lease int64) error {
+	err := validateKey(value.Key)
+	if err != nil {
+		return err
+	}
+
+	key := []byte(value.Key)
+	header, err := nextHeader(txn, key, lease)
+	if err != nil {
+		return err
+	}
+
+	if lease != 0 {
+		err = attachLease(txn, lease, key)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = txn.SetEntry(&badger.Entry{
+		Key:       key,
+		Value:     encodeValue(header, value.Value),
+		UserMeta:  header.userMeta(),
+		ExpiresAt: expire,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to set key")
+	}
+	return nil
+}
+
+// matchingKeys returns the existing keys a delete request applies to.
+func (s *KVStore) matchingKeys(txn *badger.Txn, key *apiv1.DeleteValuesRequest_DeleteValue) ([][]byte, error) {
+	if key.IsPrefix {
+		return s.prefixKeys(txn, key.Key), nil
+	}
+
+	if isInternal([]byte(key.Key)) {
+		return [][]byte{}, nil
+	}
+	_, err := txn.Get([]byte(key.Key))
+	if err == badger.ErrKeyNotFound {
+		return [][]byte{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get key")
+	}
+	return [][]byte{[]byte(key.Key)}, nil
+}
+
 func (s *KVStore) prefixScan(txn *badger.Txn, prefixKey string) ([]*apiv1.KeyValue, error) {
 	values := []*apiv1.KeyValue{}
 
-	it := txn.NewIterator(badger.DefaultIteratorOptions)
+	prefix := []byte(prefixKey)
+	_, err := scan(txn, scanOptions{start: prefix, end: prefixEnd(prefix), prefix: prefix}, func(value *apiv1.KeyValue) error {
+		values = append(values, value)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func (s *KVStore) prefixKeys(txn *badger.Txn, prefixKey string) [][]byte {
+	keys := [][]byte{}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
 	defer it.Close()
 
 	prefix := []byte(prefixKey)
 	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
-		item := it.Item()
-		k := item.Key()
-		err := item.Value(func(v []byte) error {
-			values = append(values, &apiv1.KeyValue{
-				Key:   string(k),
-				Value: append([]byte{}, v...),
-			})
-			return nil
-		})
+		if isInternal(it.Item().Key()) {
+			continue
+		}
+		keys = append(keys, it.Item().KeyCopy(nil))
+	}
+
+	return keys
+}
+
+func (s *KVStore) loadExpirations() error {
+	return s.db.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if item.ExpiresAt() == 0 {
+				continue
+			}
+			s.expirations.add(string(item.Key()), item.ExpiresAt())
+		}
+		return nil
+	})
+}
+
+// markerEntry creates an already expired entry that hides the key from reads while telling
+// subscribers why it went away.
+func markerEntry(key []byte, userMeta byte) *badger.Entry {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(time.Now().UnixNano()))
+	return &badger.Entry{
+		Key:       key,
+		Value:     buf[:n],
+		UserMeta:  userMeta | userMetaTime,
+		ExpiresAt: 1,
+	}
+}
+
+// previousVersion identifies the change a previous value was replaced by.
+type previousVersion struct {
+	key     string
+	version uint64
+}
+
+// subscribeResponse builds a response from changes, leaving out the internal keyspace. Each event
+// carries the value it replaced when prevKV is set.
+func subscribeResponse(kvs []*pb.KV, prevKV bool) (*apiv1.SubscribeResponse, error) {
+	response := &apiv1.SubscribeResponse{
+		Messages: []*apiv1.KeyValue{},
+		Events:   []*apiv1.Event{},
+		Header:   &apiv1.ResponseHeader{},
+	}
+
+	previous := map[previousVersion]*apiv1.KeyValue{}
+	for _, kv := range kvs {
+		if !prevKV {
+			break
+		}
+		if _, ok := previousTarget(kv.Key); !ok {
+			continue
+		}
+		value, err := decodePrevious(kv)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to get value")
+			return nil, errors.Wrap(err, "failed to read previous value")
 		}
+		previous[previousVersion{key: value.Key, version: kv.Version}] = value
 	}
 
-	return values, nil
+	for _, kv := range kvs {
+		if isInternal(kv.Key) {
+			continue
+		}
+		event, err := eventFromKV(kv)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read event")
+		}
+		event.PrevKv = previous[previousVersion{key: string(kv.Key), version: kv.Version}]
+		if event.Type == apiv1.Event_TYPE_PUT {
+			response.Messages = append(response.Messages, event.Kv)
+		}
+		response.Events = append(response.Events, event)
+		if kv.Version > response.Header.Revision {
+			response.Header.Revision = kv.Version
+		}
+	}
+	return response, nil
+}
+
+func eventFromKV(kv *pb.KV) (*apiv1.Event, error) {
+	var meta byte
+	if len(kv.Meta) > 0 {
+		meta = kv.Meta[0]
+	}
+
+	event := &apiv1.Event{
+		Type: apiv1.Event_TYPE_PUT,
+		Meta: uint32(meta),
+	}
+	switch {
+	case meta&userMetaDelete != 0:
+		event.Type = apiv1.Event_TYPE_DELETE
+	case meta&userMetaExpire != 0:
+		event.Type = apiv1.Event_TYPE_EXPIRE
+	}
+
+	if event.Type != apiv1.Event_TYPE_PUT {
+		event.Kv = &apiv1.KeyValue{
+			Key:         string(kv.Key),
+			ModRevision: kv.Version,
+		}
+		return event, nil
+	}
+
+	value, err := decodeValue(kv.Key, meta, kv.Version, kv.Value)
+	if err != nil {
+		return nil, err
+	}
+	setExpiry(value, kv.ExpiresAt)
+	event.Kv = value
+	return event, nil
 }
@@ -4,11 +4,19 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"math"
+	"sort"
 	"sync"
 	"testing"
 	"time"
 
+	badger "github.com/dgraph-io/badger/v2"
 	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/syncromatics/kvetch/internal/datastore"
 	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
 
@@ -22,7 +30,7 @@ func Test_GetPrefix(t *testing.T) {
 	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
 	assert.NilError(t, err)
 
-	err = store.Set(&apiv1.SetValuesRequest{
+	_, err = store.Set(&apiv1.SetValuesRequest{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
 				Key:   "test/1/stuff",
@@ -53,14 +61,23 @@ func Test_GetPrefix(t *testing.T) {
 	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
-				Key:   "test/1/stuff",
-				Value: []byte("value 1"),
+				Key:            "test/1/stuff",
+				Value:          []byte("value 1"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 			&apiv1.KeyValue{
-				Key:   "test/1/stuff2",
-				Value: []byte("value 2"),
+				Key:            "test/1/stuff2",
+				Value:          []byte("value 2"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 2,
+		},
 	})
 }
 
@@ -71,7 +88,7 @@ func Test_Get(t *testing.T) {
 	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
 	assert.NilError(t, err)
 
-	err = store.Set(&apiv1.SetValuesRequest{
+	_, err = store.Set(&apiv1.SetValuesRequest{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
 				Key:   "test/1/stuff",
@@ -107,18 +124,30 @@ func Test_Get(t *testing.T) {
 	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
-				Key:   "test/1/stuff",
-				Value: []byte("value 1"),
+				Key:            "test/1/stuff",
+				Value:          []byte("value 1"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 			&apiv1.KeyValue{
-				Key:   "test/1/stuff2",
-				Value: []byte("value 2 longer"),
+				Key:            "test/1/stuff2",
+				Value:          []byte("value 2 longer"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 			&apiv1.KeyValue{
-				Key:   "test/2/stuff",
-				Value: []byte("bad value"),
+				Key:            "test/2/stuff",
+				Value:          []byte("bad value"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 2,
+		},
 	})
 }
 
@@ -130,7 +159,7 @@ func Test_TTL(t *testing.T) {
 	assert.NilError(t, err)
 
 	ttl := 2 * time.Second
-	err = store.Set(&apiv1.SetValuesRequest{
+	_, err = store.Set(&apiv1.SetValuesRequest{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
 				Key:   "test/1/stuff",
@@ -164,21 +193,42 @@ func Test_TTL(t *testing.T) {
 	})
 	assert.NilError(t, err)
 
+	for _, message := range values.Messages {
+		assert.Assert(t, message.ExpiresAt != nil)
+		remaining, err := ptypes.Duration(message.TtlDuration)
+		assert.NilError(t, err)
+		assert.Assert(t, remaining > 0 && remaining <= ttl+time.Second)
+		message.ExpiresAt = nil
+		message.TtlDuration = nil
+	}
+
 	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
-				Key:   "test/1/stuff",
-				Value: []byte("value 1"),
+				Key:            "test/1/stuff",
+				Value:          []byte("value 1"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 			&apiv1.KeyValue{
-				Key:   "test/1/stuff2",
-				Value: []byte("value 2 longer"),
+				Key:            "test/1/stuff2",
+				Value:          []byte("value 2 longer"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 			&apiv1.KeyValue{
-				Key:   "test/2/stuff",
-				Value: []byte("bad value"),
+				Key:            "test/2/stuff",
+				Value:          []byte("bad value"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
 			},
 		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 2,
+		},
 	})
 
 	time.Sleep(ttl)
@@ -209,7 +259,7 @@ func Test_Subscribe(t *testing.T) {
 	values := []*apiv1.KeyValue{}
 	mtx := sync.Mutex{}
 
-	err = store.Set(&apiv1.SetValuesRequest{
+	_, err = store.Set(&apiv1.SetValuesRequest{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
 				Key:   "subscribe/5/serial1",
@@ -240,7 +290,7 @@ func Test_Subscribe(t *testing.T) {
 
 	time.Sleep(10 * time.Millisecond)
 
-	err = store.Set(&apiv1.SetValuesRequest{
+	_, err = store.Set(&apiv1.SetValuesRequest{
 		Messages: []*apiv1.KeyValue{
 			&apiv1.KeyValue{
 				Key:   "subscribe/5/serial1",
@@ -268,14 +318,3074 @@ func Test_Subscribe(t *testing.T) {
 
 		assert.DeepEqual(t, values, []*apiv1.KeyValue{
 			&apiv1.KeyValue{
-				Key:   "subscribe/5/serial1",
+				Key:            "subscribe/5/serial1",
+				Value:          []byte("value 1"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
+			},
+			&apiv1.KeyValue{
+				Key:            "subscribe/5/serial1",
+				Value:          []byte("value 1 1"),
+				CreateRevision: 2,
+				ModRevision:    3,
+				Version:        2,
+			},
+		})
+		break
+	}
+}
+
+func Test_Delete(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Delete")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "test/1/stuff",
 				Value: []byte("value 1"),
 			},
 			&apiv1.KeyValue{
-				Key:   "subscribe/5/serial1",
-				Value: []byte("value 1 1"),
+				Key:   "test/1/stuff2",
+				Value: []byte("value 2"),
+			},
+			&apiv1.KeyValue{
+				Key:   "test/2/stuff",
+				Value: []byte("value 3"),
+			},
+			&apiv1.KeyValue{
+				Key:   "test/3/stuff",
+				Value: []byte("good value"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	response, err := store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key:      "test/1",
+				IsPrefix: true,
+			},
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "test/1/stuff",
+			},
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "test/2/stuff",
+			},
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "test/4/missing",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, response.DeletedCount, int64(3))
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "test/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:            "test/3/stuff",
+				Value:          []byte("good value"),
+				CreateRevision: 2,
+				ModRevision:    2,
+				Version:        1,
+			},
+		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 3,
+		},
+	})
+}
+
+func Test_SubscribeEvents(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeEvents")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	events := []*apiv1.Event{}
+	mtx := sync.Mutex{}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes: []string{"events/"},
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			events = append(events, msg.Events...)
+			return nil
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "events/empty",
+				Value: []byte{},
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "events/empty",
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "events/ttl",
+				Value: []byte("value 1"),
 			},
+		},
+		TtlDuration: ptypes.DurationProto(time.Second),
+	})
+	assert.NilError(t, err)
+
+	time.Sleep(2 * time.Second)
+	err = store.SweepExpired()
+	assert.NilError(t, err)
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		count := 0
+		mtx.Lock()
+		count = len(events)
+		mtx.Unlock()
+
+		if count != 4 {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		types := []apiv1.Event_Type{}
+		keys := []string{}
+		for _, event := range events {
+			types = append(types, event.Type)
+			keys = append(keys, event.Kv.Key)
+		}
+		assert.DeepEqual(t, types, []apiv1.Event_Type{
+			apiv1.Event_TYPE_PUT,
+			apiv1.Event_TYPE_DELETE,
+			apiv1.Event_TYPE_PUT,
+			apiv1.Event_TYPE_EXPIRE,
+		})
+		assert.DeepEqual(t, keys, []string{
+			"events/empty",
+			"events/empty",
+			"events/ttl",
+			"events/ttl",
 		})
 		break
 	}
 }
+
+func Test_Revisions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Revisions")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "revisions/1",
+					Value: []byte(fmt.Sprintf("value %d", i)),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	response, err := store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "revisions/2",
+				Value: []byte("value"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, response.Header.Revision, uint64(5))
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "revisions/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:            "revisions/1",
+				Value:          []byte("value 2"),
+				CreateRevision: 2,
+				ModRevision:    4,
+				Version:        3,
+			},
+			&apiv1.KeyValue{
+				Key:            "revisions/2",
+				Value:          []byte("value"),
+				CreateRevision: 5,
+				ModRevision:    5,
+				Version:        1,
+			},
+		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 5,
+		},
+	})
+
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "revisions/1",
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "revisions/1",
+				Value: []byte("recreated"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	values, err = store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "revisions/1",
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:            "revisions/1",
+				Value:          []byte("recreated"),
+				CreateRevision: 7,
+				ModRevision:    7,
+				Version:        1,
+			},
+		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 7,
+		},
+	})
+}
+
+func Test_SubscribeFromRevision(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeFromRevision")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	for i := 0; i < 3; i++ {
+		_, err = store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "resume/1",
+					Value: []byte(fmt.Sprintf("value %d", i)),
+				},
+				&apiv1.KeyValue{
+					Key:   "other/1",
+					Value: []byte(fmt.Sprintf("value %d", i)),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	values := []*apiv1.KeyValue{}
+	mtx := sync.Mutex{}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:      []string{"resume/"},
+			StartRevision: 2,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			values = append(values, msg.Messages...)
+			return nil
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "resume/1",
+				Value: []byte("value 3"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		count := 0
+		mtx.Lock()
+		count = len(values)
+		mtx.Unlock()
+
+		if count != 3 {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		mtx.Lock()
+		revisions := []uint64{}
+		for _, value := range values {
+			revisions = append(revisions, value.ModRevision)
+		}
+		mtx.Unlock()
+		assert.DeepEqual(t, revisions, []uint64{3, 4, 5})
+		break
+	}
+}
+
+func Test_SubscribeFromCompactedRevision(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeFromCompactedRevision")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		ChangeLogSize: &wrappers.Int64Value{Value: 2},
+	})
+	assert.NilError(t, err)
+
+	for i := 0; i < 4; i++ {
+		_, err = store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "resume/1",
+					Value: []byte(fmt.Sprintf("value %d", i)),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	err = store.Subscribe(context.Background(), &apiv1.SubscribeRequest{
+		Prefixes:      []string{"resume/"},
+		StartRevision: 1,
+	}, func(msg *apiv1.SubscribeResponse) error {
+		return nil
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrCompacted)
+}
+
+func Test_SubscribeFromOpen(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeFromOpen")
+	assert.NilError(t, err)
+
+	for i := 0; i < 3; i++ {
+		store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+		assert.NilError(t, err)
+
+		response, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "open/1",
+					Value: []byte(fmt.Sprintf("value %d", i)),
+				},
+			},
+		})
+		assert.NilError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		values := []string{}
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:      []string{"open/"},
+			StartRevision: response.Header.Revision - 1,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			for _, value := range msg.Messages {
+				values = append(values, string(value.Value))
+			}
+			return nil
+		})
+		cancel()
+		assert.DeepEqual(t, values, []string{fmt.Sprintf("value %d", i)})
+
+		err = store.Close()
+		assert.NilError(t, err)
+	}
+}
+
+func Test_SubscribeResnapshot(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeResnapshot")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		ChangeLogSize: &wrappers.Int64Value{Value: 2},
+	})
+	assert.NilError(t, err)
+
+	set := func(value string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "resnapshot/1",
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+	set("value 0")
+
+	snapshotting := make(chan struct{})
+	written := make(chan struct{})
+	values := []string{}
+	mtx := sync.Mutex{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	subscribed := make(chan error)
+	go func() {
+		subscribed <- store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes: []string{"resnapshot/"},
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			for _, value := range msg.Messages {
+				values = append(values, string(value.Value))
+			}
+			first := len(values) == 1
+			mtx.Unlock()
+
+			// hold the first snapshot until more changes than the change log keeps are made
+			if first {
+				close(snapshotting)
+				<-written
+			}
+			return nil
+		})
+	}()
+
+	<-snapshotting
+	for i := 1; i <= 4; i++ {
+		set(fmt.Sprintf("value %d", i))
+	}
+	close(written)
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 10*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		mtx.Lock()
+		count := len(values)
+		mtx.Unlock()
+
+		if count < 2 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		break
+	}
+
+	mtx.Lock()
+	assert.DeepEqual(t, values, []string{"value 0", "value 4"})
+	mtx.Unlock()
+
+	cancel()
+	err = <-subscribed
+	assert.Equal(t, errors.Cause(err), context.Canceled)
+}
+
+func Test_CloseEndsSubscriptions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_CloseEndsSubscriptions")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	subscribed := make(chan error)
+	go func() {
+		subscribed <- store.Subscribe(context.Background(), &apiv1.SubscribeRequest{
+			Prefixes: []string{"close/"},
+		}, func(msg *apiv1.SubscribeResponse) error {
+			return nil
+		})
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	err = store.Close()
+	assert.NilError(t, err)
+
+	select {
+	case err = <-subscribed:
+		assert.ErrorContains(t, err, "change log stopped")
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for the subscription to end")
+	}
+}
+
+func Test_SetPreconditions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SetPreconditions")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	response, err := store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "config/shared",
+				Value: []byte("value 1"),
+			},
+		},
+		Preconditions: []*apiv1.Precondition{
+			&apiv1.Precondition{
+				Key:       "config/shared",
+				Condition: &apiv1.Precondition_Exists{Exists: false},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, response.Succeeded)
+
+	failed := &apiv1.Precondition{
+		Key:       "config/shared",
+		Condition: &apiv1.Precondition_ModRevisionEquals{ModRevisionEquals: 6},
+	}
+	response, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "config/shared",
+				Value: []byte("value 2"),
+			},
+			&apiv1.KeyValue{
+				Key:   "config/other",
+				Value: []byte("value 2"),
+			},
+		},
+		Preconditions: []*apiv1.Precondition{
+			&apiv1.Precondition{
+				Key:       "config/shared",
+				Condition: &apiv1.Precondition_ValueEquals{ValueEquals: []byte("value 1")},
+			},
+			failed,
+		},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, !response.Succeeded)
+	assert.DeepEqual(t, response.FailedPreconditions, []*apiv1.Precondition{failed})
+
+	response, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "config/shared",
+				Value: []byte("value 3"),
+			},
+		},
+		Preconditions: []*apiv1.Precondition{
+			&apiv1.Precondition{
+				Key:       "config/shared",
+				Condition: &apiv1.Precondition_ModRevisionEquals{ModRevisionEquals: 2},
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, response.Succeeded)
+
+	// preconditions cannot look into the internal keyspace
+	for _, key := range []string{"\x00kvetch/leases/1", "!badger!head"} {
+		_, err = store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "config/shared",
+					Value: []byte("value 4"),
+				},
+			},
+			Preconditions: []*apiv1.Precondition{
+				&apiv1.Precondition{
+					Key:       key,
+					Condition: &apiv1.Precondition_Exists{Exists: false},
+				},
+			},
+		})
+		assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest, "precondition on %q", key)
+	}
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "config/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:            "config/shared",
+				Value:          []byte("value 3"),
+				CreateRevision: 2,
+				ModRevision:    3,
+				Version:        2,
+			},
+		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 3,
+		},
+	})
+}
+
+func Test_SetLargeBatch(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SetLargeBatch")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	messages := []*apiv1.KeyValue{}
+	for i := 0; i < 200000; i++ {
+		messages = append(messages, &apiv1.KeyValue{
+			Key:   fmt.Sprintf("batch/%06d", i),
+			Value: []byte("value"),
+		})
+	}
+
+	response, err := store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, response.Succeeded)
+
+	response, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, response.Succeeded)
+
+	count, err := store.CountKeys(&apiv1.CountKeysRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "batch/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, count.Count, int64(len(messages)))
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+		Preconditions: []*apiv1.Precondition{
+			&apiv1.Precondition{
+				Key:       "batch/000000",
+				Condition: &apiv1.Precondition_Exists{Exists: true},
+			},
+		},
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrBatchTooLarge)
+
+	operations := []*apiv1.TxnOperation{}
+	for _, message := range messages {
+		operations = append(operations, &apiv1.TxnOperation{
+			Operation: &apiv1.TxnOperation_Set{Set: &apiv1.TxnOperation_SetValue{Message: message}},
+		})
+	}
+	_, err = store.Txn(&apiv1.TxnRequest{
+		Success: operations,
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrBatchTooLarge)
+}
+
+func Test_Txn(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Txn")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "queue/job1",
+				Value: []byte("job"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	move := &apiv1.TxnRequest{
+		Compare: []*apiv1.Precondition{
+			&apiv1.Precondition{
+				Key:       "queue/job1",
+				Condition: &apiv1.Precondition_Exists{Exists: true},
+			},
+		},
+		Success: []*apiv1.TxnOperation{
+			&apiv1.TxnOperation{
+				Operation: &apiv1.TxnOperation_Delete{Delete: &apiv1.DeleteValuesRequest_DeleteValue{
+					Key: "queue/job1",
+				}},
+			},
+			&apiv1.TxnOperation{
+				Operation: &apiv1.TxnOperation_Set{Set: &apiv1.TxnOperation_SetValue{
+					Message: &apiv1.KeyValue{
+						Key:   "running/job1",
+						Value: []byte("job"),
+					},
+				}},
+			},
+		},
+		Failure: []*apiv1.TxnOperation{
+			&apiv1.TxnOperation{
+				Operation: &apiv1.TxnOperation_Get{Get: &apiv1.GetValuesRequest_GetValue{
+					Key:      "running/",
+					IsPrefix: true,
+				}},
+			},
+		},
+	}
+
+	response, err := store.Txn(move)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, response, &apiv1.TxnResponse{
+		Header: &apiv1.ResponseHeader{
+			Revision: 3,
+		},
+		Succeeded: true,
+		Results: []*apiv1.TxnOperationResult{
+			&apiv1.TxnOperationResult{
+				DeletedCount: 1,
+			},
+			&apiv1.TxnOperationResult{},
+		},
+	})
+
+	response, err = store.Txn(move)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, response, &apiv1.TxnResponse{
+		Header: &apiv1.ResponseHeader{
+			Revision: 3,
+		},
+		Succeeded: false,
+		Results: []*apiv1.TxnOperationResult{
+			&apiv1.TxnOperationResult{
+				Messages: []*apiv1.KeyValue{
+					&apiv1.KeyValue{
+						Key:            "running/job1",
+						Value:          []byte("job"),
+						CreateRevision: 3,
+						ModRevision:    3,
+						Version:        1,
+					},
+				},
+			},
+		},
+	})
+}
+
+func Test_TxnInvalid(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_TxnInvalid")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	requests := []*apiv1.TxnRequest{
+		&apiv1.TxnRequest{Success: []*apiv1.TxnOperation{nil}},
+		&apiv1.TxnRequest{Success: []*apiv1.TxnOperation{&apiv1.TxnOperation{}}},
+		&apiv1.TxnRequest{Success: []*apiv1.TxnOperation{&apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Set{}}}},
+		&apiv1.TxnRequest{Success: []*apiv1.TxnOperation{&apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Set{Set: &apiv1.TxnOperation_SetValue{}}}}},
+		&apiv1.TxnRequest{Failure: []*apiv1.TxnOperation{&apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Delete{}}}},
+		&apiv1.TxnRequest{Failure: []*apiv1.TxnOperation{&apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Get{}}}},
+		&apiv1.TxnRequest{Compare: []*apiv1.Precondition{nil}},
+		&apiv1.TxnRequest{Compare: []*apiv1.Precondition{&apiv1.Precondition{Key: "txn/1"}}},
+	}
+	for _, request := range requests {
+		_, err = store.Txn(request)
+		assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest, "request %v", request)
+	}
+}
+
+func Test_Leases(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Leases")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(time.Minute),
+	}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, lease.Id, int64(1))
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "services/api",
+				Value: []byte("10.0.0.1"),
+			},
+		},
+		Lease: lease.Id,
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "services/db",
+				Value: []byte("10.0.0.2"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "services/cache",
+				Value: []byte("10.0.0.3"),
+			},
+		},
+		Lease: 42,
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrLeaseNotFound)
+
+	ttl, err := store.LeaseTimeToLive(&apiv1.LeaseTimeToLiveRequest{
+		Id:   lease.Id,
+		Keys: true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ttl.Keys, []string{"services/api"})
+	assert.Equal(t, ttl.GrantedTtlDuration.Seconds, int64(60))
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "services/api",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, values.Messages, []*apiv1.KeyValue{
+		&apiv1.KeyValue{
+			Key:            "services/api",
+			Value:          []byte("10.0.0.1"),
+			CreateRevision: 3,
+			ModRevision:    3,
+			Version:        1,
+			Lease:          lease.Id,
+		},
+	})
+
+	_, err = store.RevokeLease(&apiv1.RevokeLeaseRequest{
+		Id: lease.Id,
+	})
+	assert.NilError(t, err)
+
+	values, err = store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "services/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(values.Messages), 1)
+	assert.Equal(t, values.Messages[0].Key, "services/db")
+
+	_, err = store.LeaseTimeToLive(&apiv1.LeaseTimeToLiveRequest{
+		Id: lease.Id,
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrLeaseNotFound)
+}
+
+func Test_LeaseExpiry(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_LeaseExpiry")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(time.Second),
+	}, "")
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "services/api",
+				Value: []byte("10.0.0.1"),
+			},
+		},
+		Lease: lease.Id,
+	})
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan *apiv1.Event, 10)
+	go store.Subscribe(ctx, &apiv1.SubscribeRequest{
+		Prefixes: []string{"services/"},
+	}, func(response *apiv1.SubscribeResponse) error {
+		for _, event := range response.Events {
+			events <- event
+		}
+		return nil
+	})
+	time.Sleep(500 * time.Millisecond)
+	keepAlive, err := store.KeepAlive(&apiv1.KeepAliveRequest{
+		Id: lease.Id,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, keepAlive.TtlDuration.Seconds, int64(1))
+
+	time.Sleep(700 * time.Millisecond)
+	err = store.SweepExpired()
+	assert.NilError(t, err)
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "services/api",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(values.Messages), 1)
+
+	time.Sleep(500 * time.Millisecond)
+	err = store.SweepExpired()
+	assert.NilError(t, err)
+
+	values, err = store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "services/api",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(values.Messages), 0)
+
+	event := <-events
+	for event.Type == apiv1.Event_TYPE_PUT {
+		event = <-events
+	}
+	assert.Equal(t, event.Type, apiv1.Event_TYPE_EXPIRE)
+	assert.Equal(t, event.Kv.Key, "services/api")
+
+	_, err = store.KeepAlive(&apiv1.KeepAliveRequest{
+		Id: lease.Id,
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrLeaseNotFound)
+}
+
+func Test_RevokeLargeLease(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_RevokeLargeLease")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(time.Minute),
+	}, "")
+	assert.NilError(t, err)
+
+	messages := []*apiv1.KeyValue{}
+	for i := 0; i < 100000; i++ {
+		messages = append(messages, &apiv1.KeyValue{
+			Key:   fmt.Sprintf("leased/%06d", i),
+			Value: []byte("value"),
+		})
+	}
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+		Lease:    lease.Id,
+	})
+	assert.NilError(t, err)
+
+	_, err = store.RevokeLease(&apiv1.RevokeLeaseRequest{
+		Id: lease.Id,
+	})
+	assert.NilError(t, err)
+
+	count, err := store.CountKeys(&apiv1.CountKeysRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "leased/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, count.Count, int64(0))
+
+	_, err = store.LeaseTimeToLive(&apiv1.LeaseTimeToLiveRequest{
+		Id: lease.Id,
+	})
+	assert.Equal(t, errors.Cause(err), datastore.ErrLeaseNotFound)
+}
+
+func Test_LeasesSurviveRestart(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_LeasesSurviveRestart")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(time.Minute),
+	}, "alice")
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "services/api",
+				Value: []byte("10.0.0.1"),
+			},
+		},
+		Lease: lease.Id,
+	})
+	assert.NilError(t, err)
+
+	err = store.Close()
+	assert.NilError(t, err)
+
+	store, err = datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	ttl, err := store.LeaseTimeToLive(&apiv1.LeaseTimeToLiveRequest{
+		Id:   lease.Id,
+		Keys: true,
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, ttl.Keys, []string{"services/api"})
+
+	owner, err := store.LeaseOwner(lease.Id)
+	assert.NilError(t, err)
+	assert.Equal(t, owner, "alice")
+	_, err = store.LeaseOwner(lease.Id + 1)
+	assert.Equal(t, err, datastore.ErrLeaseNotFound)
+
+	next, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(time.Minute),
+	}, "")
+	assert.NilError(t, err)
+	assert.Equal(t, next.Id, lease.Id+1)
+}
+
+func Test_Lock(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Lock")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	leases := []int64{}
+	for i := 0; i < 3; i++ {
+		lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+			TtlDuration: ptypes.DurationProto(time.Minute),
+		}, "")
+		assert.NilError(t, err)
+		leases = append(leases, lease.Id)
+	}
+
+	first, err := store.Lock(context.Background(), &apiv1.LockRequest{
+		Name:  "jobs",
+		Lease: leases[0],
+	})
+	assert.NilError(t, err)
+
+	acquired := make(chan string, 1)
+	go func() {
+		second, err := store.Lock(context.Background(), &apiv1.LockRequest{
+			Name:  "jobs",
+			Lease: leases[1],
+		})
+		assert.NilError(t, err)
+		acquired <- second.Key
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = store.Lock(ctx, &apiv1.LockRequest{
+		Name:  "jobs",
+		Lease: leases[2],
+	})
+	assert.Equal(t, errors.Cause(err), context.DeadlineExceeded)
+
+	select {
+	case <-acquired:
+		t.Fatal("lock was acquired while held")
+	default:
+	}
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "jobs/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(values.Messages), 2)
+
+	// a lock is only released by the lease holding it
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   fmt.Sprintf("jobs/%016x", leases[2]),
+				Value: []byte("not a lock"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	rejected := []*apiv1.UnlockRequest{
+		&apiv1.UnlockRequest{Key: first.Key},
+		&apiv1.UnlockRequest{Key: first.Key, Lease: leases[1]},
+		&apiv1.UnlockRequest{Key: "jobs/other", Lease: leases[0]},
+		&apiv1.UnlockRequest{Key: fmt.Sprintf("%016x", leases[0]), Lease: leases[0]},
+		&apiv1.UnlockRequest{Key: fmt.Sprintf("jobs/%016x", leases[2]), Lease: leases[2]},
+	}
+	for _, request := range rejected {
+		_, err = store.Unlock(request)
+		assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest, "unlocking %q with lease %d", request.Key, request.Lease)
+	}
+	select {
+	case <-acquired:
+		t.Fatal("lock was acquired after unlocking with another lease")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	_, err = store.Unlock(&apiv1.UnlockRequest{
+		Key:   first.Key,
+		Lease: leases[0],
+	})
+	assert.NilError(t, err)
+
+	select {
+	case key := <-acquired:
+		assert.Equal(t, key, fmt.Sprintf("jobs/%016x", leases[1]))
+	case <-time.After(5 * time.Second):
+		t.Fatal("lock was not acquired after unlock")
+	}
+}
+
+func Test_Election(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Election")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	leases := []int64{}
+	for i := 0; i < 2; i++ {
+		lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+			TtlDuration: ptypes.DurationProto(time.Minute),
+		}, "")
+		assert.NilError(t, err)
+		leases = append(leases, lease.Id)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leaders := make(chan string, 10)
+	go store.Observe(ctx, &apiv1.ObserveRequest{
+		Name: "scheduler",
+	}, func(response *apiv1.ObserveResponse) error {
+		leaders <- string(response.Kv.Value)
+		return nil
+	})
+
+	first, err := store.Campaign(context.Background(), &apiv1.CampaignRequest{
+		Name:  "scheduler",
+		Lease: leases[0],
+		Value: []byte("node 1"),
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, first.Leader.Lease, leases[0])
+	assert.Equal(t, <-leaders, "node 1")
+
+	elected := make(chan *apiv1.LeaderKey, 1)
+	go func() {
+		second, err := store.Campaign(context.Background(), &apiv1.CampaignRequest{
+			Name:  "scheduler",
+			Lease: leases[1],
+			Value: []byte("node 2"),
+		})
+		assert.NilError(t, err)
+		elected <- second.Leader
+	}()
+
+	_, err = store.RevokeLease(&apiv1.RevokeLeaseRequest{
+		Id: leases[0],
+	})
+	assert.NilError(t, err)
+
+	select {
+	case leader := <-elected:
+		assert.Equal(t, leader.Lease, leases[1])
+	case <-time.After(5 * time.Second):
+		t.Fatal("candidate was not elected after the leader's lease was revoked")
+	}
+	assert.Equal(t, <-leaders, "node 2")
+
+	_, err = store.Resign(&apiv1.ResignRequest{
+		Leader: first.Leader,
+	})
+	assert.NilError(t, err)
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "scheduler/",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(values.Messages), 1)
+	assert.Equal(t, string(values.Messages[0].Value), "node 2")
+}
+
+func Test_GetLimit(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_GetLimit")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	messages := []*apiv1.KeyValue{}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, &apiv1.KeyValue{
+			Key:   fmt.Sprintf("items/%d", i),
+			Value: []byte(fmt.Sprintf("value %d", i)),
+		})
+	}
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+	})
+	assert.NilError(t, err)
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "items/",
+				IsPrefix: true,
+				Limit:    2,
+			},
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "items/4",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(values.Messages), 3)
+	assert.Equal(t, values.Messages[1].Key, "items/1")
+	assert.DeepEqual(t, values.NextStartAfter, []string{"items/1", ""})
+
+	values, err = store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:        "items/",
+				IsPrefix:   true,
+				Limit:      2,
+				StartAfter: "items/3",
+				KeysOnly:   true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, values, &apiv1.GetValuesResponse{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:         "items/4",
+				ModRevision: 2,
+			},
+		},
+		Header: &apiv1.ResponseHeader{
+			Revision: 2,
+		},
+	})
+}
+
+func Test_Scan(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Scan")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	messages := []*apiv1.KeyValue{}
+	for i := 0; i < 5; i++ {
+		messages = append(messages, &apiv1.KeyValue{
+			Key:   fmt.Sprintf("items/%d", i),
+			Value: []byte(fmt.Sprintf("value %d", i)),
+		})
+	}
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+	})
+	assert.NilError(t, err)
+
+	pages := [][]string{}
+	err = store.Scan(&apiv1.ScanValuesRequest{
+		Request: &apiv1.GetValuesRequest_GetValue{
+			Key:      "items/",
+			IsPrefix: true,
+		},
+		PageSize: 2,
+	}, func(response *apiv1.ScanValuesResponse) error {
+		page := []string{}
+		for _, message := range response.Messages {
+			page = append(page, message.Key)
+		}
+		pages = append(pages, page)
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pages, [][]string{
+		[]string{"items/0", "items/1"},
+		[]string{"items/2", "items/3"},
+		[]string{"items/4"},
+	})
+}
+
+func Test_GetRange(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_GetRange")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	messages := []*apiv1.KeyValue{}
+	for day := 1; day <= 5; day++ {
+		messages = append(messages, &apiv1.KeyValue{
+			Key:   fmt.Sprintf("events/2026-10-0%d", day),
+			Value: []byte("event"),
+		})
+	}
+	messages = append(messages, &apiv1.KeyValue{
+		Key:   "other",
+		Value: []byte("other"),
+	})
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: messages,
+	})
+	assert.NilError(t, err)
+
+	keys := func(request *apiv1.GetValuesRequest_GetValue) []string {
+		values, err := store.Get(&apiv1.GetValuesRequest{
+			Requests: []*apiv1.GetValuesRequest_GetValue{request},
+		})
+		assert.NilError(t, err)
+
+		keys := []string{}
+		for _, message := range values.Messages {
+			keys = append(keys, message.Key)
+		}
+		return keys
+	}
+
+	assert.DeepEqual(t, keys(&apiv1.GetValuesRequest_GetValue{
+		Key:      "events/2026-10-02",
+		RangeEnd: "events/2026-10-04",
+	}), []string{"events/2026-10-02", "events/2026-10-03"})
+
+	assert.DeepEqual(t, keys(&apiv1.GetValuesRequest_GetValue{
+		Key:      "events/2026-10-02",
+		RangeEnd: "events/2026-10-04",
+		Reverse:  true,
+	}), []string{"events/2026-10-03", "events/2026-10-02"})
+
+	assert.DeepEqual(t, keys(&apiv1.GetValuesRequest_GetValue{
+		Key:      "events/",
+		IsPrefix: true,
+		Reverse:  true,
+		Limit:    2,
+	}), []string{"events/2026-10-05", "events/2026-10-04"})
+
+	assert.DeepEqual(t, keys(&apiv1.GetValuesRequest_GetValue{
+		Key:        "events/",
+		IsPrefix:   true,
+		Reverse:    true,
+		Limit:      2,
+		StartAfter: "events/2026-10-04",
+	}), []string{"events/2026-10-03", "events/2026-10-02"})
+
+	assert.DeepEqual(t, keys(&apiv1.GetValuesRequest_GetValue{
+		Key:      "",
+		IsPrefix: true,
+		Reverse:  true,
+		Limit:    1,
+	}), []string{"other"})
+}
+
+func Test_Stat(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Stat")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	lease, err := store.GrantLease(&apiv1.GrantLeaseRequest{
+		TtlDuration: ptypes.DurationProto(time.Minute),
+	}, "")
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "devices/1",
+				Value: []byte("device 1"),
+			},
+		},
+		Lease: lease.Id,
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "devices/2",
+				Value: []byte("device 2 longer"),
+			},
+		},
+		TtlDuration: ptypes.DurationProto(time.Hour),
+	})
+	assert.NilError(t, err)
+
+	requests := []*apiv1.GetValuesRequest_GetValue{
+		&apiv1.GetValuesRequest_GetValue{
+			Key:      "devices/",
+			IsPrefix: true,
+		},
+	}
+
+	count, err := store.CountKeys(&apiv1.CountKeysRequest{
+		Requests: requests,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, count.Count, int64(2))
+	assert.Equal(t, count.TotalValueBytes, int64(len("device 1")+len("device 2 longer")))
+
+	stat, err := store.Stat(&apiv1.StatRequest{
+		Requests: requests,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, stat.Count, int64(2))
+	assert.Equal(t, stat.TotalValueBytes, count.TotalValueBytes)
+	assert.Equal(t, len(stat.Stats), 2)
+
+	assert.Equal(t, stat.Stats[0].Key, "devices/1")
+	assert.Equal(t, stat.Stats[0].ModRevision, uint64(3))
+	assert.Equal(t, stat.Stats[0].ValueSize, int64(len("device 1")))
+	assert.Equal(t, stat.Stats[0].Lease, lease.Id)
+	assert.Assert(t, stat.Stats[0].ExpiresAt == nil)
+
+	assert.Equal(t, stat.Stats[1].Key, "devices/2")
+	assert.Equal(t, stat.Stats[1].ModRevision, uint64(4))
+	assert.Equal(t, stat.Stats[1].ValueSize, int64(len("device 2 longer")))
+	assert.Equal(t, stat.Stats[1].Lease, int64(0))
+	expiresAt, err := ptypes.Timestamp(stat.Stats[1].ExpiresAt)
+	assert.NilError(t, err)
+	assert.Assert(t, expiresAt.After(time.Now().Add(59*time.Minute)))
+}
+
+func Test_SetPerKeyTTL(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SetPerKeyTTL")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	expiresAt := time.Now().Add(2 * time.Hour).Truncate(time.Second)
+	expiresAtProto, err := ptypes.TimestampProto(expiresAt)
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "config/permanent",
+				Value: []byte("value"),
+			},
+			&apiv1.KeyValue{
+				Key:            "heartbeats/1",
+				Value:          []byte("alive"),
+				SetTtlDuration: ptypes.DurationProto(time.Hour),
+			},
+			&apiv1.KeyValue{
+				Key:          "heartbeats/2",
+				Value:        []byte("alive"),
+				SetExpiresAt: expiresAtProto,
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "config/temporary",
+				Value: []byte("value"),
+			},
+			&apiv1.KeyValue{
+				Key:            "heartbeats/3",
+				Value:          []byte("alive"),
+				SetTtlDuration: ptypes.DurationProto(time.Hour),
+			},
+		},
+		TtlDuration: ptypes.DurationProto(time.Minute),
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:          "heartbeats/4",
+				Value:        []byte("alive"),
+				SetExpiresAt: &timestamp.Timestamp{Seconds: time.Now().Add(-time.Minute).Unix()},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "expires at is in the past")
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "",
+				IsPrefix: true,
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	remaining := map[string]time.Duration{}
+	for _, message := range values.Messages {
+		if message.TtlDuration == nil {
+			continue
+		}
+		ttl, err := ptypes.Duration(message.TtlDuration)
+		assert.NilError(t, err)
+		remaining[message.Key] = ttl.Round(time.Minute)
+	}
+	assert.DeepEqual(t, remaining, map[string]time.Duration{
+		"config/temporary": time.Minute,
+		"heartbeats/1":     time.Hour,
+		"heartbeats/2":     2 * time.Hour,
+		"heartbeats/3":     time.Hour,
+	})
+	assert.Equal(t, values.Messages[3].Key, "heartbeats/2")
+	assert.Equal(t, values.Messages[3].ExpiresAt.Seconds, expiresAt.Unix())
+
+	// a value that is read and written back does not keep the expiry it was read with
+	written := values.Messages[3]
+	written.Value = []byte("updated")
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{written},
+	})
+	assert.NilError(t, err)
+
+	values, err = store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "heartbeats/2",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, string(values.Messages[0].Value), "updated")
+	assert.Assert(t, values.Messages[0].ExpiresAt == nil)
+	assert.Assert(t, values.Messages[0].TtlDuration == nil)
+}
+
+func Test_Increment(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Increment")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			response, err := store.Increment(&apiv1.IncrementRequest{
+				Key:         "counters/requests",
+				Delta:       2,
+				TtlDuration: ptypes.DurationProto(time.Hour),
+			})
+			assert.NilError(t, err)
+			assert.Assert(t, response.Succeeded)
+		}()
+	}
+	wg.Wait()
+
+	response, err := store.Increment(&apiv1.IncrementRequest{
+		Key:   "counters/requests",
+		Delta: 1,
+		Max:   &wrappers.Int64Value{Value: 40},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, !response.Succeeded)
+	assert.Equal(t, response.Value, int64(40))
+
+	response, err = store.Increment(&apiv1.IncrementRequest{
+		Key:   "counters/requests",
+		Delta: -41,
+		Min:   &wrappers.Int64Value{Value: 0},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, !response.Succeeded)
+
+	response, err = store.Increment(&apiv1.IncrementRequest{
+		Key:   "counters/requests",
+		Delta: -40,
+		Min:   &wrappers.Int64Value{Value: 0},
+	})
+	assert.NilError(t, err)
+	assert.Assert(t, response.Succeeded)
+	assert.Equal(t, response.Value, int64(0))
+
+	values, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "counters/requests",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, string(values.Messages[0].Value), "0")
+	assert.Equal(t, values.Messages[0].Version, uint64(21))
+	assert.Assert(t, values.Messages[0].ExpiresAt != nil)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "counters/name",
+				Value: []byte("not a number"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	_, err = store.Increment(&apiv1.IncrementRequest{
+		Key:   "counters/name",
+		Delta: 1,
+	})
+	assert.ErrorContains(t, err, "is not an integer")
+	assert.Equal(t, errors.Cause(err), datastore.ErrFailedPrecondition)
+
+	_, err = store.Increment(&apiv1.IncrementRequest{
+		Key:   "counters/max",
+		Delta: math.MaxInt64,
+	})
+	assert.NilError(t, err)
+	_, err = store.Increment(&apiv1.IncrementRequest{
+		Key:   "counters/max",
+		Delta: 1,
+	})
+	assert.ErrorContains(t, err, "overflows")
+	assert.Equal(t, errors.Cause(err), datastore.ErrFailedPrecondition)
+}
+
+func Test_History(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_History")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		HistoryVersions: &wrappers.Int32Value{Value: 3},
+	})
+	assert.NilError(t, err)
+
+	set := func(value string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "config/routes",
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+	history := func(limit int64) []string {
+		response, err := store.GetHistory(&apiv1.GetHistoryRequest{
+			Key:   "config/routes",
+			Limit: limit,
+		})
+		assert.NilError(t, err)
+		values := []string{}
+		for i, version := range response.Versions {
+			assert.Assert(t, version.ModTime != nil)
+			if i > 0 {
+				assert.Assert(t, version.Kv.ModRevision < response.Versions[i-1].Kv.ModRevision)
+			}
+			values = append(values, string(version.Kv.Value))
+		}
+		return values
+	}
+
+	set("v1")
+	set("v2")
+	set("v3")
+	set("v4")
+
+	assert.DeepEqual(t, history(0), []string{"v4", "v3", "v2"})
+	assert.DeepEqual(t, history(2), []string{"v4", "v3"})
+
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "config/routes",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	set("v5")
+
+	assert.DeepEqual(t, history(0), []string{"v5", "v4"})
+
+	response, err := store.GetHistory(&apiv1.GetHistoryRequest{
+		Key: "config/other",
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(response.Versions), 0)
+
+	tmpDir, err = ioutil.TempDir("", "Test_History_Default")
+	assert.NilError(t, err)
+
+	store, err = datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	set("v1")
+	set("v2")
+
+	assert.DeepEqual(t, history(0), []string{"v2"})
+}
+
+func Test_HistoryAgeRequiresVersions(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_HistoryAgeRequiresVersions")
+	assert.NilError(t, err)
+
+	_, err = datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		HistoryAge: ptypes.DurationProto(time.Hour),
+	})
+	assert.ErrorContains(t, err, "history age requires more than one history version")
+}
+
+func Test_GetAtRevision(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_GetAtRevision")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		HistoryVersions: &wrappers.Int32Value{Value: 2},
+	})
+	assert.NilError(t, err)
+
+	set := func(key, value string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+	get := func(request *apiv1.GetValuesRequest) []string {
+		response, err := store.Get(request)
+		assert.NilError(t, err)
+		values := []string{}
+		for _, message := range response.Messages {
+			values = append(values, fmt.Sprintf("%s=%s", message.Key, message.Value))
+		}
+		return values
+	}
+	prefix := []*apiv1.GetValuesRequest_GetValue{
+		&apiv1.GetValuesRequest_GetValue{
+			Key:      "routes/",
+			IsPrefix: true,
+		},
+	}
+
+	set("routes/a", "1")
+	set("routes/b", "1")
+	time.Sleep(10 * time.Millisecond)
+	asOf := ptypes.TimestampNow()
+	time.Sleep(10 * time.Millisecond)
+	set("routes/a", "2")
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "routes/b",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	set("routes/c", "1")
+
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest{Requests: prefix}), []string{"routes/a=2", "routes/c=1"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest{Requests: prefix, Revision: 3}), []string{"routes/a=1", "routes/b=1"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest{Requests: prefix, Revision: 4}), []string{"routes/a=2", "routes/b=1"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest{Requests: prefix, Revision: 5}), []string{"routes/a=2"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest{Requests: prefix, AsOf: asOf}), []string{"routes/a=1", "routes/b=1"})
+
+	response, err := store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key:      "routes/",
+				IsPrefix: true,
+				Limit:    1,
+				Reverse:  true,
+			},
+		},
+		Revision: 5,
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(response.Messages), 1)
+	assert.Equal(t, response.Messages[0].Key, "routes/a")
+	assert.Equal(t, len(response.NextStartAfter), 0)
+	assert.Equal(t, response.Header.Revision, uint64(5))
+
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Key: "routes/b",
+			},
+		},
+		Revision: 3,
+	}), []string{"routes/b=1"})
+
+	set("routes/a", "3")
+
+	_, err = store.Get(&apiv1.GetValuesRequest{Requests: prefix, Revision: 3})
+	assert.Equal(t, errors.Cause(err), datastore.ErrCompacted)
+
+	_, err = store.Get(&apiv1.GetValuesRequest{Requests: prefix, Revision: 100})
+	assert.ErrorContains(t, err, "is newer than the current revision")
+	assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest)
+
+	_, err = store.Get(&apiv1.GetValuesRequest{Requests: prefix, Revision: 3, AsOf: ptypes.TimestampNow()})
+	assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest)
+
+	_, err = store.Get(&apiv1.GetValuesRequest{Requests: prefix, AsOf: &timestamp.Timestamp{Nanos: -1}})
+	assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest)
+}
+
+func Test_GetAtRevisionWithoutHistory(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_GetAtRevisionWithoutHistory")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	var revision uint64
+	for _, value := range []string{"1", "2"} {
+		response, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "routes/a",
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+		revision = response.Header.Revision
+	}
+	requests := []*apiv1.GetValuesRequest_GetValue{
+		&apiv1.GetValuesRequest_GetValue{
+			Key: "routes/a",
+		},
+	}
+
+	// the current revision is the latest version
+	response, err := store.Get(&apiv1.GetValuesRequest{Requests: requests, Revision: revision})
+	assert.NilError(t, err)
+	assert.Equal(t, string(response.Messages[0].Value), "2")
+
+	_, err = store.Get(&apiv1.GetValuesRequest{Requests: requests, Revision: revision - 1})
+	assert.Equal(t, errors.Cause(err), datastore.ErrHistoryNotRetained)
+
+	_, err = store.Get(&apiv1.GetValuesRequest{Requests: requests, AsOf: ptypes.TimestampNow()})
+	assert.Equal(t, errors.Cause(err), datastore.ErrHistoryNotRetained)
+}
+
+func Test_SubscribePrevKV(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribePrevKV")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	set := func(value string, ttl time.Duration) {
+		request := &apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "prev/1",
+					Value: []byte(value),
+				},
+			},
+		}
+		if ttl != 0 {
+			request.TtlDuration = ptypes.DurationProto(ttl)
+		}
+		_, err := store.Set(request)
+		assert.NilError(t, err)
+	}
+
+	set("value 1", 0)
+	set("value 2", 0)
+
+	live := []*apiv1.Event{}
+	resumed := []*apiv1.Event{}
+	mtx := sync.Mutex{}
+	subscribed := sync.WaitGroup{}
+
+	subscribe := func(startRevision uint64, events *[]*apiv1.Event) {
+		synced := false
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:      []string{"prev/"},
+			StartRevision: startRevision,
+			PrevKv:        true,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			if msg.Sync && !synced {
+				synced = true
+				subscribed.Done()
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			*events = append(*events, msg.Events...)
+			return nil
+		})
+	}
+	subscribed.Add(2)
+	go subscribe(0, &live)
+	go subscribe(2, &resumed)
+	subscribed.Wait()
+
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "prev/1",
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	set("value 3", time.Second)
+	time.Sleep(2 * time.Second)
+	err = store.SweepExpired()
+	assert.NilError(t, err)
+
+	// changes describes the events after the initial values as the type and the previous value
+	changes := func(events []*apiv1.Event) []string {
+		described := []string{}
+		for _, event := range events {
+			if event.Kv.ModRevision <= 3 && event.PrevKv == nil {
+				continue
+			}
+			previous := "none"
+			if event.PrevKv != nil {
+				previous = string(event.PrevKv.Value)
+			}
+			described = append(described, fmt.Sprintf("%s %s", event.Type, previous))
+		}
+		return described
+	}
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		mtx.Lock()
+		liveChanges := changes(live)
+		resumedChanges := changes(resumed)
+		mtx.Unlock()
+
+		if len(liveChanges) != 3 || len(resumedChanges) != 4 {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		assert.DeepEqual(t, liveChanges, []string{
+			"TYPE_DELETE value 2",
+			"TYPE_PUT none",
+			"TYPE_EXPIRE value 3",
+		})
+		assert.DeepEqual(t, resumedChanges, []string{
+			"TYPE_PUT value 1",
+			"TYPE_DELETE value 2",
+			"TYPE_PUT none",
+			"TYPE_EXPIRE value 3",
+		})
+		break
+	}
+}
+
+func Test_SubscribePrevKVConcurrentWrites(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribePrevKVConcurrentWrites")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	set := func(key, value string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	keys := 100
+	var response *apiv1.SetValuesResponse
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("concurrent/%03d", i)
+		response, err = store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte("old " + key),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	// concurrent commits reach the change log together
+	wg := sync.WaitGroup{}
+	for i := 0; i < keys; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			set(key, "new")
+		}(fmt.Sprintf("concurrent/%03d", i))
+	}
+	wg.Wait()
+
+	events := []*apiv1.Event{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store.Subscribe(ctx, &apiv1.SubscribeRequest{
+		Prefixes:      []string{"concurrent/"},
+		StartRevision: response.Header.Revision,
+		PrevKv:        true,
+	}, func(msg *apiv1.SubscribeResponse) error {
+		events = append(events, msg.Events...)
+		if msg.Sync {
+			cancel()
+		}
+		return nil
+	})
+
+	assert.Equal(t, len(events), keys)
+	for _, event := range events {
+		assert.Assert(t, event.PrevKv != nil, "no previous value for %s", event.Kv.Key)
+		assert.Equal(t, string(event.PrevKv.Value), "old "+event.Kv.Key)
+	}
+}
+
+func Test_PreviousValuesNotStored(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_PreviousValuesNotStored")
+	assert.NilError(t, err)
+
+	previousKeys := func() []string {
+		db, err := badger.Open(badger.DefaultOptions(tmpDir).WithLogger(nil))
+		assert.NilError(t, err)
+		defer db.Close()
+
+		keys := []string{}
+		err = db.View(func(txn *badger.Txn) error {
+			opts := badger.DefaultIteratorOptions
+			opts.AllVersions = true
+			opts.Prefix = []byte("\x00kvetch/prev-kvs/")
+			it := txn.NewIterator(opts)
+			defer it.Close()
+
+			for it.Rewind(); it.Valid(); it.Next() {
+				keys = append(keys, string(it.Item().Key()))
+			}
+			return nil
+		})
+		assert.NilError(t, err)
+		return keys
+	}
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	// previous values are read for subscriptions that want them without being written
+	subscribed := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.Subscribe(ctx, &apiv1.SubscribeRequest{
+		Prefixes: []string{"stored/"},
+		PrevKv:   true,
+	}, func(msg *apiv1.SubscribeResponse) error {
+		if msg.Sync {
+			close(subscribed)
+		}
+		return nil
+	})
+	<-subscribed
+
+	for i := 0; i < 3; i++ {
+		_, err = store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "stored/1",
+					Value: []byte(fmt.Sprintf("value %d", i)),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "stored/1",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, store.Close())
+
+	assert.DeepEqual(t, previousKeys(), []string{})
+}
+
+func Test_SubscribeSync(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeSync")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	set := func(key string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte("value"),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	set("sync/1")
+	set("sync/2")
+
+	snapshot := []string{}
+	skipped := []string{}
+	mtx := sync.Mutex{}
+
+	subscribe := func(skipSnapshot bool, responses *[]string) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:     []string{"sync/"},
+			SkipSnapshot: skipSnapshot,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if msg.Sync {
+				*responses = append(*responses, fmt.Sprintf("sync %d", msg.Header.Revision))
+				return nil
+			}
+			for _, value := range msg.Messages {
+				*responses = append(*responses, fmt.Sprintf("%s %d", value.Key, value.ModRevision))
+			}
+			return nil
+		})
+	}
+	go subscribe(false, &snapshot)
+	go subscribe(true, &skipped)
+
+	wait := func(count int) {
+		now := time.Now()
+		for {
+			if time.Now().Sub(now) > 30*time.Second {
+				t.Fatal("timed out waiting for results")
+			}
+
+			mtx.Lock()
+			done := len(snapshot) == count+2 && len(skipped) == count
+			mtx.Unlock()
+			if done {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	wait(1)
+	set("sync/3")
+	wait(2)
+
+	assert.DeepEqual(t, snapshot, []string{"sync/1 2", "sync/2 3", "sync 3", "sync/3 4"})
+	assert.DeepEqual(t, skipped, []string{"sync 3", "sync/3 4"})
+}
+
+func Test_SubscribeProgress(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeProgress")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	err = store.Subscribe(context.Background(), &apiv1.SubscribeRequest{
+		Prefixes:         []string{"quiet/"},
+		ProgressInterval: ptypes.DurationProto(time.Millisecond),
+	}, func(msg *apiv1.SubscribeResponse) error {
+		return nil
+	})
+	assert.ErrorContains(t, err, "progress interval must be at least one second")
+	assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest)
+
+	progress := []uint64{}
+	mtx := sync.Mutex{}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:         []string{"quiet/"},
+			ProgressInterval: ptypes.DurationProto(time.Second),
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if msg.Progress {
+				assert.Equal(t, len(msg.Events), 0)
+				progress = append(progress, msg.Header.Revision)
+			}
+			return nil
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "loud/1",
+				Value: []byte("value"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		mtx.Lock()
+		count := len(progress)
+		mtx.Unlock()
+
+		if count < 2 {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		mtx.Lock()
+		assert.Equal(t, progress[1], uint64(2))
+		mtx.Unlock()
+		break
+	}
+}
+
+func Test_PatternPrefix(t *testing.T) {
+	tests := []struct {
+		pattern *apiv1.KeyPattern
+		prefix  string
+	}{
+		{&apiv1.KeyPattern{Glob: "devices/*/status"}, "devices/"},
+		{&apiv1.KeyPattern{Glob: "devices/1/status"}, "devices/1/status"},
+		{&apiv1.KeyPattern{Regex: "devices/[0-9]+/status"}, "devices/"},
+		// not one-pass, so the compiled regex does not know its prefix
+		{&apiv1.KeyPattern{Regex: "devices/.*/status"}, "devices/"},
+		{&apiv1.KeyPattern{Regex: "devices/(a|b)/.*"}, "devices/"},
+		{&apiv1.KeyPattern{Regex: "(devices/)(.*)"}, "devices/"},
+		{&apiv1.KeyPattern{Regex: "devices/1/status"}, "devices/1/status"},
+		{&apiv1.KeyPattern{Regex: "(?i)devices/.*"}, ""},
+		{&apiv1.KeyPattern{Regex: "devices|machines"}, ""},
+		{&apiv1.KeyPattern{Regex: ".*/status"}, ""},
+	}
+	for _, test := range tests {
+		prefix, err := datastore.PatternPrefix(test.pattern)
+		assert.NilError(t, err)
+		assert.Equal(t, prefix, test.prefix, "pattern %v", test.pattern)
+	}
+
+	invalid := []*apiv1.KeyPattern{
+		&apiv1.KeyPattern{},
+		&apiv1.KeyPattern{Glob: "devices/*", Regex: "devices/.*"},
+		&apiv1.KeyPattern{Glob: "devices/["},
+		&apiv1.KeyPattern{Regex: "devices/(["},
+	}
+	for _, pattern := range invalid {
+		_, err := datastore.PatternPrefix(pattern)
+		assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest, "pattern %v", pattern)
+	}
+}
+
+func Test_SubscribeInvalid(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeInvalid")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	requests := []*apiv1.SubscribeRequest{
+		&apiv1.SubscribeRequest{ProgressInterval: ptypes.DurationProto(time.Millisecond)},
+		&apiv1.SubscribeRequest{ProgressInterval: &duration.Duration{Nanos: -1, Seconds: 1}},
+		&apiv1.SubscribeRequest{CoalesceWindow: ptypes.DurationProto(-time.Second)},
+		&apiv1.SubscribeRequest{CoalesceWindow: &duration.Duration{Nanos: -1, Seconds: 1}},
+		&apiv1.SubscribeRequest{SlowConsumerPolicy: 100},
+		&apiv1.SubscribeRequest{Patterns: []*apiv1.KeyPattern{&apiv1.KeyPattern{Regex: "devices/(["}}},
+	}
+	for _, request := range requests {
+		err = store.Subscribe(context.Background(), request, func(msg *apiv1.SubscribeResponse) error {
+			return nil
+		})
+		assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest, "request %v", request)
+	}
+}
+
+func Test_Patterns(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_Patterns")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	set := func(keys ...string) {
+		messages := []*apiv1.KeyValue{}
+		for _, key := range keys {
+			messages = append(messages, &apiv1.KeyValue{
+				Key:   key,
+				Value: []byte("value"),
+			})
+		}
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: messages,
+		})
+		assert.NilError(t, err)
+	}
+	get := func(request *apiv1.GetValuesRequest_GetValue) []string {
+		response, err := store.Get(&apiv1.GetValuesRequest{
+			Requests: []*apiv1.GetValuesRequest_GetValue{request},
+		})
+		assert.NilError(t, err)
+		keys := []string{}
+		for _, message := range response.Messages {
+			keys = append(keys, message.Key)
+		}
+		return keys
+	}
+
+	set("devices/1/status", "devices/1/config", "devices/22/status", "devices/x/status", "devices/1/status/old")
+
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest_GetValue{
+		Pattern: &apiv1.KeyPattern{Glob: "devices/*/status"},
+	}), []string{"devices/1/status", "devices/22/status", "devices/x/status"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest_GetValue{
+		Pattern: &apiv1.KeyPattern{Regex: "devices/[0-9]+/status"},
+	}), []string{"devices/1/status", "devices/22/status"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest_GetValue{
+		Pattern: &apiv1.KeyPattern{Regex: "devices/.*/status"},
+	}), []string{"devices/1/status", "devices/22/status", "devices/x/status"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest_GetValue{
+		Key:      "devices/2",
+		IsPrefix: true,
+		Pattern:  &apiv1.KeyPattern{Glob: "devices/*/status"},
+	}), []string{"devices/22/status"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest_GetValue{
+		Pattern: &apiv1.KeyPattern{Glob: "devices/*/status"},
+		Limit:   1,
+		Reverse: true,
+	}), []string{"devices/x/status"})
+	assert.DeepEqual(t, get(&apiv1.GetValuesRequest_GetValue{
+		Key:     "devices/1/config",
+		Pattern: &apiv1.KeyPattern{Glob: "devices/*/status"},
+	}), []string{})
+
+	_, err = store.Get(&apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{
+				Pattern: &apiv1.KeyPattern{Regex: "devices/(["},
+			},
+		},
+	})
+	assert.ErrorContains(t, err, "invalid regex")
+	assert.Equal(t, errors.Cause(err), datastore.ErrInvalidRequest)
+
+	keys := []string{}
+	mtx := sync.Mutex{}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Patterns: []*apiv1.KeyPattern{
+				&apiv1.KeyPattern{Glob: "devices/*/status"},
+			},
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, event := range msg.Events {
+				keys = append(keys, fmt.Sprintf("%s %d", event.Kv.Key, event.Kv.ModRevision))
+			}
+			return nil
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+
+	set("devices/3/config")
+	set("devices/3/status")
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		mtx.Lock()
+		count := len(keys)
+		mtx.Unlock()
+
+		if count != 4 {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+
+		mtx.Lock()
+		assert.DeepEqual(t, keys, []string{
+			"devices/1/status 2",
+			"devices/22/status 2",
+			"devices/x/status 2",
+			"devices/3/status 4",
+		})
+		mtx.Unlock()
+		break
+	}
+}
+
+func Test_SubscribeCoalesce(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscribeCoalesce")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	set := func(key string, value string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	set("coalesce/a", "0")
+
+	responses := []string{}
+	mtx := sync.Mutex{}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:       []string{"coalesce/"},
+			SkipSnapshot:   true,
+			PrevKv:         true,
+			CoalesceWindow: ptypes.DurationProto(500 * time.Millisecond),
+		}, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if msg.Sync {
+				responses = append(responses, "sync")
+				return nil
+			}
+			response := fmt.Sprintf("rev %d:", msg.Header.Revision)
+			for _, event := range msg.Events {
+				response += fmt.Sprintf(" %s %s", event.Kv.Key, event.Type)
+				if event.Type == apiv1.Event_TYPE_PUT {
+					response += "=" + string(event.Kv.Value)
+				}
+				if event.PrevKv != nil {
+					response += " prev=" + string(event.PrevKv.Value)
+				}
+				response += fmt.Sprintf(" coalesced=%d", event.Coalesced)
+			}
+			responses = append(responses, response)
+			return nil
+		})
+	}()
+
+	wait := func(count int) {
+		now := time.Now()
+		for {
+			if time.Now().Sub(now) > 30*time.Second {
+				t.Fatal("timed out waiting for results")
+			}
+
+			mtx.Lock()
+			done := len(responses) == count
+			mtx.Unlock()
+			if done {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	wait(1)
+	set("coalesce/a", "1")
+	set("coalesce/b", "1")
+	set("coalesce/a", "2")
+	set("coalesce/a", "3")
+	wait(2)
+
+	_, err = store.Delete(&apiv1.DeleteValuesRequest{
+		Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{
+				Key: "coalesce/b",
+			},
+		},
+	})
+	assert.NilError(t, err)
+	wait(3)
+
+	assert.DeepEqual(t, responses, []string{
+		"sync",
+		"rev 6: coalesce/b TYPE_PUT=1 coalesced=0 coalesce/a TYPE_PUT=3 prev=0 coalesced=2",
+		"rev 7: coalesce/b TYPE_DELETE prev=1 coalesced=0",
+	})
+}
+
+func Test_SubscriptionHub(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SubscriptionHub")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+
+	registry := prometheus.NewRegistry()
+	assert.NilError(t, registry.Register(store.Metrics()))
+
+	subscribers := func() float64 {
+		families, err := registry.Gather()
+		assert.NilError(t, err)
+		for _, family := range families {
+			if family.GetName() == "kvetch_subscribers" {
+				return family.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		t.Fatal("missing subscribers metric")
+		return 0
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	keys := map[string][]string{}
+	mtx := sync.Mutex{}
+	subscribed := sync.WaitGroup{}
+
+	subscribe := func(name string, request *apiv1.SubscribeRequest) {
+		request.SkipSnapshot = true
+		subscribed.Add(1)
+		go store.Subscribe(ctx, request, func(msg *apiv1.SubscribeResponse) error {
+			mtx.Lock()
+			defer mtx.Unlock()
+			if msg.Sync {
+				subscribed.Done()
+			}
+			for _, event := range msg.Events {
+				keys[name] = append(keys[name], event.Kv.Key)
+			}
+			return nil
+		})
+	}
+	subscribe("nested", &apiv1.SubscribeRequest{Prefixes: []string{"hub/", "hub/a/"}})
+	subscribe("a", &apiv1.SubscribeRequest{Prefixes: []string{"hub/a/"}})
+	subscribe("b", &apiv1.SubscribeRequest{Prefixes: []string{"hub/b/"}})
+	subscribe("everything", &apiv1.SubscribeRequest{Prefixes: []string{""}})
+	subscribe("pattern", &apiv1.SubscribeRequest{Patterns: []*apiv1.KeyPattern{&apiv1.KeyPattern{Glob: "*/b/*"}}})
+	subscribed.Wait()
+
+	assert.Equal(t, subscribers(), float64(5))
+
+	_, err = store.Set(&apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{Key: "hub/a/1", Value: []byte("value")},
+			&apiv1.KeyValue{Key: "hub/b/1", Value: []byte("value")},
+			&apiv1.KeyValue{Key: "other", Value: []byte("value")},
+		},
+	})
+	assert.NilError(t, err)
+
+	now := time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		// every listener is handed its changes separately
+		mtx.Lock()
+		count := 0
+		for _, k := range keys {
+			count += len(k)
+		}
+		mtx.Unlock()
+
+		if count < 8 {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		break
+	}
+
+	mtx.Lock()
+	// the changes of one write are not in any particular order
+	for _, k := range keys {
+		sort.Strings(k)
+	}
+	assert.DeepEqual(t, keys, map[string][]string{
+		"nested":     []string{"hub/a/1", "hub/b/1"},
+		"a":          []string{"hub/a/1"},
+		"b":          []string{"hub/b/1"},
+		"everything": []string{"hub/a/1", "hub/b/1", "other"},
+		"pattern":    []string{"hub/b/1"},
+	})
+	mtx.Unlock()
+
+	cancel()
+	now = time.Now()
+	for subscribers() != 0 {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for subscribers to stop")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_SlowConsumerPolicy(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SlowConsumerPolicy")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		SubscriberBufferSize: &wrappers.Int32Value{Value: 2},
+	})
+	assert.NilError(t, err)
+
+	registry := prometheus.NewRegistry()
+	assert.NilError(t, registry.Register(store.Metrics()))
+
+	metric := func(name string) float64 {
+		families, err := registry.Gather()
+		assert.NilError(t, err)
+		for _, family := range families {
+			if family.GetName() != name {
+				continue
+			}
+			m := family.GetMetric()[0]
+			if m.GetCounter() != nil {
+				return m.GetCounter().GetValue()
+			}
+			return m.GetGauge().GetValue()
+		}
+		t.Fatalf("missing metric %s", name)
+		return 0
+	}
+
+	set := func(key string, value string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gate := make(chan struct{})
+	events := map[apiv1.SubscribeRequest_SlowConsumerPolicy][]string{}
+	errs := map[apiv1.SubscribeRequest_SlowConsumerPolicy]error{}
+	mtx := sync.Mutex{}
+	subscribed := sync.WaitGroup{}
+	blocked := sync.WaitGroup{}
+	stopped := sync.WaitGroup{}
+
+	policies := []apiv1.SubscribeRequest_SlowConsumerPolicy{
+		apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DISCONNECT,
+		apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST,
+		apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_COALESCE,
+	}
+	for _, policy := range policies {
+		policy := policy
+		subscribed.Add(1)
+		blocked.Add(1)
+		stopped.Add(1)
+		go func() {
+			defer stopped.Done()
+			first := true
+			err := store.Subscribe(ctx, &apiv1.SubscribeRequest{
+				Prefixes:           []string{"slow/"},
+				SkipSnapshot:       true,
+				SlowConsumerPolicy: policy,
+			}, func(msg *apiv1.SubscribeResponse) error {
+				if msg.Sync {
+					subscribed.Done()
+					return nil
+				}
+				mtx.Lock()
+				if msg.Dropped > 0 {
+					events[policy] = append(events[policy], fmt.Sprintf("dropped %d", msg.Dropped))
+				}
+				for _, event := range msg.Events {
+					events[policy] = append(events[policy], fmt.Sprintf("%s=%s", event.Kv.Key, event.Kv.Value))
+				}
+				mtx.Unlock()
+				if first {
+					first = false
+					blocked.Done()
+					<-gate
+				}
+				return nil
+			})
+			mtx.Lock()
+			errs[policy] = err
+			mtx.Unlock()
+		}()
+	}
+	subscribed.Wait()
+
+	set("slow/a", "1")
+	blocked.Wait()
+
+	set("slow/a", "2")
+	set("slow/b", "1")
+	set("slow/a", "3")
+
+	now := time.Now()
+	for metric("kvetch_subscriber_changes_dropped_total") != 5 {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for changes to be dropped")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Equal(t, metric("kvetch_subscribers_dropped_total"), float64(1))
+	assert.Equal(t, metric("kvetch_subscriber_max_queue_depth"), float64(2))
+	assert.Equal(t, metric("kvetch_subscribers_lagging"), float64(2))
+
+	close(gate)
+
+	now = time.Now()
+	for {
+		if time.Now().Sub(now) > 30*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+
+		mtx.Lock()
+		done := len(events[apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST]) == 4 &&
+			len(events[apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_COALESCE]) == 3
+		mtx.Unlock()
+		if done {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	stopped.Wait()
+
+	assert.DeepEqual(t, events, map[apiv1.SubscribeRequest_SlowConsumerPolicy][]string{
+		apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DISCONNECT:  []string{"slow/a=1"},
+		apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST: []string{"slow/a=1", "dropped 1", "slow/b=1", "slow/a=3"},
+		apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_COALESCE:    []string{"slow/a=1", "slow/b=1", "slow/a=3"},
+	})
+	assert.Equal(t, errors.Cause(errs[apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DISCONNECT]), datastore.ErrSlowConsumer)
+}
+
+func Test_SlowConsumerDoesNotDelayOthers(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SlowConsumerDoesNotDelayOthers")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		SubscriberBufferSize: &wrappers.Int32Value{Value: 2},
+	})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gate := make(chan struct{})
+	defer close(gate)
+	stalled := make(chan struct{})
+	fast := []string{}
+	mtx := sync.Mutex{}
+	subscribed := sync.WaitGroup{}
+	slowErr := make(chan error, 1)
+
+	subscribed.Add(2)
+	go func() {
+		first := true
+		slowErr <- store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:     []string{"stall/"},
+			SkipSnapshot: true,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			if msg.Sync {
+				subscribed.Done()
+				return nil
+			}
+			if first {
+				first = false
+				close(stalled)
+				<-gate
+			}
+			return nil
+		})
+	}()
+	go func() {
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:     []string{"stall/"},
+			SkipSnapshot: true,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			if msg.Sync {
+				subscribed.Done()
+				return nil
+			}
+			mtx.Lock()
+			defer mtx.Unlock()
+			for _, event := range msg.Events {
+				fast = append(fast, string(event.Kv.Key))
+			}
+			return nil
+		})
+	}()
+	subscribed.Wait()
+
+	set := func(key string) {
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   key,
+					Value: []byte("value"),
+				},
+			},
+		})
+		assert.NilError(t, err)
+	}
+
+	set("stall/0")
+	<-stalled
+
+	// the stalled subscriber is disconnected by default once its buffer fills, so the others keep
+	// getting changes while it is stuck
+	expected := []string{"stall/0"}
+	for i := 1; i < 20; i++ {
+		key := fmt.Sprintf("stall/%d", i)
+		set(key)
+		expected = append(expected, key)
+	}
+
+	now := time.Now()
+	for {
+		mtx.Lock()
+		count := len(fast)
+		mtx.Unlock()
+		if count == len(expected) {
+			break
+		}
+		if time.Now().Sub(now) > time.Second {
+			t.Fatalf("the stalled subscriber held up the others, %d of %d changes sent", count, len(expected))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mtx.Lock()
+	assert.DeepEqual(t, fast, expected)
+	mtx.Unlock()
+
+	gate <- struct{}{}
+	assert.Equal(t, errors.Cause(<-slowErr), datastore.ErrSlowConsumer)
+}
+
+func Test_SlowConsumerDropOldestLargeCommit(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_SlowConsumerDropOldestLargeCommit")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		SubscriberBufferSize: &wrappers.Int32Value{Value: 2},
+	})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	gate := make(chan struct{})
+	events := []string{}
+	mtx := sync.Mutex{}
+	subscribed := make(chan struct{})
+	go func() {
+		first := true
+		store.Subscribe(ctx, &apiv1.SubscribeRequest{
+			Prefixes:           []string{"large/"},
+			SkipSnapshot:       true,
+			SlowConsumerPolicy: apiv1.SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST,
+		}, func(msg *apiv1.SubscribeResponse) error {
+			if msg.Sync {
+				close(subscribed)
+				return nil
+			}
+			mtx.Lock()
+			if msg.Dropped > 0 {
+				events = append(events, fmt.Sprintf("dropped %d", msg.Dropped))
+			}
+			for _, event := range msg.Events {
+				events = append(events, string(event.Kv.Value))
+			}
+			mtx.Unlock()
+			if first {
+				first = false
+				<-gate
+			}
+			return nil
+		})
+	}()
+	<-subscribed
+
+	set := func(values ...string) {
+		messages := []*apiv1.KeyValue{}
+		for _, value := range values {
+			messages = append(messages, &apiv1.KeyValue{
+				Key:   "large/" + value,
+				Value: []byte(value),
+			})
+		}
+		_, err := store.Set(&apiv1.SetValuesRequest{
+			Messages: messages,
+		})
+		assert.NilError(t, err)
+	}
+	count := func() int {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return len(events)
+	}
+
+	set("1")
+	for count() < 1 {
+		time.Sleep(10 * time.Millisecond)
+	}
+	set("2")
+	set("3", "4", "5")
+	time.Sleep(100 * time.Millisecond)
+	close(gate)
+
+	now := time.Now()
+	for count() < 5 {
+		if time.Now().Sub(now) > 10*time.Second {
+			t.Fatal("timed out waiting for results")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	mtx.Lock()
+	defer mtx.Unlock()
+	// the changes of a commit come in no particular order
+	sort.Strings(events[2:])
+	assert.DeepEqual(t, events, []string{"1", "dropped 1", "3", "4", "5"})
+}
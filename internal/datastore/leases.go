@@ -0,0 +1,495 @@
+package datastore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// ErrLeaseNotFound is returned when a lease does not exist or has already expired.
+var ErrLeaseNotFound = errors.New("lease not found")
+
+const (
+	leasePrefix            = internalPrefix + "leases/"
+	leaseAttachmentsPrefix = internalPrefix + "lease-keys/"
+	keyLeasesPrefix        = internalPrefix + "key-leases/"
+	leaseIDKey             = internalPrefix + "lease-id"
+)
+
+func leaseKey(id int64) []byte {
+	return []byte(fmt.Sprintf("%s%016x", leasePrefix, uint64(id)))
+}
+
+func leaseAttachmentPrefix(id int64) []byte {
+	return []byte(fmt.Sprintf("%s%016x/", leaseAttachmentsPrefix, uint64(id)))
+}
+
+func leaseAttachmentKey(id int64, key []byte) []byte {
+	return append(leaseAttachmentPrefix(id), key...)
+}
+
+// keyLeaseKey holds the lease a key was last written with, so it can be found without reading the
+// value of the key. It is only current while the key is marked with userMetaLease.
+func keyLeaseKey(key []byte) []byte {
+	return append([]byte(keyLeasesPrefix), key...)
+}
+
+// leases keeps the deadline and owner of every lease in memory. Deadlines are not persisted, so a
+// lease gets its full ttl again when the datastore restarts.
+type leases struct {
+	mtx       sync.Mutex
+	granted   map[int64]time.Duration
+	deadlines map[int64]time.Time
+	owners    map[int64]string
+}
+
+func newLeases() *leases {
+	return &leases{
+		granted:   map[int64]time.Duration{},
+		deadlines: map[int64]time.Time{},
+		owners:    map[int64]string{},
+	}
+}
+
+func (l *leases) set(id int64, ttl time.Duration, owner string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	l.granted[id] = ttl
+	l.deadlines[id] = time.Now().Add(ttl)
+	l.owners[id] = owner
+}
+
+func (l *leases) owner(id int64) (string, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	owner, ok := l.owners[id]
+	return owner, ok
+}
+
+func (l *leases) keepAlive(id int64) (time.Duration, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	ttl, ok := l.granted[id]
+	if !ok || !l.deadlines[id].After(time.Now()) {
+		// an expired lease is revoked by the next sweep
+		return 0, false
+	}
+	l.deadlines[id] = time.Now().Add(ttl)
+	return ttl, true
+}
+
+func (l *leases) remaining(id int64) (time.Duration, time.Duration, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	ttl, ok := l.granted[id]
+	if !ok {
+		return 0, 0, false
+	}
+	remaining := time.Until(l.deadlines[id])
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, ttl, true
+}
+
+func (l *leases) remove(id int64) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	delete(l.granted, id)
+	delete(l.deadlines, id)
+	delete(l.owners, id)
+}
+
+func (l *leases) expired(now time.Time) []int64 {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	expired := []int64{}
+	for id, deadline := range l.deadlines {
+		if !deadline.After(now) {
+			expired = append(expired, id)
+		}
+	}
+	return expired
+}
+
+// encodeLease encodes the ttl of a lease followed by its owner.
+func encodeLease(ttl time.Duration, owner string) []byte {
+	return append(encodeVarint(int64(ttl)), owner...)
+}
+
+// decodeLease decodes the ttl and owner of a lease. Leases granted before owners were recorded have
+// no owner.
+func decodeLease(v []byte) (time.Duration, string, error) {
+	ttl, n := binary.Varint(v)
+	if n <= 0 {
+		return 0, "", errors.New("invalid lease ttl")
+	}
+	return time.Duration(ttl), string(v[n:]), nil
+}
+
+// GrantLease creates a lease that expires unless it is kept alive. The owner is who the lease was
+// granted to, for the api to check when the lease is used.
+func (s *KVStore) GrantLease(request *apiv1.GrantLeaseRequest, owner string) (*apiv1.GrantLeaseResponse, error) {
+	if request.TtlDuration == nil {
+		return nil, errors.New("ttl is required")
+	}
+	ttl, err := ptypes.Duration(request.TtlDuration)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize ttl")
+	}
+	if ttl < time.Second {
+		return nil, errors.New("ttl must be at least one second")
+	}
+
+	var id int64
+	err = s.update(func(txn *badger.Txn) error {
+		id = 1
+		item, err := txn.Get([]byte(leaseIDKey))
+		switch {
+		case err == badger.ErrKeyNotFound:
+		case err != nil:
+			return errors.Wrap(err, "failed to get last lease id")
+		default:
+			err = item.Value(func(v []byte) error {
+				last, n := binary.Varint(v)
+				if n <= 0 {
+					return errors.New("invalid lease id")
+				}
+				id = last + 1
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		err = txn.Set([]byte(leaseIDKey), encodeVarint(id))
+		if err != nil {
+			return errors.Wrap(err, "failed to set last lease id")
+		}
+		err = txn.Set(leaseKey(id), encodeLease(ttl, owner))
+		if err != nil {
+			return errors.Wrap(err, "failed to set lease")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to grant lease")
+	}
+
+	s.leases.set(id, ttl, owner)
+
+	return &apiv1.GrantLeaseResponse{
+		Header:      s.responseHeader(),
+		Id:          id,
+		TtlDuration: ptypes.DurationProto(ttl),
+	}, nil
+}
+
+// KeepAlive refreshes the deadline of a lease.
+func (s *KVStore) KeepAlive(request *apiv1.KeepAliveRequest) (*apiv1.KeepAliveResponse, error) {
+	ttl, ok := s.leases.keepAlive(request.Id)
+	if !ok {
+		return nil, ErrLeaseNotFound
+	}
+
+	return &apiv1.KeepAliveResponse{
+		Header:      s.responseHeader(),
+		Id:          request.Id,
+		TtlDuration: ptypes.DurationProto(ttl),
+	}, nil
+}
+
+// LeaseOwner returns who a lease was granted to.
+func (s *KVStore) LeaseOwner(id int64) (string, error) {
+	owner, ok := s.leases.owner(id)
+	if !ok {
+		return "", ErrLeaseNotFound
+	}
+	return owner, nil
+}
+
+// RevokeLease revokes a lease and deletes every key attached to it.
+func (s *KVStore) RevokeLease(request *apiv1.RevokeLeaseRequest) (*apiv1.RevokeLeaseResponse, error) {
+	err := s.revokeLease(request.Id, userMetaDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiv1.RevokeLeaseResponse{
+		Header: s.responseHeader(),
+	}, nil
+}
+
+// LeaseTimeToLive retrieves the remaining time to live of a lease.
+func (s *KVStore) LeaseTimeToLive(request *apiv1.LeaseTimeToLiveRequest) (*apiv1.LeaseTimeToLiveResponse, error) {
+	remaining, granted, ok := s.leases.remaining(request.Id)
+	if !ok {
+		return nil, ErrLeaseNotFound
+	}
+
+	response := &apiv1.LeaseTimeToLiveResponse{
+		Header:             s.responseHeader(),
+		Id:                 request.Id,
+		TtlDuration:        ptypes.DurationProto(remaining),
+		GrantedTtlDuration: ptypes.DurationProto(granted),
+		Keys:               []string{},
+	}
+	if !request.Keys {
+		return response, nil
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		keys, err := leaseKeys(txn, request.Id)
+		if err != nil {
+			return err
+		}
+		for _, key := range keys {
+			response.Keys = append(response.Keys, string(key))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get lease keys")
+	}
+
+	return response, nil
+}
+
+// revokeLease deletes the lease and its keys, marking the keys with the given user meta so
+// subscribers can tell a revoke from an expiration. The keys are deleted over as many transactions
+// as they need and the lease itself is deleted with the last of them, so a revoke that fails part
+// way is finished when the lease expires.
+func (s *KVStore) revokeLease(id int64, userMeta byte) error {
+	for done := false; !done; {
+		err := s.update(func(txn *badger.Txn) error {
+			done = false
+			_, err := txn.Get(leaseKey(id))
+			if err == badger.ErrKeyNotFound {
+				return ErrLeaseNotFound
+			}
+			if err != nil {
+				return errors.Wrap(err, "failed to get lease")
+			}
+
+			count := 0
+			for _, attachment := range leaseAttachments(txn, id) {
+				err = revokeAttachment(txn, id, attachment, userMeta)
+				if errors.Cause(err) == badger.ErrTxnTooBig && count > 0 {
+					// commit what fits and delete the rest in the next transaction
+					return nil
+				}
+				if err != nil {
+					return err
+				}
+				count++
+			}
+
+			err = txn.Delete(leaseKey(id))
+			if err == badger.ErrTxnTooBig && count > 0 {
+				return nil
+			}
+			if err != nil {
+				return errors.Wrap(err, "failed to delete lease")
+			}
+			done = true
+			return nil
+		})
+		if err == ErrLeaseNotFound {
+			s.leases.remove(id)
+			return err
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to revoke lease")
+		}
+	}
+
+	s.leases.remove(id)
+
+	return nil
+}
+
+// revokeAttachment deletes the key of an attachment if it is still attached to the lease, and then
+// the attachment itself.
+func revokeAttachment(txn *badger.Txn, id int64, attachment []byte, userMeta byte) error {
+	key := attachment[len(leaseAttachmentPrefix(id)):]
+	attached, err := leaseAttached(txn, id, key)
+	if err != nil {
+		return err
+	}
+	if attached {
+		err = setMarker(txn, key, userMeta)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete key")
+		}
+		err = txn.Delete(keyLeaseKey(key))
+		if err != nil {
+			return errors.Wrap(err, "failed to delete key lease")
+		}
+	}
+
+	err = txn.Delete(attachment)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete lease attachment")
+	}
+	return nil
+}
+
+// leaseKeys returns the keys still attached to a lease. Attachments are only added when keys are
+// written, so keys that were since deleted or written without the lease are skipped.
+func leaseKeys(txn *badger.Txn, id int64) ([][]byte, error) {
+	keys := [][]byte{}
+	prefix := leaseAttachmentPrefix(id)
+	for _, attachment := range leaseAttachments(txn, id) {
+		key := attachment[len(prefix):]
+		attached, err := leaseAttached(txn, id, key)
+		if err != nil {
+			return nil, err
+		}
+		if attached {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys, nil
+}
+
+// leaseAttachments returns every attachment recorded for a lease.
+func leaseAttachments(txn *badger.Txn, id int64) [][]byte {
+	attachments := [][]byte{}
+
+	opts := badger.DefaultIteratorOptions
+	opts.PrefetchValues = false
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	prefix := leaseAttachmentPrefix(id)
+	for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+		attachments = append(attachments, it.Item().KeyCopy(nil))
+	}
+
+	return attachments
+}
+
+// leaseAttached returns whether a key was last written with the lease.
+func leaseAttached(txn *badger.Txn, id int64, key []byte) (bool, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "failed to get key")
+	}
+	value, err := itemKeyValue(item)
+	if err != nil {
+		return false, err
+	}
+	return value.Lease == id, nil
+}
+
+// attachLease records that a key was written with a lease.
+func attachLease(txn *badger.Txn, id int64, key []byte) error {
+	_, err := txn.Get(leaseKey(id))
+	if err == badger.ErrKeyNotFound {
+		return ErrLeaseNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to get lease")
+	}
+
+	err = txn.Set(leaseAttachmentKey(id, key), []byte{})
+	if err != nil {
+		return errors.Wrap(err, "failed to attach lease")
+	}
+	err = txn.Set(keyLeaseKey(key), encodeVarint(id))
+	if err != nil {
+		return errors.Wrap(err, "failed to set key lease")
+	}
+	return nil
+}
+
+// keyLease returns the lease a key marked with userMetaLease was written with.
+func keyLease(txn *badger.Txn, key []byte) (int64, error) {
+	item, err := txn.Get(keyLeaseKey(key))
+	if err == badger.ErrKeyNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get key lease")
+	}
+
+	var lease int64
+	err = item.Value(func(v []byte) error {
+		var n int
+		lease, n = binary.Varint(v)
+		if n <= 0 {
+			return errors.New("invalid key lease")
+		}
+		return nil
+	})
+	return lease, err
+}
+
+func (s *KVStore) loadLeases() error {
+	return s.db.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(leasePrefix)
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			item := it.Item()
+			var id uint64
+			_, err := fmt.Sscanf(string(item.Key()[len(prefix):]), "%016x", &id)
+			if err != nil {
+				return errors.Wrap(err, "invalid lease key")
+			}
+			err = item.Value(func(v []byte) error {
+				ttl, owner, err := decodeLease(v)
+				if err != nil {
+					return err
+				}
+				s.leases.set(int64(id), ttl, owner)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// sweepExpiredLeases revokes the leases whose deadline has passed. A lease that fails to be revoked
+// keeps its deadline, so it is tried again on the next sweep, and the first error is returned once
+// the other leases have been revoked.
+func (s *KVStore) sweepExpiredLeases() error {
+	var sweepErr error
+	for _, id := range s.leases.expired(time.Now()) {
+		err := s.revokeLease(id, userMetaExpire)
+		if errors.Cause(err) == ErrLeaseNotFound {
+			continue
+		}
+		if err != nil && sweepErr == nil {
+			sweepErr = err
+		}
+	}
+	return sweepErr
+}
+
+func encodeVarint(v int64) []byte {
+	buf := make([]byte, binary.MaxVarintLen64)
+	return buf[:binary.PutVarint(buf, v)]
+}
@@ -0,0 +1,341 @@
+package datastore
+
+import (
+	"sync"
+
+	"github.com/dgraph-io/badger/v2/pb"
+)
+
+// overflowPolicy is what a listener does when changes arrive faster than it takes them.
+type overflowPolicy int
+
+const (
+	// overflowDisconnect ends the subscription once its buffer is full.
+	overflowDisconnect overflowPolicy = iota
+	// overflowDropOldest drops the oldest queued changes to make room for new ones, but never the
+	// only commit queued.
+	overflowDropOldest
+	// overflowCoalesce keeps only the latest queued change to each key, and ends the subscription
+	// when that is still more than the buffer holds.
+	overflowCoalesce
+	// overflowNotify only tells the listener something changed, without queueing the changes.
+	overflowNotify
+)
+
+// changeListener queues the changes to the keys its filter matches as they are appended to the
+// change log, up to its buffer size.
+type changeListener struct {
+	filter keyFilter
+	policy overflowPolicy
+	size   int
+	// previous is set when the listener wants the values changes replaced.
+	previous bool
+	// ready is signalled when changes are queued.
+	ready chan struct{}
+
+	mtx     sync.Mutex
+	batches [][]*pb.KV
+	queued  int
+	dropped droppedChanges
+	// err is set once the listener cannot keep going, such as when it fell too far behind.
+	err error
+}
+
+// droppedChanges counts the changes left out for a listener since it last took its queue, along with
+// the revision of the latest of them.
+type droppedChanges struct {
+	count    int
+	revision uint64
+}
+
+func newChangeListener(filter keyFilter, policy overflowPolicy, size int) *changeListener {
+	return &changeListener{
+		filter:  filter,
+		policy:  policy,
+		size:    size,
+		ready:   make(chan struct{}, 1),
+		batches: [][]*pb.KV{},
+	}
+}
+
+// matches reports whether the filter of the listener matches a key. The previous value of a key is
+// matched along with the key.
+func (l *changeListener) matches(key []byte) bool {
+	if target, ok := previousTarget(key); ok {
+		key = target
+	}
+	if isInternal(key) {
+		return false
+	}
+	return l.filter.matches(key)
+}
+
+// push queues a batch of changes and applies the overflow policy when the buffer is full. It
+// returns how many changes were left out and whether the listener was disconnected.
+func (l *changeListener) push(kvs []*pb.KV) (int, bool) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	defer func() {
+		select {
+		case l.ready <- struct{}{}:
+		default:
+		}
+	}()
+
+	if l.policy == overflowNotify || l.err != nil {
+		return 0, false
+	}
+
+	l.batches = append(l.batches, kvs)
+	l.queued += len(kvs)
+	if l.queued <= l.size {
+		return 0, false
+	}
+
+	switch l.policy {
+	case overflowDropOldest:
+		return l.dropOldest(), false
+
+	case overflowCoalesce:
+		dropped := l.coalesce()
+		if l.queued <= l.size {
+			return dropped, false
+		}
+		return dropped + l.disconnect(), true
+	}
+
+	return l.disconnect(), true
+}
+
+// dropOldest leaves out the oldest changes until the queue fits. The changes of a commit share its
+// version and are left out together, so a key is never sent without its previous value or the other
+// way around. The latest commit is always kept, even when it is larger than the buffer on its own.
+func (l *changeListener) dropOldest() int {
+	newest := l.batches[len(l.batches)-1]
+	latest := newest[len(newest)-1].Version
+	for _, kv := range newest {
+		if kv.Version > latest {
+			latest = kv.Version
+		}
+	}
+
+	dropped := 0
+	for l.queued > l.size {
+		oldest := l.batches[0]
+		version := oldest[0].Version
+		if version == latest {
+			break
+		}
+		kept := []*pb.KV{}
+		for _, kv := range oldest {
+			if kv.Version != version {
+				kept = append(kept, kv)
+				continue
+			}
+			if _, ok := previousTarget(kv.Key); !ok {
+				dropped++
+			}
+		}
+		l.queued -= len(oldest) - len(kept)
+		if len(kept) == 0 {
+			l.batches = l.batches[1:]
+		} else {
+			l.batches[0] = kept
+		}
+		if version > l.dropped.revision {
+			l.dropped.revision = version
+		}
+	}
+	l.dropped.count += dropped
+	return dropped
+}
+
+// coalesce collapses the queued changes into one batch with the latest change to each key.
+func (l *changeListener) coalesce() int {
+	latest := map[string]int{}
+	kvs := []*pb.KV{}
+	for _, batch := range l.batches {
+		for _, kv := range batch {
+			if i, ok := latest[string(kv.Key)]; ok {
+				kvs[i] = nil
+			}
+			latest[string(kv.Key)] = len(kvs)
+			kvs = append(kvs, kv)
+		}
+	}
+
+	coalesced := []*pb.KV{}
+	for _, kv := range kvs {
+		if kv != nil {
+			coalesced = append(coalesced, kv)
+		}
+	}
+
+	dropped := changeCount(l.batches) - changeCount([][]*pb.KV{coalesced})
+	l.batches = [][]*pb.KV{coalesced}
+	l.queued = len(coalesced)
+	return dropped
+}
+
+func (l *changeListener) disconnect() int {
+	dropped := changeCount(l.batches)
+	l.batches = [][]*pb.KV{}
+	l.queued = 0
+	l.err = ErrSlowConsumer
+	return dropped
+}
+
+// changeCount returns the number of changes in batches, leaving out the previous value entries that
+// go along with them.
+func changeCount(batches [][]*pb.KV) int {
+	count := 0
+	for _, batch := range batches {
+		for _, kv := range batch {
+			if _, ok := previousTarget(kv.Key); !ok {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// fail ends the listener with the error, once it has taken the changes already queued.
+func (l *changeListener) fail(err error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.err == nil {
+		l.err = err
+	}
+	select {
+	case l.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued batches of changes in order along with the changes left out before them,
+// or the error the listener ended with once they have all been taken, such as ErrSlowConsumer when
+// it was disconnected.
+func (l *changeListener) take() ([][]*pb.KV, droppedChanges, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.err != nil {
+		if len(l.batches) == 0 {
+			return nil, droppedChanges{}, l.err
+		}
+		// the error is returned by the next take
+		select {
+		case l.ready <- struct{}{}:
+		default:
+		}
+	}
+
+	batches := l.batches
+	dropped := l.dropped
+	l.batches = [][]*pb.KV{}
+	l.queued = 0
+	l.dropped = droppedChanges{}
+	return batches, dropped, nil
+}
+
+// depth returns the number of changes queued.
+func (l *changeListener) depth() int {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.queued
+}
+
+// listenerIndex is a trie of the literal prefixes listeners are interested in, so the listeners for
+// a change are found by walking its key instead of checking every listener.
+type listenerIndex struct {
+	root *indexNode
+}
+
+type indexNode struct {
+	children  map[byte]*indexNode
+	listeners map[*changeListener]struct{}
+}
+
+func newIndexNode() *indexNode {
+	return &indexNode{
+		children:  map[byte]*indexNode{},
+		listeners: map[*changeListener]struct{}{},
+	}
+}
+
+func newListenerIndex() *listenerIndex {
+	return &listenerIndex{
+		root: newIndexNode(),
+	}
+}
+
+// prefixes returns the literal prefixes every key the listener matches starts with one of.
+func (l *changeListener) prefixes() [][]byte {
+	prefixes := append([][]byte{}, l.filter.prefixes...)
+	for _, pattern := range l.filter.patterns {
+		prefixes = append(prefixes, pattern.prefix)
+	}
+	return prefixes
+}
+
+func (x *listenerIndex) add(listener *changeListener) {
+	for _, prefix := range listener.prefixes() {
+		node := x.root
+		for _, b := range prefix {
+			child, ok := node.children[b]
+			if !ok {
+				child = newIndexNode()
+				node.children[b] = child
+			}
+			node = child
+		}
+		node.listeners[listener] = struct{}{}
+	}
+}
+
+func (x *listenerIndex) remove(listener *changeListener) {
+	for _, prefix := range listener.prefixes() {
+		x.root.remove(listener, prefix)
+	}
+}
+
+// remove takes the listener off the node for the prefix and reports whether the node is left empty.
+func (n *indexNode) remove(listener *changeListener, prefix []byte) bool {
+	if len(prefix) == 0 {
+		delete(n.listeners, listener)
+	} else if child, ok := n.children[prefix[0]]; ok && child.remove(listener, prefix[1:]) {
+		delete(n.children, prefix[0])
+	}
+	return len(n.children) == 0 && len(n.listeners) == 0
+}
+
+// lookup returns the listeners with a prefix of the key. Listeners with patterns still have to
+// check the whole key.
+func (x *listenerIndex) lookup(key []byte) []*changeListener {
+	found := map[*changeListener]struct{}{}
+	listeners := []*changeListener{}
+	collect := func(node *indexNode) {
+		for listener := range node.listeners {
+			if _, ok := found[listener]; ok {
+				continue
+			}
+			found[listener] = struct{}{}
+			listeners = append(listeners, listener)
+		}
+	}
+
+	node := x.root
+	collect(node)
+	for _, b := range key {
+		child, ok := node.children[b]
+		if !ok {
+			break
+		}
+		node = child
+		collect(node)
+	}
+	return listeners
+}
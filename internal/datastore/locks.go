@@ -0,0 +1,272 @@
+package datastore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// ErrWaiterDeleted is returned when the key of a lock waiter or election candidate is deleted
+// before it gets its turn, usually because its lease expired or was revoked.
+var ErrWaiterDeleted = errors.New("key was deleted while waiting")
+
+// Lock waits until the lock is held by the lease and returns the key that holds it.
+func (s *KVStore) Lock(ctx context.Context, request *apiv1.LockRequest) (*apiv1.LockResponse, error) {
+	kv, err := s.acquire(ctx, request.Name, request.Lease, []byte{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to acquire lock")
+	}
+
+	return &apiv1.LockResponse{
+		Header: s.responseHeader(),
+		Key:    kv.Key,
+	}, nil
+}
+
+// Unlock releases a lock by deleting its key, as long as the key is held by the lease of the
+// request. A lock that was already released is left alone.
+func (s *KVStore) Unlock(request *apiv1.UnlockRequest) (*apiv1.UnlockResponse, error) {
+	if request.Lease == 0 {
+		return nil, errors.Wrap(ErrInvalidRequest, "lease is required")
+	}
+	suffix := waiterKey("", request.Lease)
+	if len(request.Key) <= len(suffix) || !strings.HasSuffix(request.Key, suffix) {
+		return nil, errors.Wrapf(ErrInvalidRequest, "key %q is not a lock of lease %d", request.Key, request.Lease)
+	}
+	err := validateKey(request.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(request.Key))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to get key")
+		}
+
+		current, err := itemKeyValue(item)
+		if err != nil {
+			return err
+		}
+		if current.Lease != request.Lease {
+			return errors.Wrapf(ErrInvalidRequest, "key %q is not held by lease %d", request.Key, request.Lease)
+		}
+
+		err = setMarker(txn, []byte(request.Key), userMetaDelete)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete key")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to release lock")
+	}
+
+	return &apiv1.UnlockResponse{
+		Header: s.responseHeader(),
+	}, nil
+}
+
+// Campaign waits until the lease is elected leader and returns its leadership.
+func (s *KVStore) Campaign(ctx context.Context, request *apiv1.CampaignRequest) (*apiv1.CampaignResponse, error) {
+	kv, err := s.acquire(ctx, request.Name, request.Lease, request.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to campaign")
+	}
+
+	return &apiv1.CampaignResponse{
+		Header: s.responseHeader(),
+		Leader: &apiv1.LeaderKey{
+			Name:     request.Name,
+			Key:      kv.Key,
+			Revision: kv.CreateRevision,
+			Lease:    kv.Lease,
+		},
+	}, nil
+}
+
+// Resign gives up leadership. Leadership that was already lost is left alone.
+func (s *KVStore) Resign(request *apiv1.ResignRequest) (*apiv1.ResignResponse, error) {
+	if request.Leader == nil {
+		return nil, errors.New("leader is required")
+	}
+
+	err := s.deleteKey(request.Leader.Key, request.Leader.Revision)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resign")
+	}
+
+	return &apiv1.ResignResponse{
+		Header: s.responseHeader(),
+	}, nil
+}
+
+// Observe sends the leader of an election every time it changes. This will block until there is an
+// error or the context is cancelled
+func (s *KVStore) Observe(ctx context.Context, request *apiv1.ObserveRequest, cb func(*apiv1.ObserveResponse) error) error {
+	prefix := request.Name + "/"
+	listener := s.changes.watch([][]byte{[]byte(prefix)})
+	defer s.changes.remove(listener)
+
+	var last *apiv1.KeyValue
+	for {
+		waiters, revision, err := s.waiters(prefix)
+		if err != nil {
+			return err
+		}
+
+		if len(waiters) > 0 {
+			leader := waiters[0]
+			if last == nil || leader.Key != last.Key || leader.ModRevision != last.ModRevision {
+				err = cb(&apiv1.ObserveResponse{
+					Header: &apiv1.ResponseHeader{
+						Revision: revision,
+					},
+					Kv: leader,
+				})
+				if err != nil {
+					return errors.Wrap(err, "failed callback")
+				}
+				last = leader
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-listener.ready:
+		}
+	}
+}
+
+// acquire writes the key of the lease under the name and waits until it is the oldest key there,
+// so waiters are served in the order they arrived. The key is attached to the lease, so it goes
+// away with it.
+func (s *KVStore) acquire(ctx context.Context, name string, lease int64, value []byte) (*apiv1.KeyValue, error) {
+	if lease == 0 {
+		return nil, errors.New("lease is required")
+	}
+
+	prefix := name + "/"
+	key := waiterKey(name, lease)
+
+	listener := s.changes.watch([][]byte{[]byte(prefix)})
+	defer s.changes.remove(listener)
+
+	existed := false
+	err := s.update(func(txn *badger.Txn) error {
+		_, err := txn.Get([]byte(key))
+		existed = err == nil
+
+		return putValue(txn, &apiv1.KeyValue{
+			Key:   key,
+			Value: value,
+		}, 0, lease)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to set key")
+	}
+
+	for {
+		waiters, _, err := s.waiters(prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		var own *apiv1.KeyValue
+		for _, waiter := range waiters {
+			if waiter.Key == key {
+				own = waiter
+			}
+		}
+		if own == nil {
+			return nil, ErrWaiterDeleted
+		}
+		if waiters[0] == own {
+			return own, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if !existed {
+				err = s.deleteKey(key, own.CreateRevision)
+				if err != nil {
+					return nil, errors.Wrap(err, "failed to stop waiting")
+				}
+			}
+			return nil, ctx.Err()
+		case <-listener.ready:
+		}
+	}
+}
+
+// waiterKey returns the key a lease waits for a lock or campaigns in an election with.
+func waiterKey(name string, lease int64) string {
+	return fmt.Sprintf("%s/%016x", name, uint64(lease))
+}
+
+// waiters returns the keys under the prefix in the order they were created.
+func (s *KVStore) waiters(prefix string) ([]*apiv1.KeyValue, uint64, error) {
+	var waiters []*apiv1.KeyValue
+	var revision uint64
+	err := s.db.View(func(txn *badger.Txn) error {
+		revision = txn.ReadTs()
+
+		var err error
+		waiters, err = s.prefixScan(txn, prefix)
+		return err
+	})
+	if err != nil {
+		return nil, 0, errors.Wrap(err, "failed to get waiters")
+	}
+
+	sort.SliceStable(waiters, func(i, j int) bool {
+		return waiters[i].CreateRevision < waiters[j].CreateRevision
+	})
+
+	return waiters, revision, nil
+}
+
+// deleteKey deletes a key if it exists. A non zero create revision only deletes the key if it was
+// created at that revision.
+func (s *KVStore) deleteKey(key string, createRevision uint64) error {
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(key))
+		if err == badger.ErrKeyNotFound {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to get key")
+		}
+
+		if createRevision != 0 {
+			current, err := itemKeyValue(item)
+			if err != nil {
+				return err
+			}
+			if current.CreateRevision != createRevision {
+				return nil
+			}
+		}
+
+		err = setMarker(txn, []byte(key), userMetaDelete)
+		if err != nil {
+			return errors.Wrap(err, "failed to delete key")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,77 @@
+package datastore
+
+import (
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	subscribersDesc = prometheus.NewDesc(
+		"kvetch_subscribers",
+		"Number of subscriptions and lock waiters listening for changes.",
+		nil, nil)
+	subscriberQueueDepthDesc = prometheus.NewDesc(
+		"kvetch_subscriber_queue_depth",
+		"Number of changes queued for all subscribers.",
+		nil, nil)
+	subscriberMaxQueueDepthDesc = prometheus.NewDesc(
+		"kvetch_subscriber_max_queue_depth",
+		"Largest number of changes queued for a single subscriber.",
+		nil, nil)
+	laggingSubscribersDesc = prometheus.NewDesc(
+		"kvetch_subscribers_lagging",
+		"Number of subscribers with at least half of their buffer queued.",
+		nil, nil)
+	droppedSubscribersDesc = prometheus.NewDesc(
+		"kvetch_subscribers_dropped_total",
+		"Number of subscriptions ended for falling too far behind.",
+		nil, nil)
+	droppedChangesDesc = prometheus.NewDesc(
+		"kvetch_subscriber_changes_dropped_total",
+		"Number of changes left out or coalesced for subscribers that fell behind.",
+		nil, nil)
+)
+
+// changeLogCollector reports the listeners of a change log and how far behind they are.
+type changeLogCollector struct {
+	changes *changeLog
+}
+
+// Metrics returns a collector for the subscriptions to the datastore.
+func (s *KVStore) Metrics() prometheus.Collector {
+	return &changeLogCollector{changes: s.changes}
+}
+
+func (c *changeLogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- subscribersDesc
+	ch <- subscriberQueueDepthDesc
+	ch <- subscriberMaxQueueDepthDesc
+	ch <- laggingSubscribersDesc
+	ch <- droppedSubscribersDesc
+	ch <- droppedChangesDesc
+}
+
+func (c *changeLogCollector) Collect(ch chan<- prometheus.Metric) {
+	c.changes.mtx.Lock()
+	subscribers := len(c.changes.listeners)
+	depth, maxDepth, lagging := 0, 0, 0
+	for listener := range c.changes.listeners {
+		queued := listener.depth()
+		depth += queued
+		if queued > maxDepth {
+			maxDepth = queued
+		}
+		if listener.size > 0 && queued*2 >= listener.size {
+			lagging++
+		}
+	}
+	c.changes.mtx.Unlock()
+
+	ch <- prometheus.MustNewConstMetric(subscribersDesc, prometheus.GaugeValue, float64(subscribers))
+	ch <- prometheus.MustNewConstMetric(subscriberQueueDepthDesc, prometheus.GaugeValue, float64(depth))
+	ch <- prometheus.MustNewConstMetric(subscriberMaxQueueDepthDesc, prometheus.GaugeValue, float64(maxDepth))
+	ch <- prometheus.MustNewConstMetric(laggingSubscribersDesc, prometheus.GaugeValue, float64(lagging))
+	ch <- prometheus.MustNewConstMetric(droppedSubscribersDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&c.changes.disconnected)))
+	ch <- prometheus.MustNewConstMetric(droppedChangesDesc, prometheus.CounterValue, float64(atomic.LoadUint64(&c.changes.dropped)))
+}
@@ -0,0 +1,128 @@
+package datastore
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	"github.com/pkg/errors"
+)
+
+// keyPattern matches whole keys against a glob or a regular expression. Every key it matches starts
+// with its literal prefix, so only the keys with the prefix have to be scanned.
+type keyPattern struct {
+	prefix []byte
+	match  func(key string) bool
+}
+
+func compilePattern(pattern *apiv1.KeyPattern) (*keyPattern, error) {
+	switch {
+	case pattern.Glob != "" && pattern.Regex != "":
+		return nil, errors.Wrap(ErrInvalidRequest, "only one of glob and regex can be set")
+
+	case pattern.Glob != "":
+		glob := pattern.Glob
+		_, err := path.Match(glob, "")
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidRequest, "invalid glob %q: %v", glob, err)
+		}
+		prefix := glob
+		if i := strings.IndexAny(glob, `*?[\`); i >= 0 {
+			prefix = glob[:i]
+		}
+		return &keyPattern{
+			prefix: []byte(prefix),
+			match: func(key string) bool {
+				matched, _ := path.Match(glob, key)
+				return matched
+			},
+		}, nil
+
+	case pattern.Regex != "":
+		re, err := regexp.Compile(`^(?:` + pattern.Regex + `)$`)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidRequest, "invalid regex %q: %v", pattern.Regex, err)
+		}
+		// the compiled regex only knows its literal prefix when it is one-pass, so it is read from
+		// the parsed regex instead
+		parsed, err := syntax.Parse(pattern.Regex, syntax.Perl)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidRequest, "invalid regex %q: %v", pattern.Regex, err)
+		}
+		prefix, _ := literalPrefix(parsed)
+		return &keyPattern{
+			prefix: []byte(prefix),
+			match:  re.MatchString,
+		}, nil
+	}
+
+	return nil, errors.Wrap(ErrInvalidRequest, "glob or regex is required")
+}
+
+// literalPrefix returns the literal text every match of the parsed regex starts with, and whether
+// that is all the regex matches.
+func literalPrefix(re *syntax.Regexp) (string, bool) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return "", false
+		}
+		return string(re.Rune), true
+
+	case syntax.OpEmptyMatch, syntax.OpBeginText:
+		return "", true
+
+	case syntax.OpCapture:
+		return literalPrefix(re.Sub[0])
+
+	case syntax.OpConcat:
+		prefix := ""
+		for _, sub := range re.Sub {
+			literal, complete := literalPrefix(sub)
+			prefix += literal
+			if !complete {
+				return prefix, false
+			}
+		}
+		return prefix, true
+	}
+
+	return "", false
+}
+
+// PatternPrefix returns the literal prefix every key the pattern matches starts with.
+func PatternPrefix(pattern *apiv1.KeyPattern) (string, error) {
+	compiled, err := compilePattern(pattern)
+	if err != nil {
+		return "", err
+	}
+	return string(compiled.prefix), nil
+}
+
+func (p *keyPattern) matches(key []byte) bool {
+	return bytes.HasPrefix(key, p.prefix) && p.match(string(key))
+}
+
+// keyFilter matches keys by literal prefix or by pattern.
+type keyFilter struct {
+	prefixes [][]byte
+	patterns []*keyPattern
+}
+
+func (f keyFilter) matches(key []byte) bool {
+	for _, prefix := range f.prefixes {
+		if bytes.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for _, pattern := range f.patterns {
+		if pattern.matches(key) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package datastore
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/golang/protobuf/ptypes"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// ErrHistoryNotRetained is returned for reads at a past revision or time when only the latest version
+// of each key is retained.
+var ErrHistoryNotRetained = errors.New("reading at a past revision or time requires history versions greater than 1")
+
+// pointInTime bounds reads to the versions of keys written at or before a revision or a time.
+// Badger only hands out snapshots at past revisions in managed mode, so the version of each key is
+// found among the versions retained for history instead.
+type pointInTime struct {
+	revision uint64
+	// asOf is in nanoseconds since the epoch.
+	asOf int64
+	// versions is the number of versions retained for each key.
+	versions int
+}
+
+// getPointInTime returns the point in time a get request reads at, or nil when it reads the latest
+// key values.
+func (s *KVStore) getPointInTime(txn *badger.Txn, request *apiv1.GetValuesRequest) (*pointInTime, error) {
+	switch {
+	case request.Revision != 0 && request.AsOf != nil:
+		return nil, errors.Wrap(ErrInvalidRequest, "revision and as of cannot both be set")
+	case request.Revision > txn.ReadTs():
+		return nil, errors.Wrapf(ErrInvalidRequest, "revision %d is newer than the current revision %d", request.Revision, txn.ReadTs())
+	case request.Revision == txn.ReadTs():
+		return nil, nil
+	case (request.Revision != 0 || request.AsOf != nil) && s.historyVersions <= 1:
+		// every past version is compacted away, so the read would only fail for some keys
+		return nil, ErrHistoryNotRetained
+	case request.Revision != 0:
+		return &pointInTime{
+			revision: request.Revision,
+			versions: s.historyVersions,
+		}, nil
+	case request.AsOf != nil:
+		asOf, err := ptypes.Timestamp(request.AsOf)
+		if err != nil {
+			return nil, errors.Wrapf(ErrInvalidRequest, "invalid as of: %v", err)
+		}
+		return &pointInTime{
+			asOf:     asOf.UnixNano(),
+			versions: s.historyVersions,
+		}, nil
+	}
+	return nil, nil
+}
+
+// includes reports whether a version of a key was written at or before the point in time.
+func (p *pointInTime) includes(item *badger.Item) (bool, error) {
+	if p.revision != 0 {
+		return item.Version() <= p.revision, nil
+	}
+
+	modTime, err := itemModTime(item)
+	if err != nil {
+		return false, err
+	}
+	return modTime <= p.asOf, nil
+}
+
+// visit calls fn with the version of a key at the point in time. It returns false when the key did
+// not exist then, and ErrCompacted when every retained version is newer.
+func (p *pointInTime) visit(txn *badger.Txn, key []byte, fn func(*badger.Item) error) (bool, error) {
+	opts := badger.DefaultIteratorOptions
+	opts.AllVersions = true
+	opts.PrefetchValues = false
+	opts.Prefix = key
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	retained := 0
+	for it.Seek(key); it.Valid() && retained < p.versions; it.Next() {
+		item := it.Item()
+		if !bytes.Equal(item.Key(), key) {
+			return false, nil
+		}
+		retained++
+
+		ok, err := p.includes(item)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			continue
+		}
+		if item.UserMeta()&(userMetaDelete|userMetaExpire) != 0 {
+			return false, nil
+		}
+		return true, fn(item)
+	}
+
+	if retained == p.versions {
+		return false, ErrCompacted
+	}
+	return false, nil
+}
+
+// itemModTime returns the time an item was written in nanoseconds since the epoch, or zero for
+// items written before write times were recorded.
+func itemModTime(item *badger.Item) (int64, error) {
+	userMeta := item.UserMeta()
+	if userMeta&userMetaTime == 0 {
+		return 0, nil
+	}
+
+	var modTime int64
+	err := item.Value(func(raw []byte) error {
+		if userMeta&(userMetaDelete|userMetaExpire) != 0 {
+			t, n := binary.Uvarint(raw)
+			if n <= 0 {
+				return errors.New("invalid mod time")
+			}
+			modTime = int64(t)
+			return nil
+		}
+
+		header, _, err := decodeHeader(userMeta, raw)
+		if err != nil {
+			return err
+		}
+		modTime = header.modTime
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to get mod time")
+	}
+	return modTime, nil
+}
@@ -0,0 +1,55 @@
+package datastore
+
+import (
+	"bytes"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// checkPreconditions returns the preconditions that do not hold in the transaction.
+func checkPreconditions(txn *badger.Txn, preconditions []*apiv1.Precondition) ([]*apiv1.Precondition, error) {
+	failed := []*apiv1.Precondition{}
+	for _, precondition := range preconditions {
+		ok, err := checkPrecondition(txn, precondition)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			failed = append(failed, precondition)
+		}
+	}
+	return failed, nil
+}
+
+func checkPrecondition(txn *badger.Txn, precondition *apiv1.Precondition) (bool, error) {
+	if isInternal([]byte(precondition.Key)) {
+		return false, errors.Wrapf(ErrInvalidRequest, "key %q is reserved", precondition.Key)
+	}
+
+	var current *apiv1.KeyValue
+	item, err := txn.Get([]byte(precondition.Key))
+	switch {
+	case err == badger.ErrKeyNotFound:
+	case err != nil:
+		return false, errors.Wrap(err, "failed to get key")
+	default:
+		current, err = itemKeyValue(item)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	switch condition := precondition.Condition.(type) {
+	case *apiv1.Precondition_Exists:
+		return (current != nil) == condition.Exists, nil
+	case *apiv1.Precondition_ValueEquals:
+		return current != nil && bytes.Equal(current.Value, condition.ValueEquals), nil
+	case *apiv1.Precondition_ModRevisionEquals:
+		return current != nil && current.ModRevision == condition.ModRevisionEquals, nil
+	default:
+		return false, errors.Errorf("unsupported precondition for key %s", precondition.Key)
+	}
+}
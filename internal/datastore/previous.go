@@ -0,0 +1,177 @@
+package datastore
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/dgraph-io/badger/v2/pb"
+	"github.com/pkg/errors"
+)
+
+// previousKeysPrefix is the keyspace of the previous value entries added next to the changes sent to
+// subscriptions that want them, holding the value the change replaced. They are never written to the
+// datastore, besides by older versions of kvetch which wrote them already expired along with every
+// change, until badger compacts them away.
+const previousKeysPrefix = internalPrefix + "prev-kvs/"
+
+func previousKey(key []byte) []byte {
+	return append([]byte(previousKeysPrefix), key...)
+}
+
+// previousTarget returns the key a previous value entry belongs to.
+func previousTarget(key []byte) ([]byte, bool) {
+	if !bytes.HasPrefix(key, []byte(previousKeysPrefix)) {
+		return nil, false
+	}
+	return key[len(previousKeysPrefix):], true
+}
+
+// withPrevious adds a previous value entry right after each change that replaced a value and does
+// not have one yet, so it stays with the other changes of its commit. The value is read from the
+// versions of the key badger retains, and is left out when its version was already compacted away.
+func withPrevious(db *badger.DB, kvs []*pb.KV) ([]*pb.KV, error) {
+	changes := make([]*pb.KV, 0, len(kvs))
+	err := db.View(func(txn *badger.Txn) error {
+		for i, kv := range kvs {
+			changes = append(changes, kv)
+			if isInternal(kv.Key) || created(kv) || hasPrevious(kvs[i+1:], kv) {
+				continue
+			}
+			previous, err := previousKV(txn, kv)
+			if err != nil {
+				return err
+			}
+			if previous != nil {
+				changes = append(changes, previous)
+			}
+		}
+		return nil
+	})
+	return changes, err
+}
+
+// hasPrevious reports whether the previous value entry of a change is the next in the changes after
+// it.
+func hasPrevious(next []*pb.KV, kv *pb.KV) bool {
+	if len(next) == 0 || next[0].Version != kv.Version {
+		return false
+	}
+	target, ok := previousTarget(next[0].Key)
+	return ok && bytes.Equal(target, kv.Key)
+}
+
+// created reports whether a change wrote the first version of a key, so it did not replace a value.
+func created(kv *pb.KV) bool {
+	var userMeta byte
+	if len(kv.Meta) > 0 {
+		userMeta = kv.Meta[0]
+	}
+	if userMeta&userMetaRevision == 0 || userMeta&(userMetaDelete|userMetaExpire) != 0 {
+		return false
+	}
+	header, _, err := decodeHeader(userMeta, kv.Value)
+	return err == nil && header.version == 1
+}
+
+// previousKV finds the version of a key a change replaced and builds its previous value entry.
+// Expirations replace the value that expired, while other changes only replace a live value.
+func previousKV(txn *badger.Txn, kv *pb.KV) (*pb.KV, error) {
+	var userMeta byte
+	if len(kv.Meta) > 0 {
+		userMeta = kv.Meta[0]
+	}
+	expiration := userMeta&userMetaExpire != 0
+
+	opts := badger.DefaultIteratorOptions
+	opts.AllVersions = true
+	opts.PrefetchValues = false
+	opts.Prefix = kv.Key
+	it := txn.NewIterator(opts)
+	defer it.Close()
+
+	for it.Seek(kv.Key); it.Valid(); it.Next() {
+		item := it.Item()
+		if !bytes.Equal(item.Key(), kv.Key) {
+			return nil, nil
+		}
+		if item.Version() >= kv.Version {
+			continue
+		}
+		if item.UserMeta()&(userMetaDelete|userMetaExpire) != 0 {
+			return nil, nil
+		}
+		if item.IsDeletedOrExpired() && !(expiration && item.ExpiresAt() != 0) {
+			return nil, nil
+		}
+
+		value, err := encodePrevious(item)
+		if err != nil {
+			return nil, err
+		}
+		return &pb.KV{
+			Key:     previousKey(kv.Key),
+			Value:   value,
+			Version: kv.Version,
+		}, nil
+	}
+	return nil, nil
+}
+
+// encodePrevious records the value stored in an item along with what is needed to decode it.
+func encodePrevious(item *badger.Item) ([]byte, error) {
+	raw, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get previous value")
+	}
+
+	buf := make([]byte, 2*binary.MaxVarintLen64+1+len(raw))
+	n := binary.PutUvarint(buf, item.Version())
+	n += binary.PutUvarint(buf[n:], item.ExpiresAt())
+	buf[n] = item.UserMeta()
+	n++
+	n += copy(buf[n:], raw)
+	return buf[:n], nil
+}
+
+// setMarker writes a marker entry that hides the current value of a key from reads.
+func setMarker(txn *badger.Txn, key []byte, userMeta byte) error {
+	err := txn.SetEntry(markerEntry(key, userMeta))
+	if err != nil {
+		return errors.Wrap(err, "failed to set marker")
+	}
+	return nil
+}
+
+// decodePrevious reads the value recorded by a previous value entry.
+func decodePrevious(kv *pb.KV) (*apiv1.KeyValue, error) {
+	key, ok := previousTarget(kv.Key)
+	if !ok {
+		return nil, errors.New("not a previous value")
+	}
+
+	raw := kv.Value
+	modRevision, n := binary.Uvarint(raw)
+	if n <= 0 {
+		return nil, errors.New("invalid previous mod revision")
+	}
+	expiresAt, m := binary.Uvarint(raw[n:])
+	if m <= 0 {
+		return nil, errors.New("invalid previous expiry")
+	}
+	n += m
+	if len(raw) <= n {
+		return nil, errors.New("invalid previous user meta")
+	}
+	userMeta := raw[n]
+	n++
+
+	value, err := decodeValue(key, userMeta, modRevision, raw[n:])
+	if err != nil {
+		return nil, err
+	}
+	setExpiry(value, expiresAt)
+	return value, nil
+}
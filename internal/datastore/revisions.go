@@ -0,0 +1,249 @@
+package datastore
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+const (
+	// userMetaRevision marks an entry whose value is prefixed with a revision header.
+	userMetaRevision byte = 1 << 2
+	// userMetaLease marks a revision header that also holds the lease of the key.
+	userMetaLease byte = 1 << 3
+	// userMetaTime marks a revision header that also holds the time of the write.
+	userMetaTime byte = 1 << 4
+	// userMetaFixed marks a revision header whose fields are stored at a fixed width, so its length
+	// is known without reading the value. Headers written before then are varint encoded.
+	userMetaFixed byte = 1 << 5
+)
+
+// headerFieldSize is the width of each field of a fixed revision header.
+const headerFieldSize = 8
+
+// revisionHeader is stored in front of each value. A create revision of zero means the
+// entry itself created the key, since the commit version is not known until after the write.
+type revisionHeader struct {
+	createRevision uint64
+	version        uint64
+	lease          int64
+	// modTime is the time of the write in nanoseconds since the epoch.
+	modTime int64
+}
+
+func (h revisionHeader) userMeta() byte {
+	userMeta := userMetaRevision | userMetaFixed
+	if h.lease != 0 {
+		userMeta |= userMetaLease
+	}
+	if h.modTime != 0 {
+		userMeta |= userMetaTime
+	}
+	return userMeta
+}
+
+func encodeValue(header revisionHeader, value []byte) []byte {
+	buf := make([]byte, headerSize(header.userMeta())+len(value))
+	binary.BigEndian.PutUint64(buf, header.createRevision)
+	binary.BigEndian.PutUint64(buf[headerFieldSize:], header.version)
+	n := 2 * headerFieldSize
+	if header.lease != 0 {
+		binary.BigEndian.PutUint64(buf[n:], uint64(header.lease))
+		n += headerFieldSize
+	}
+	if header.modTime != 0 {
+		binary.BigEndian.PutUint64(buf[n:], uint64(header.modTime))
+		n += headerFieldSize
+	}
+	copy(buf[n:], value)
+	return buf
+}
+
+// headerSize returns the length of a fixed revision header from the user meta of its entry.
+func headerSize(userMeta byte) int {
+	size := 2 * headerFieldSize
+	if userMeta&userMetaLease != 0 {
+		size += headerFieldSize
+	}
+	if userMeta&userMetaTime != 0 {
+		size += headerFieldSize
+	}
+	return size
+}
+
+// decodeFixedHeader reads a revision header stored at a fixed width.
+func decodeFixedHeader(userMeta byte, raw []byte) (revisionHeader, int, error) {
+	header := revisionHeader{}
+	n := headerSize(userMeta)
+	if len(raw) < n {
+		return header, 0, errors.New("invalid revision header")
+	}
+
+	header.createRevision = binary.BigEndian.Uint64(raw)
+	header.version = binary.BigEndian.Uint64(raw[headerFieldSize:])
+	m := 2 * headerFieldSize
+	if userMeta&userMetaLease != 0 {
+		header.lease = int64(binary.BigEndian.Uint64(raw[m:]))
+		m += headerFieldSize
+	}
+	if userMeta&userMetaTime != 0 {
+		header.modTime = int64(binary.BigEndian.Uint64(raw[m:]))
+	}
+	return header, n, nil
+}
+
+// decodeHeader reads the revision header in front of a stored value and returns it along with
+// the length of the header.
+func decodeHeader(userMeta byte, raw []byte) (revisionHeader, int, error) {
+	if userMeta&userMetaFixed != 0 {
+		return decodeFixedHeader(userMeta, raw)
+	}
+
+	header := revisionHeader{}
+
+	createRevision, n := binary.Uvarint(raw)
+	if n <= 0 {
+		return header, 0, errors.New("invalid create revision")
+	}
+	version, m := binary.Uvarint(raw[n:])
+	if m <= 0 {
+		return header, 0, errors.New("invalid version")
+	}
+	n += m
+	header.createRevision = createRevision
+	header.version = version
+
+	if userMeta&userMetaLease != 0 {
+		lease, m := binary.Uvarint(raw[n:])
+		if m <= 0 {
+			return header, 0, errors.New("invalid lease")
+		}
+		header.lease = int64(lease)
+		n += m
+	}
+
+	if userMeta&userMetaTime != 0 {
+		modTime, m := binary.Uvarint(raw[n:])
+		if m <= 0 {
+			return header, 0, errors.New("invalid mod time")
+		}
+		header.modTime = int64(modTime)
+		n += m
+	}
+
+	return header, n, nil
+}
+
+// decodeValue builds a key value from a stored entry. Entries written before revisions
+// were tracked are treated as having been created by their only write.
+func decodeValue(key []byte, userMeta byte, modRevision uint64, raw []byte) (*apiv1.KeyValue, error) {
+	kv := &apiv1.KeyValue{
+		Key:            string(key),
+		Value:          raw,
+		CreateRevision: modRevision,
+		ModRevision:    modRevision,
+		Version:        1,
+	}
+	if userMeta&userMetaRevision == 0 {
+		return kv, nil
+	}
+
+	header, n, err := decodeHeader(userMeta, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if header.createRevision != 0 {
+		kv.CreateRevision = header.createRevision
+	}
+	kv.Version = header.version
+	kv.Lease = header.lease
+	kv.Value = raw[n:]
+	return kv, nil
+}
+
+// itemKeyValue reads a copy of the key value stored in an item.
+func itemKeyValue(item *badger.Item) (*apiv1.KeyValue, error) {
+	raw, err := item.ValueCopy(nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get value")
+	}
+
+	kv, err := decodeValue(item.KeyCopy(nil), item.UserMeta(), item.Version(), raw)
+	if err != nil {
+		return nil, err
+	}
+
+	setExpiry(kv, item.ExpiresAt())
+	return kv, nil
+}
+
+// setExpiry reports when a key expires and the time it has left to live.
+func setExpiry(kv *apiv1.KeyValue, expiresAt uint64) {
+	if expiresAt == 0 {
+		return
+	}
+
+	remaining := time.Until(time.Unix(int64(expiresAt), 0))
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	kv.ExpiresAt = &timestamp.Timestamp{
+		Seconds: int64(expiresAt),
+	}
+	kv.TtlDuration = ptypes.DurationProto(remaining)
+}
+
+// nextHeader reads the current entry for a key and returns the header for the next write to it.
+func nextHeader(txn *badger.Txn, key []byte, lease int64) (revisionHeader, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return revisionHeader{version: 1, lease: lease, modTime: time.Now().UnixNano()}, nil
+	}
+	if err != nil {
+		return revisionHeader{}, errors.Wrap(err, "failed to get key")
+	}
+
+	current, err := itemKeyValue(item)
+	if err != nil {
+		return revisionHeader{}, err
+	}
+
+	return revisionHeader{
+		createRevision: current.CreateRevision,
+		version:        current.Version + 1,
+		lease:          lease,
+		modTime:        time.Now().UnixNano(),
+	}, nil
+}
+
+// currentRevision returns the revision of the latest committed write.
+func (s *KVStore) currentRevision() uint64 {
+	txn := s.db.NewTransaction(false)
+	defer txn.Discard()
+
+	return txn.ReadTs()
+}
+
+func (s *KVStore) responseHeader() *apiv1.ResponseHeader {
+	return &apiv1.ResponseHeader{
+		Revision: s.currentRevision(),
+	}
+}
+
+// update runs fn in a read-write transaction, retrying when it conflicts with another write.
+func (s *KVStore) update(fn func(txn *badger.Txn) error) error {
+	for {
+		err := s.db.Update(fn)
+		if err != badger.ErrConflict {
+			return err
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package datastore
+
+import (
+	"bytes"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+const defaultScanPageSize = 1000
+
+// scanOptions bound a scan over the keys from start up to but not including end.
+type scanOptions struct {
+	start []byte
+	// end is nil to scan through the last key.
+	end []byte
+	// prefix, when set, lets forward scans skip tables without keys in the prefix.
+	prefix     []byte
+	startAfter []byte
+	limit      int64
+	keysOnly   bool
+	reverse    bool
+	// at, when set, scans the keys as they were at a point in time.
+	at *pointInTime
+	// pattern, when set, skips the keys it does not match.
+	pattern *keyPattern
+}
+
+func getScanOptions(key *apiv1.GetValuesRequest_GetValue) (scanOptions, error) {
+	opts := scanOptions{
+		start:    []byte(key.Key),
+		limit:    key.Limit,
+		keysOnly: key.KeysOnly,
+		reverse:  key.Reverse,
+	}
+	if key.Pattern != nil {
+		pattern, err := compilePattern(key.Pattern)
+		if err != nil {
+			return opts, err
+		}
+		opts.pattern = pattern
+		if key.Key == "" {
+			opts.start = pattern.prefix
+		}
+	}
+	if key.RangeEnd != "" {
+		opts.end = []byte(key.RangeEnd)
+	} else {
+		opts.prefix = opts.start
+		opts.end = prefixEnd(opts.prefix)
+	}
+	if key.StartAfter != "" {
+		opts.startAfter = []byte(key.StartAfter)
+	}
+	return opts, nil
+}
+
+// prefixEnd returns the first key after every key with the prefix, or nil if there is none.
+func prefixEnd(prefix []byte) []byte {
+	end := append([]byte{}, prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// scan calls fn with each key value in the scan in key order, or reverse key order. It returns true
+// when the limit was reached before the keys ran out.
+func scan(txn *badger.Txn, opts scanOptions, fn func(*apiv1.KeyValue) error) (bool, error) {
+	return scanItems(txn, opts, func(item *badger.Item) error {
+		value, err := scanKeyValue(item, opts.keysOnly)
+		if err != nil {
+			return err
+		}
+		return fn(value)
+	})
+}
+
+// scanItems calls fn with each item in the scan. Values are only prefetched when more than keys are
+// wanted.
+func scanItems(txn *badger.Txn, opts scanOptions, fn func(*badger.Item) error) (bool, error) {
+	iteratorOptions := badger.DefaultIteratorOptions
+	iteratorOptions.PrefetchValues = !opts.keysOnly
+	iteratorOptions.Reverse = opts.reverse
+	if opts.at != nil {
+		// every key that has a retained version is visited, even when it is deleted now
+		iteratorOptions.AllVersions = true
+		iteratorOptions.PrefetchValues = false
+	}
+	if !opts.reverse {
+		// a reverse iterator with a prefix rewinds to the start of the prefix
+		iteratorOptions.Prefix = opts.prefix
+	}
+	it := txn.NewIterator(iteratorOptions)
+	defer it.Close()
+
+	// after is the exclusive bound in the direction of the scan
+	after := opts.startAfter
+	switch {
+	case opts.reverse && after != nil && (opts.end == nil || bytes.Compare(after, opts.end) < 0):
+		it.Seek(after)
+	case opts.reverse && opts.end != nil:
+		after = opts.end
+		it.Seek(after)
+	case opts.reverse:
+		it.Rewind()
+	case after != nil && bytes.Compare(after, opts.start) >= 0:
+		// the smallest key after start after
+		it.Seek(append(append([]byte{}, after...), 0))
+	default:
+		it.Seek(opts.start)
+	}
+
+	var count int64
+	var last []byte
+	for ; it.Valid(); it.Next() {
+		item := it.Item()
+		key := item.Key()
+		if opts.at != nil {
+			if last != nil && bytes.Equal(key, last) {
+				continue
+			}
+			last = item.KeyCopy(last)
+		}
+		if opts.reverse {
+			if after != nil && bytes.Compare(key, after) >= 0 {
+				continue
+			}
+			if bytes.Compare(key, opts.start) < 0 {
+				break
+			}
+		} else if opts.end != nil && bytes.Compare(key, opts.end) >= 0 {
+			break
+		}
+		if isInternal(key) {
+			continue
+		}
+		if opts.pattern != nil && !opts.pattern.matches(key) {
+			continue
+		}
+		if opts.limit > 0 && count == opts.limit {
+			if opts.at == nil {
+				return true, nil
+			}
+			// the limit was only reached if the key existed at the point in time
+			found, err := opts.at.visit(txn, last, func(*badger.Item) error { return nil })
+			if err != nil {
+				return false, err
+			}
+			if found {
+				return true, nil
+			}
+			continue
+		}
+
+		if opts.at != nil {
+			found, err := opts.at.visit(txn, last, fn)
+			if err != nil {
+				return false, err
+			}
+			if found {
+				count++
+			}
+			continue
+		}
+
+		err := fn(item)
+		if err != nil {
+			return false, err
+		}
+		count++
+	}
+
+	return false, nil
+}
+
+// scanKeyValue reads the key value of an item, leaving out everything stored in the value when only
+// keys are wanted.
+func scanKeyValue(item *badger.Item, keysOnly bool) (*apiv1.KeyValue, error) {
+	if keysOnly {
+		return &apiv1.KeyValue{
+			Key:         string(item.KeyCopy(nil)),
+			ModRevision: item.Version(),
+		}, nil
+	}
+	return itemKeyValue(item)
+}
+
+// Scan retrieves the key values for a request in pages of at most the page size. Every page is read
+// from the same revision.
+func (s *KVStore) Scan(request *apiv1.ScanValuesRequest, cb func(*apiv1.ScanValuesResponse) error) error {
+	if request.Request == nil {
+		return errors.New("request is required")
+	}
+	pageSize := int(request.PageSize)
+	if pageSize <= 0 {
+		pageSize = defaultScanPageSize
+	}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		header := &apiv1.ResponseHeader{
+			Revision: txn.ReadTs(),
+		}
+
+		page := []*apiv1.KeyValue{}
+		sent := false
+		flush := func() error {
+			err := cb(&apiv1.ScanValuesResponse{
+				Messages: page,
+				Header:   header,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed callback")
+			}
+			page = []*apiv1.KeyValue{}
+			sent = true
+			return nil
+		}
+
+		_, err := s.visitValues(txn, request.Request, nil, func(value *apiv1.KeyValue) error {
+			page = append(page, value)
+			if len(page) < pageSize {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(page) > 0 || !sent {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to scan")
+	}
+
+	return nil
+}
@@ -0,0 +1,140 @@
+package datastore
+
+import (
+	"github.com/golang/protobuf/ptypes/timestamp"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// CountKeys counts the keys for requests and the size of their values.
+func (s *KVStore) CountKeys(request *apiv1.CountKeysRequest) (*apiv1.CountKeysResponse, error) {
+	response := &apiv1.CountKeysResponse{}
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		response.Header = &apiv1.ResponseHeader{
+			Revision: txn.ReadTs(),
+		}
+		for _, key := range request.Requests {
+			_, err := s.visitItems(txn, key, true, nil, func(item *badger.Item) error {
+				size, err := valueSize(item)
+				if err != nil {
+					return err
+				}
+				response.Count++
+				response.TotalValueBytes += size
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to count keys")
+	}
+
+	return response, nil
+}
+
+// Stat retrieves the metadata of the keys for requests without returning their values.
+func (s *KVStore) Stat(request *apiv1.StatRequest) (*apiv1.StatResponse, error) {
+	response := &apiv1.StatResponse{
+		Stats: []*apiv1.KeyStat{},
+	}
+	nextStartAfter := []string{}
+	limited := false
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		response.Header = &apiv1.ResponseHeader{
+			Revision: txn.ReadTs(),
+		}
+		for _, key := range request.Requests {
+			last := ""
+			more, err := s.visitItems(txn, key, true, nil, func(item *badger.Item) error {
+				stat, err := keyStat(txn, item)
+				if err != nil {
+					return err
+				}
+				response.Stats = append(response.Stats, stat)
+				response.Count++
+				response.TotalValueBytes += stat.ValueSize
+				last = stat.Key
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if !more {
+				last = ""
+			}
+			nextStartAfter = append(nextStartAfter, last)
+			limited = limited || more
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to stat keys")
+	}
+
+	if limited {
+		response.NextStartAfter = nextStartAfter
+	}
+
+	return response, nil
+}
+
+// keyStat builds the metadata of a key from its item and the key lease index.
+func keyStat(txn *badger.Txn, item *badger.Item) (*apiv1.KeyStat, error) {
+	size, err := valueSize(item)
+	if err != nil {
+		return nil, err
+	}
+	stat := &apiv1.KeyStat{
+		Key:         string(item.KeyCopy(nil)),
+		ModRevision: item.Version(),
+		ValueSize:   size,
+	}
+	if item.ExpiresAt() != 0 {
+		stat.ExpiresAt = &timestamp.Timestamp{
+			Seconds: int64(item.ExpiresAt()),
+		}
+	}
+	if item.UserMeta()&userMetaLease != 0 {
+		lease, err := keyLease(txn, item.Key())
+		if err != nil {
+			return nil, err
+		}
+		stat.Lease = lease
+	}
+	return stat, nil
+}
+
+// valueSize returns the size of the value of an item as it was written, without the revision
+// header stored in front of it. Only values with a varint header written before headers were fixed
+// are read.
+func valueSize(item *badger.Item) (int64, error) {
+	if item.UserMeta()&userMetaRevision == 0 {
+		return item.ValueSize(), nil
+	}
+	if item.UserMeta()&userMetaFixed != 0 {
+		return item.ValueSize() - int64(headerSize(item.UserMeta())), nil
+	}
+
+	var size int64
+	err := item.Value(func(raw []byte) error {
+		_, n, err := decodeHeader(item.UserMeta(), raw)
+		if err != nil {
+			return err
+		}
+		size = int64(len(raw) - n)
+		return nil
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read value")
+	}
+	return size, nil
+}
@@ -0,0 +1,158 @@
+package datastore
+
+import (
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	badger "github.com/dgraph-io/badger/v2"
+	"github.com/pkg/errors"
+)
+
+// Txn evaluates the comparisons and runs the success operations if they all hold or the failure
+// operations otherwise, all in a single transaction.
+func (s *KVStore) Txn(request *apiv1.TxnRequest) (*apiv1.TxnResponse, error) {
+	err := validateTxn(request)
+	if err != nil {
+		return nil, err
+	}
+
+	var succeeded bool
+	var results []*apiv1.TxnOperationResult
+	var expiring []expiration
+
+	err = s.update(func(txn *badger.Txn) error {
+		failed, err := checkPreconditions(txn, request.Compare)
+		if err != nil {
+			return errors.Wrap(err, "failed to check comparisons")
+		}
+
+		succeeded = len(failed) == 0
+		operations := request.Success
+		if !succeeded {
+			operations = request.Failure
+		}
+
+		results = []*apiv1.TxnOperationResult{}
+		expiring = []expiration{}
+		for _, operation := range operations {
+			result, err := s.runOperation(txn, operation, &expiring)
+			if err != nil {
+				return err
+			}
+			results = append(results, result)
+		}
+		return nil
+	})
+	if errors.Cause(err) == badger.ErrTxnTooBig {
+		return nil, errors.Wrap(ErrBatchTooLarge, "failed to run transaction")
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to run transaction")
+	}
+
+	for _, e := range expiring {
+		s.expirations.add(e.key, e.expiresAt)
+	}
+
+	return &apiv1.TxnResponse{
+		Header:    s.responseHeader(),
+		Succeeded: succeeded,
+		Results:   results,
+	}, nil
+}
+
+// validateTxn checks every comparison and operation has what it needs before the transaction
+// is opened.
+func validateTxn(request *apiv1.TxnRequest) error {
+	for i, compare := range request.Compare {
+		if compare == nil || compare.Condition == nil {
+			return errors.Wrapf(ErrInvalidRequest, "comparison %d has no condition", i)
+		}
+	}
+
+	for i, operation := range request.Success {
+		err := validateOperation(operation)
+		if err != nil {
+			return errors.Wrapf(err, "invalid success operation %d", i)
+		}
+	}
+	for i, operation := range request.Failure {
+		err := validateOperation(operation)
+		if err != nil {
+			return errors.Wrapf(err, "invalid failure operation %d", i)
+		}
+	}
+	return nil
+}
+
+func validateOperation(operation *apiv1.TxnOperation) error {
+	if operation == nil {
+		return errors.Wrap(ErrInvalidRequest, "operation is empty")
+	}
+
+	switch op := operation.Operation.(type) {
+	case *apiv1.TxnOperation_Get:
+		if op.Get == nil {
+			return errors.Wrap(ErrInvalidRequest, "get has no request")
+		}
+	case *apiv1.TxnOperation_Set:
+		if op.Set == nil || op.Set.Message == nil {
+			return errors.Wrap(ErrInvalidRequest, "set has no message")
+		}
+	case *apiv1.TxnOperation_Delete:
+		if op.Delete == nil {
+			return errors.Wrap(ErrInvalidRequest, "delete has no request")
+		}
+	default:
+		return errors.Wrap(ErrInvalidRequest, "operation has no get, set or delete")
+	}
+	return nil
+}
+
+func (s *KVStore) runOperation(txn *badger.Txn, operation *apiv1.TxnOperation, expiring *[]expiration) (*apiv1.TxnOperationResult, error) {
+	switch op := operation.Operation.(type) {
+	case *apiv1.TxnOperation_Get:
+		values, _, err := s.getValue(txn, op.Get, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &apiv1.TxnOperationResult{
+			Messages: values,
+		}, nil
+
+	case *apiv1.TxnOperation_Set:
+		expire, err := ttlExpiry(op.Set.TtlDuration)
+		if err != nil {
+			return nil, err
+		}
+		expire, err = messageExpiry(op.Set.Message, expire)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid expiry for key %q", op.Set.Message.Key)
+		}
+		if expire != 0 {
+			*expiring = append(*expiring, expiration{op.Set.Message.Key, expire})
+		}
+		err = putValue(txn, op.Set.Message, expire, op.Set.Lease)
+		if err != nil {
+			return nil, err
+		}
+		return &apiv1.TxnOperationResult{}, nil
+
+	case *apiv1.TxnOperation_Delete:
+		keys, err := s.matchingKeys(txn, op.Delete)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			err = setMarker(txn, key, userMetaDelete)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to delete key")
+			}
+		}
+		return &apiv1.TxnOperationResult{
+			DeletedCount: int64(len(keys)),
+		}, nil
+
+	default:
+		return nil, errors.New("unsupported transaction operation")
+	}
+}
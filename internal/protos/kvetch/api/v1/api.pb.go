@@ -8,6 +8,8 @@ import (
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
 	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
+	wrappers "github.com/golang/protobuf/ptypes/wrappers"
 	grpc "google.golang.org/grpc"
 	codes "google.golang.org/grpc/codes"
 	status "google.golang.org/grpc/status"
@@ -25,19 +27,143 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
+// SlowConsumerPolicy is what happens when changes are made faster than a
+// subscription takes them and its buffer on the server fills up.
+type SubscribeRequest_SlowConsumerPolicy int32
+
+const (
+	// SLOW_CONSUMER_POLICY_UNSPECIFIED is the same as
+	// SLOW_CONSUMER_POLICY_DISCONNECT.
+	SubscribeRequest_SLOW_CONSUMER_POLICY_UNSPECIFIED SubscribeRequest_SlowConsumerPolicy = 0
+	// SLOW_CONSUMER_POLICY_DROP_OLDEST leaves out the oldest changes that
+	// have not been sent yet, besides the latest commit, and sends a dropped
+	// response in their place.
+	SubscribeRequest_SLOW_CONSUMER_POLICY_DROP_OLDEST SubscribeRequest_SlowConsumerPolicy = 1
+	// SLOW_CONSUMER_POLICY_COALESCE only sends the latest change to each key
+	// that has not been sent yet, and ends the subscription like
+	// SLOW_CONSUMER_POLICY_DISCONNECT when that is still too many.
+	SubscribeRequest_SLOW_CONSUMER_POLICY_COALESCE SubscribeRequest_SlowConsumerPolicy = 2
+	// SLOW_CONSUMER_POLICY_DISCONNECT ends the subscription with a
+	// RESOURCE_EXHAUSTED error.
+	SubscribeRequest_SLOW_CONSUMER_POLICY_DISCONNECT SubscribeRequest_SlowConsumerPolicy = 3
+)
+
+var SubscribeRequest_SlowConsumerPolicy_name = map[int32]string{
+	0: "SLOW_CONSUMER_POLICY_UNSPECIFIED",
+	1: "SLOW_CONSUMER_POLICY_DROP_OLDEST",
+	2: "SLOW_CONSUMER_POLICY_COALESCE",
+	3: "SLOW_CONSUMER_POLICY_DISCONNECT",
+}
+
+var SubscribeRequest_SlowConsumerPolicy_value = map[string]int32{
+	"SLOW_CONSUMER_POLICY_UNSPECIFIED": 0,
+	"SLOW_CONSUMER_POLICY_DROP_OLDEST": 1,
+	"SLOW_CONSUMER_POLICY_COALESCE":    2,
+	"SLOW_CONSUMER_POLICY_DISCONNECT":  3,
+}
+
+func (x SubscribeRequest_SlowConsumerPolicy) String() string {
+	return proto.EnumName(SubscribeRequest_SlowConsumerPolicy_name, int32(x))
+}
+
+func (SubscribeRequest_SlowConsumerPolicy) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{44, 0}
+}
+
+// Type is the kind of change.
+type Event_Type int32
+
+const (
+	Event_TYPE_INVALID Event_Type = 0
+	Event_TYPE_PUT     Event_Type = 1
+	Event_TYPE_DELETE  Event_Type = 2
+	Event_TYPE_EXPIRE  Event_Type = 3
+)
+
+var Event_Type_name = map[int32]string{
+	0: "TYPE_INVALID",
+	1: "TYPE_PUT",
+	2: "TYPE_DELETE",
+	3: "TYPE_EXPIRE",
+}
+
+var Event_Type_value = map[string]int32{
+	"TYPE_INVALID": 0,
+	"TYPE_PUT":     1,
+	"TYPE_DELETE":  2,
+	"TYPE_EXPIRE":  3,
+}
+
+func (x Event_Type) String() string {
+	return proto.EnumName(Event_Type_name, int32(x))
+}
+
+func (Event_Type) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{46, 0}
+}
+
+// ResponseHeader is returned with every response.
+type ResponseHeader struct {
+	// revision is the revision of the datastore the response represents.
+	Revision             uint64   `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ResponseHeader) Reset()         { *m = ResponseHeader{} }
+func (m *ResponseHeader) String() string { return proto.CompactTextString(m) }
+func (*ResponseHeader) ProtoMessage()    {}
+func (*ResponseHeader) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{0}
+}
+
+func (m *ResponseHeader) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResponseHeader.Unmarshal(m, b)
+}
+func (m *ResponseHeader) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResponseHeader.Marshal(b, m, deterministic)
+}
+func (m *ResponseHeader) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResponseHeader.Merge(m, src)
+}
+func (m *ResponseHeader) XXX_Size() int {
+	return xxx_messageInfo_ResponseHeader.Size(m)
+}
+func (m *ResponseHeader) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResponseHeader.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResponseHeader proto.InternalMessageInfo
+
+func (m *ResponseHeader) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
 type SetValuesRequest struct {
-	Messages             []*KeyValue        `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
-	TtlDuration          *duration.Duration `protobuf:"bytes,2,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
-	XXX_unrecognized     []byte             `json:"-"`
-	XXX_sizecache        int32              `json:"-"`
+	Messages    []*KeyValue        `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	TtlDuration *duration.Duration `protobuf:"bytes,2,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	// preconditions must all hold for any of the messages to be written. The
+	// messages are then written in a single transaction, so a batch with
+	// preconditions fails with INVALID_ARGUMENT when it is too large for one.
+	// Batches without preconditions are written in as many transactions as
+	// they need, and are not atomic when they are split.
+	Preconditions []*Precondition `protobuf:"bytes,3,rep,name=preconditions,proto3" json:"preconditions,omitempty"`
+	// lease attaches the messages to a lease so they are deleted with it.
+	Lease                int64    `protobuf:"varint,4,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *SetValuesRequest) Reset()         { *m = SetValuesRequest{} }
 func (m *SetValuesRequest) String() string { return proto.CompactTextString(m) }
 func (*SetValuesRequest) ProtoMessage()    {}
 func (*SetValuesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{0}
+	return fileDescriptor_261ca598fa2afdd5, []int{1}
 }
 
 func (m *SetValuesRequest) XXX_Unmarshal(b []byte) error {
@@ -72,17 +198,35 @@ func (m *SetValuesRequest) GetTtlDuration() *duration.Duration {
 	return nil
 }
 
+func (m *SetValuesRequest) GetPreconditions() []*Precondition {
+	if m != nil {
+		return m.Preconditions
+	}
+	return nil
+}
+
+func (m *SetValuesRequest) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
 type SetValuesResponse struct {
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// succeeded is false when a precondition failed and nothing was written.
+	Succeeded            bool            `protobuf:"varint,2,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	FailedPreconditions  []*Precondition `protobuf:"bytes,3,rep,name=failed_preconditions,json=failedPreconditions,proto3" json:"failed_preconditions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
 func (m *SetValuesResponse) Reset()         { *m = SetValuesResponse{} }
 func (m *SetValuesResponse) String() string { return proto.CompactTextString(m) }
 func (*SetValuesResponse) ProtoMessage()    {}
 func (*SetValuesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{1}
+	return fileDescriptor_261ca598fa2afdd5, []int{2}
 }
 
 func (m *SetValuesResponse) XXX_Unmarshal(b []byte) error {
@@ -103,18 +247,155 @@ func (m *SetValuesResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_SetValuesResponse proto.InternalMessageInfo
 
+func (m *SetValuesResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *SetValuesResponse) GetSucceeded() bool {
+	if m != nil {
+		return m.Succeeded
+	}
+	return false
+}
+
+func (m *SetValuesResponse) GetFailedPreconditions() []*Precondition {
+	if m != nil {
+		return m.FailedPreconditions
+	}
+	return nil
+}
+
+// Precondition is a condition on the current state of a key.
+type Precondition struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// Types that are valid to be assigned to Condition:
+	//	*Precondition_Exists
+	//	*Precondition_ValueEquals
+	//	*Precondition_ModRevisionEquals
+	Condition            isPrecondition_Condition `protobuf_oneof:"condition"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *Precondition) Reset()         { *m = Precondition{} }
+func (m *Precondition) String() string { return proto.CompactTextString(m) }
+func (*Precondition) ProtoMessage()    {}
+func (*Precondition) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{3}
+}
+
+func (m *Precondition) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Precondition.Unmarshal(m, b)
+}
+func (m *Precondition) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Precondition.Marshal(b, m, deterministic)
+}
+func (m *Precondition) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Precondition.Merge(m, src)
+}
+func (m *Precondition) XXX_Size() int {
+	return xxx_messageInfo_Precondition.Size(m)
+}
+func (m *Precondition) XXX_DiscardUnknown() {
+	xxx_messageInfo_Precondition.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Precondition proto.InternalMessageInfo
+
+func (m *Precondition) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type isPrecondition_Condition interface {
+	isPrecondition_Condition()
+}
+
+type Precondition_Exists struct {
+	Exists bool `protobuf:"varint,2,opt,name=exists,proto3,oneof"`
+}
+
+type Precondition_ValueEquals struct {
+	ValueEquals []byte `protobuf:"bytes,3,opt,name=value_equals,json=valueEquals,proto3,oneof"`
+}
+
+type Precondition_ModRevisionEquals struct {
+	ModRevisionEquals uint64 `protobuf:"varint,4,opt,name=mod_revision_equals,json=modRevisionEquals,proto3,oneof"`
+}
+
+func (*Precondition_Exists) isPrecondition_Condition() {}
+
+func (*Precondition_ValueEquals) isPrecondition_Condition() {}
+
+func (*Precondition_ModRevisionEquals) isPrecondition_Condition() {}
+
+func (m *Precondition) GetCondition() isPrecondition_Condition {
+	if m != nil {
+		return m.Condition
+	}
+	return nil
+}
+
+func (m *Precondition) GetExists() bool {
+	if x, ok := m.GetCondition().(*Precondition_Exists); ok {
+		return x.Exists
+	}
+	return false
+}
+
+func (m *Precondition) GetValueEquals() []byte {
+	if x, ok := m.GetCondition().(*Precondition_ValueEquals); ok {
+		return x.ValueEquals
+	}
+	return nil
+}
+
+func (m *Precondition) GetModRevisionEquals() uint64 {
+	if x, ok := m.GetCondition().(*Precondition_ModRevisionEquals); ok {
+		return x.ModRevisionEquals
+	}
+	return 0
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*Precondition) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*Precondition_Exists)(nil),
+		(*Precondition_ValueEquals)(nil),
+		(*Precondition_ModRevisionEquals)(nil),
+	}
+}
+
 type GetValuesRequest struct {
-	Requests             []*GetValuesRequest_GetValue `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
-	XXX_unrecognized     []byte                       `json:"-"`
-	XXX_sizecache        int32                        `json:"-"`
+	Requests []*GetValuesRequest_GetValue `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	// revision reads the key values as they were at the revision instead of
+	// the latest ones. Only the versions of each key the datastore retains for
+	// history can be read, and versions before a delete or expiration are
+	// dropped once they are compacted. Reading a version that is no longer
+	// retained fails with OUT_OF_RANGE, and a revision newer than the current
+	// one with INVALID_ARGUMENT. Reading a past revision fails with
+	// FAILED_PRECONDITION when the datastore only retains the latest version of
+	// each key, which it does unless HISTORY_VERSIONS is greater than 1.
+	Revision uint64 `protobuf:"varint,2,opt,name=revision,proto3" json:"revision,omitempty"`
+	// as_of reads the key values as they were at the time, like revision. It
+	// cannot be set along with revision.
+	AsOf                 *timestamp.Timestamp `protobuf:"bytes,3,opt,name=as_of,json=asOf,proto3" json:"as_of,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
 func (m *GetValuesRequest) Reset()         { *m = GetValuesRequest{} }
 func (m *GetValuesRequest) String() string { return proto.CompactTextString(m) }
 func (*GetValuesRequest) ProtoMessage()    {}
 func (*GetValuesRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{2}
+	return fileDescriptor_261ca598fa2afdd5, []int{4}
 }
 
 func (m *GetValuesRequest) XXX_Unmarshal(b []byte) error {
@@ -142,20 +423,51 @@ func (m *GetValuesRequest) GetRequests() []*GetValuesRequest_GetValue {
 	return nil
 }
 
+func (m *GetValuesRequest) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *GetValuesRequest) GetAsOf() *timestamp.Timestamp {
+	if m != nil {
+		return m.AsOf
+	}
+	return nil
+}
+
 // GetValue is a get value request.
 type GetValuesRequest_GetValue struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	IsPrefix             bool     `protobuf:"varint,2,opt,name=is_prefix,json=isPrefix,proto3" json:"is_prefix,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key      string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	IsPrefix bool   `protobuf:"varint,2,opt,name=is_prefix,json=isPrefix,proto3" json:"is_prefix,omitempty"`
+	// limit is the most key values a prefix or range returns. Zero is no
+	// limit.
+	Limit int64 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	// start_after only returns the keys of a prefix or range after the key,
+	// to continue from a previous response.
+	StartAfter string `protobuf:"bytes,4,opt,name=start_after,json=startAfter,proto3" json:"start_after,omitempty"`
+	// keys_only returns keys and their mod revisions without reading values.
+	KeysOnly bool `protobuf:"varint,5,opt,name=keys_only,json=keysOnly,proto3" json:"keys_only,omitempty"`
+	// range_end returns every key from key up to but not including range_end
+	// instead of a single key or prefix.
+	RangeEnd string `protobuf:"bytes,6,opt,name=range_end,json=rangeEnd,proto3" json:"range_end,omitempty"`
+	// reverse returns the keys of a prefix or range from the last to the
+	// first. start_after then continues with the keys before it.
+	Reverse bool `protobuf:"varint,7,opt,name=reverse,proto3" json:"reverse,omitempty"`
+	// pattern only returns the keys that match it. When key is empty, the
+	// keys with the literal prefix of the pattern are scanned.
+	Pattern              *KeyPattern `protobuf:"bytes,8,opt,name=pattern,proto3" json:"pattern,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
+	XXX_unrecognized     []byte      `json:"-"`
+	XXX_sizecache        int32       `json:"-"`
 }
 
 func (m *GetValuesRequest_GetValue) Reset()         { *m = GetValuesRequest_GetValue{} }
 func (m *GetValuesRequest_GetValue) String() string { return proto.CompactTextString(m) }
 func (*GetValuesRequest_GetValue) ProtoMessage()    {}
 func (*GetValuesRequest_GetValue) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{2, 0}
+	return fileDescriptor_261ca598fa2afdd5, []int{4, 0}
 }
 
 func (m *GetValuesRequest_GetValue) XXX_Unmarshal(b []byte) error {
@@ -190,18 +502,115 @@ func (m *GetValuesRequest_GetValue) GetIsPrefix() bool {
 	return false
 }
 
+func (m *GetValuesRequest_GetValue) GetLimit() int64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+func (m *GetValuesRequest_GetValue) GetStartAfter() string {
+	if m != nil {
+		return m.StartAfter
+	}
+	return ""
+}
+
+func (m *GetValuesRequest_GetValue) GetKeysOnly() bool {
+	if m != nil {
+		return m.KeysOnly
+	}
+	return false
+}
+
+func (m *GetValuesRequest_GetValue) GetRangeEnd() string {
+	if m != nil {
+		return m.RangeEnd
+	}
+	return ""
+}
+
+func (m *GetValuesRequest_GetValue) GetReverse() bool {
+	if m != nil {
+		return m.Reverse
+	}
+	return false
+}
+
+func (m *GetValuesRequest_GetValue) GetPattern() *KeyPattern {
+	if m != nil {
+		return m.Pattern
+	}
+	return nil
+}
+
+// KeyPattern matches whole keys. Exactly one of glob and regex must be set.
+type KeyPattern struct {
+	// glob uses the syntax of Go's path.Match, so * does not match a /.
+	Glob string `protobuf:"bytes,1,opt,name=glob,proto3" json:"glob,omitempty"`
+	// regex is an RE2 regular expression that has to match the whole key.
+	Regex                string   `protobuf:"bytes,2,opt,name=regex,proto3" json:"regex,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeyPattern) Reset()         { *m = KeyPattern{} }
+func (m *KeyPattern) String() string { return proto.CompactTextString(m) }
+func (*KeyPattern) ProtoMessage()    {}
+func (*KeyPattern) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{5}
+}
+
+func (m *KeyPattern) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyPattern.Unmarshal(m, b)
+}
+func (m *KeyPattern) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyPattern.Marshal(b, m, deterministic)
+}
+func (m *KeyPattern) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyPattern.Merge(m, src)
+}
+func (m *KeyPattern) XXX_Size() int {
+	return xxx_messageInfo_KeyPattern.Size(m)
+}
+func (m *KeyPattern) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyPattern.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyPattern proto.InternalMessageInfo
+
+func (m *KeyPattern) GetGlob() string {
+	if m != nil {
+		return m.Glob
+	}
+	return ""
+}
+
+func (m *KeyPattern) GetRegex() string {
+	if m != nil {
+		return m.Regex
+	}
+	return ""
+}
+
 type GetValuesResponse struct {
-	Messages             []*KeyValue `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+	Messages []*KeyValue     `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	Header   *ResponseHeader `protobuf:"bytes,2,opt,name=header,proto3" json:"header,omitempty"`
+	// next_start_after is only set when a request was limited before its keys
+	// ran out. It then holds the start_after that continues each request, in
+	// order, or an empty string for requests that returned every key.
+	NextStartAfter       []string `protobuf:"bytes,3,rep,name=next_start_after,json=nextStartAfter,proto3" json:"next_start_after,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *GetValuesResponse) Reset()         { *m = GetValuesResponse{} }
 func (m *GetValuesResponse) String() string { return proto.CompactTextString(m) }
 func (*GetValuesResponse) ProtoMessage()    {}
 func (*GetValuesResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{3}
+	return fileDescriptor_261ca598fa2afdd5, []int{6}
 }
 
 func (m *GetValuesResponse) XXX_Unmarshal(b []byte) error {
@@ -229,292 +638,3349 @@ func (m *GetValuesResponse) GetMessages() []*KeyValue {
 	return nil
 }
 
-type SubscribeRequest struct {
-	Prefixes             []string `protobuf:"bytes,1,rep,name=prefixes,proto3" json:"prefixes,omitempty"`
+func (m *GetValuesResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *GetValuesResponse) GetNextStartAfter() []string {
+	if m != nil {
+		return m.NextStartAfter
+	}
+	return nil
+}
+
+type ScanValuesRequest struct {
+	Request *GetValuesRequest_GetValue `protobuf:"bytes,1,opt,name=request,proto3" json:"request,omitempty"`
+	// page_size is the most key values sent in each response. Zero uses the
+	// default of 1000.
+	PageSize             int64    `protobuf:"varint,2,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
 }
 
-func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
-func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
-func (*SubscribeRequest) ProtoMessage()    {}
-func (*SubscribeRequest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{4}
+func (m *ScanValuesRequest) Reset()         { *m = ScanValuesRequest{} }
+func (m *ScanValuesRequest) String() string { return proto.CompactTextString(m) }
+func (*ScanValuesRequest) ProtoMessage()    {}
+func (*ScanValuesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{7}
 }
 
-func (m *SubscribeRequest) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SubscribeRequest.Unmarshal(m, b)
+func (m *ScanValuesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanValuesRequest.Unmarshal(m, b)
 }
-func (m *SubscribeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SubscribeRequest.Marshal(b, m, deterministic)
+func (m *ScanValuesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanValuesRequest.Marshal(b, m, deterministic)
 }
-func (m *SubscribeRequest) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SubscribeRequest.Merge(m, src)
+func (m *ScanValuesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanValuesRequest.Merge(m, src)
 }
-func (m *SubscribeRequest) XXX_Size() int {
-	return xxx_messageInfo_SubscribeRequest.Size(m)
+func (m *ScanValuesRequest) XXX_Size() int {
+	return xxx_messageInfo_ScanValuesRequest.Size(m)
 }
-func (m *SubscribeRequest) XXX_DiscardUnknown() {
-	xxx_messageInfo_SubscribeRequest.DiscardUnknown(m)
+func (m *ScanValuesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanValuesRequest.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_SubscribeRequest proto.InternalMessageInfo
+var xxx_messageInfo_ScanValuesRequest proto.InternalMessageInfo
 
-func (m *SubscribeRequest) GetPrefixes() []string {
+func (m *ScanValuesRequest) GetRequest() *GetValuesRequest_GetValue {
 	if m != nil {
-		return m.Prefixes
+		return m.Request
 	}
 	return nil
 }
 
-type SubscribeResponse struct {
-	Messages             []*KeyValue `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
-	XXX_NoUnkeyedLiteral struct{}    `json:"-"`
-	XXX_unrecognized     []byte      `json:"-"`
-	XXX_sizecache        int32       `json:"-"`
+func (m *ScanValuesRequest) GetPageSize() int64 {
+	if m != nil {
+		return m.PageSize
+	}
+	return 0
 }
 
-func (m *SubscribeResponse) Reset()         { *m = SubscribeResponse{} }
-func (m *SubscribeResponse) String() string { return proto.CompactTextString(m) }
-func (*SubscribeResponse) ProtoMessage()    {}
-func (*SubscribeResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_261ca598fa2afdd5, []int{5}
+type ScanValuesResponse struct {
+	Messages             []*KeyValue     `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	Header               *ResponseHeader `protobuf:"bytes,2,opt,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
 }
 
-func (m *SubscribeResponse) XXX_Unmarshal(b []byte) error {
-	return xxx_messageInfo_SubscribeResponse.Unmarshal(m, b)
+func (m *ScanValuesResponse) Reset()         { *m = ScanValuesResponse{} }
+func (m *ScanValuesResponse) String() string { return proto.CompactTextString(m) }
+func (*ScanValuesResponse) ProtoMessage()    {}
+func (*ScanValuesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{8}
 }
-func (m *SubscribeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
-	return xxx_messageInfo_SubscribeResponse.Marshal(b, m, deterministic)
+
+func (m *ScanValuesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ScanValuesResponse.Unmarshal(m, b)
 }
-func (m *SubscribeResponse) XXX_Merge(src proto.Message) {
-	xxx_messageInfo_SubscribeResponse.Merge(m, src)
+func (m *ScanValuesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ScanValuesResponse.Marshal(b, m, deterministic)
 }
-func (m *SubscribeResponse) XXX_Size() int {
-	return xxx_messageInfo_SubscribeResponse.Size(m)
+func (m *ScanValuesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ScanValuesResponse.Merge(m, src)
 }
-func (m *SubscribeResponse) XXX_DiscardUnknown() {
-	xxx_messageInfo_SubscribeResponse.DiscardUnknown(m)
+func (m *ScanValuesResponse) XXX_Size() int {
+	return xxx_messageInfo_ScanValuesResponse.Size(m)
+}
+func (m *ScanValuesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ScanValuesResponse.DiscardUnknown(m)
 }
 
-var xxx_messageInfo_SubscribeResponse proto.InternalMessageInfo
+var xxx_messageInfo_ScanValuesResponse proto.InternalMessageInfo
 
-func (m *SubscribeResponse) GetMessages() []*KeyValue {
+func (m *ScanValuesResponse) GetMessages() []*KeyValue {
 	if m != nil {
 		return m.Messages
 	}
-	return nil
+	return nil
+}
+
+func (m *ScanValuesResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+type CountKeysRequest struct {
+	Requests             []*GetValuesRequest_GetValue `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *CountKeysRequest) Reset()         { *m = CountKeysRequest{} }
+func (m *CountKeysRequest) String() string { return proto.CompactTextString(m) }
+func (*CountKeysRequest) ProtoMessage()    {}
+func (*CountKeysRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{9}
+}
+
+func (m *CountKeysRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountKeysRequest.Unmarshal(m, b)
+}
+func (m *CountKeysRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountKeysRequest.Marshal(b, m, deterministic)
+}
+func (m *CountKeysRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountKeysRequest.Merge(m, src)
+}
+func (m *CountKeysRequest) XXX_Size() int {
+	return xxx_messageInfo_CountKeysRequest.Size(m)
+}
+func (m *CountKeysRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountKeysRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountKeysRequest proto.InternalMessageInfo
+
+func (m *CountKeysRequest) GetRequests() []*GetValuesRequest_GetValue {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+type CountKeysResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Count  int64           `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
+	// total_value_bytes is the size of every value counted.
+	TotalValueBytes      int64    `protobuf:"varint,3,opt,name=total_value_bytes,json=totalValueBytes,proto3" json:"total_value_bytes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CountKeysResponse) Reset()         { *m = CountKeysResponse{} }
+func (m *CountKeysResponse) String() string { return proto.CompactTextString(m) }
+func (*CountKeysResponse) ProtoMessage()    {}
+func (*CountKeysResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{10}
+}
+
+func (m *CountKeysResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CountKeysResponse.Unmarshal(m, b)
+}
+func (m *CountKeysResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CountKeysResponse.Marshal(b, m, deterministic)
+}
+func (m *CountKeysResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CountKeysResponse.Merge(m, src)
+}
+func (m *CountKeysResponse) XXX_Size() int {
+	return xxx_messageInfo_CountKeysResponse.Size(m)
+}
+func (m *CountKeysResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CountKeysResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CountKeysResponse proto.InternalMessageInfo
+
+func (m *CountKeysResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *CountKeysResponse) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *CountKeysResponse) GetTotalValueBytes() int64 {
+	if m != nil {
+		return m.TotalValueBytes
+	}
+	return 0
+}
+
+type StatRequest struct {
+	Requests             []*GetValuesRequest_GetValue `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                     `json:"-"`
+	XXX_unrecognized     []byte                       `json:"-"`
+	XXX_sizecache        int32                        `json:"-"`
+}
+
+func (m *StatRequest) Reset()         { *m = StatRequest{} }
+func (m *StatRequest) String() string { return proto.CompactTextString(m) }
+func (*StatRequest) ProtoMessage()    {}
+func (*StatRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{11}
+}
+
+func (m *StatRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatRequest.Unmarshal(m, b)
+}
+func (m *StatRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatRequest.Marshal(b, m, deterministic)
+}
+func (m *StatRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatRequest.Merge(m, src)
+}
+func (m *StatRequest) XXX_Size() int {
+	return xxx_messageInfo_StatRequest.Size(m)
+}
+func (m *StatRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatRequest proto.InternalMessageInfo
+
+func (m *StatRequest) GetRequests() []*GetValuesRequest_GetValue {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+type StatResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Stats  []*KeyStat      `protobuf:"bytes,2,rep,name=stats,proto3" json:"stats,omitempty"`
+	Count  int64           `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	// total_value_bytes is the size of every value in stats.
+	TotalValueBytes int64 `protobuf:"varint,4,opt,name=total_value_bytes,json=totalValueBytes,proto3" json:"total_value_bytes,omitempty"`
+	// next_start_after continues limited requests like it does in
+	// GetValuesResponse.
+	NextStartAfter       []string `protobuf:"bytes,5,rep,name=next_start_after,json=nextStartAfter,proto3" json:"next_start_after,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *StatResponse) Reset()         { *m = StatResponse{} }
+func (m *StatResponse) String() string { return proto.CompactTextString(m) }
+func (*StatResponse) ProtoMessage()    {}
+func (*StatResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{12}
+}
+
+func (m *StatResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StatResponse.Unmarshal(m, b)
+}
+func (m *StatResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StatResponse.Marshal(b, m, deterministic)
+}
+func (m *StatResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StatResponse.Merge(m, src)
+}
+func (m *StatResponse) XXX_Size() int {
+	return xxx_messageInfo_StatResponse.Size(m)
+}
+func (m *StatResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_StatResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StatResponse proto.InternalMessageInfo
+
+func (m *StatResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *StatResponse) GetStats() []*KeyStat {
+	if m != nil {
+		return m.Stats
+	}
+	return nil
+}
+
+func (m *StatResponse) GetCount() int64 {
+	if m != nil {
+		return m.Count
+	}
+	return 0
+}
+
+func (m *StatResponse) GetTotalValueBytes() int64 {
+	if m != nil {
+		return m.TotalValueBytes
+	}
+	return 0
+}
+
+func (m *StatResponse) GetNextStartAfter() []string {
+	if m != nil {
+		return m.NextStartAfter
+	}
+	return nil
+}
+
+// KeyStat is the metadata of a key.
+type KeyStat struct {
+	Key         string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	ModRevision uint64 `protobuf:"varint,2,opt,name=mod_revision,json=modRevision,proto3" json:"mod_revision,omitempty"`
+	// value_size is the size of the value, as it was written.
+	ValueSize int64 `protobuf:"varint,3,opt,name=value_size,json=valueSize,proto3" json:"value_size,omitempty"`
+	// expires_at is empty for keys without a ttl.
+	ExpiresAt            *timestamp.Timestamp `protobuf:"bytes,4,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	Lease                int64                `protobuf:"varint,5,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *KeyStat) Reset()         { *m = KeyStat{} }
+func (m *KeyStat) String() string { return proto.CompactTextString(m) }
+func (*KeyStat) ProtoMessage()    {}
+func (*KeyStat) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{13}
+}
+
+func (m *KeyStat) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyStat.Unmarshal(m, b)
+}
+func (m *KeyStat) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyStat.Marshal(b, m, deterministic)
+}
+func (m *KeyStat) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyStat.Merge(m, src)
+}
+func (m *KeyStat) XXX_Size() int {
+	return xxx_messageInfo_KeyStat.Size(m)
+}
+func (m *KeyStat) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyStat.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyStat proto.InternalMessageInfo
+
+func (m *KeyStat) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *KeyStat) GetModRevision() uint64 {
+	if m != nil {
+		return m.ModRevision
+	}
+	return 0
+}
+
+func (m *KeyStat) GetValueSize() int64 {
+	if m != nil {
+		return m.ValueSize
+	}
+	return 0
+}
+
+func (m *KeyStat) GetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *KeyStat) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+type GetHistoryRequest struct {
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// limit is the maximum number of versions to return, or zero for every
+	// retained version.
+	Limit                int64    `protobuf:"varint,2,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetHistoryRequest) Reset()         { *m = GetHistoryRequest{} }
+func (m *GetHistoryRequest) String() string { return proto.CompactTextString(m) }
+func (*GetHistoryRequest) ProtoMessage()    {}
+func (*GetHistoryRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{14}
+}
+
+func (m *GetHistoryRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetHistoryRequest.Unmarshal(m, b)
+}
+func (m *GetHistoryRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetHistoryRequest.Marshal(b, m, deterministic)
+}
+func (m *GetHistoryRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetHistoryRequest.Merge(m, src)
+}
+func (m *GetHistoryRequest) XXX_Size() int {
+	return xxx_messageInfo_GetHistoryRequest.Size(m)
+}
+func (m *GetHistoryRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetHistoryRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetHistoryRequest proto.InternalMessageInfo
+
+func (m *GetHistoryRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *GetHistoryRequest) GetLimit() int64 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type GetHistoryResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// versions are newest first. Deletes and expirations are left out.
+	Versions             []*KeyVersion `protobuf:"bytes,2,rep,name=versions,proto3" json:"versions,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}      `json:"-"`
+	XXX_unrecognized     []byte        `json:"-"`
+	XXX_sizecache        int32         `json:"-"`
+}
+
+func (m *GetHistoryResponse) Reset()         { *m = GetHistoryResponse{} }
+func (m *GetHistoryResponse) String() string { return proto.CompactTextString(m) }
+func (*GetHistoryResponse) ProtoMessage()    {}
+func (*GetHistoryResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{15}
+}
+
+func (m *GetHistoryResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetHistoryResponse.Unmarshal(m, b)
+}
+func (m *GetHistoryResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetHistoryResponse.Marshal(b, m, deterministic)
+}
+func (m *GetHistoryResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetHistoryResponse.Merge(m, src)
+}
+func (m *GetHistoryResponse) XXX_Size() int {
+	return xxx_messageInfo_GetHistoryResponse.Size(m)
+}
+func (m *GetHistoryResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetHistoryResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetHistoryResponse proto.InternalMessageInfo
+
+func (m *GetHistoryResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *GetHistoryResponse) GetVersions() []*KeyVersion {
+	if m != nil {
+		return m.Versions
+	}
+	return nil
+}
+
+// KeyVersion is a retained version of a key.
+type KeyVersion struct {
+	Kv *KeyValue `protobuf:"bytes,1,opt,name=kv,proto3" json:"kv,omitempty"`
+	// mod_time is when the version was written, or empty for versions written
+	// before write times were recorded.
+	ModTime              *timestamp.Timestamp `protobuf:"bytes,2,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *KeyVersion) Reset()         { *m = KeyVersion{} }
+func (m *KeyVersion) String() string { return proto.CompactTextString(m) }
+func (*KeyVersion) ProtoMessage()    {}
+func (*KeyVersion) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{16}
+}
+
+func (m *KeyVersion) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeyVersion.Unmarshal(m, b)
+}
+func (m *KeyVersion) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeyVersion.Marshal(b, m, deterministic)
+}
+func (m *KeyVersion) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeyVersion.Merge(m, src)
+}
+func (m *KeyVersion) XXX_Size() int {
+	return xxx_messageInfo_KeyVersion.Size(m)
+}
+func (m *KeyVersion) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeyVersion.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeyVersion proto.InternalMessageInfo
+
+func (m *KeyVersion) GetKv() *KeyValue {
+	if m != nil {
+		return m.Kv
+	}
+	return nil
+}
+
+func (m *KeyVersion) GetModTime() *timestamp.Timestamp {
+	if m != nil {
+		return m.ModTime
+	}
+	return nil
+}
+
+type DeleteValuesRequest struct {
+	Requests             []*DeleteValuesRequest_DeleteValue `protobuf:"bytes,1,rep,name=requests,proto3" json:"requests,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                           `json:"-"`
+	XXX_unrecognized     []byte                             `json:"-"`
+	XXX_sizecache        int32                              `json:"-"`
+}
+
+func (m *DeleteValuesRequest) Reset()         { *m = DeleteValuesRequest{} }
+func (m *DeleteValuesRequest) String() string { return proto.CompactTextString(m) }
+func (*DeleteValuesRequest) ProtoMessage()    {}
+func (*DeleteValuesRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{17}
+}
+
+func (m *DeleteValuesRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteValuesRequest.Unmarshal(m, b)
+}
+func (m *DeleteValuesRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteValuesRequest.Marshal(b, m, deterministic)
+}
+func (m *DeleteValuesRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteValuesRequest.Merge(m, src)
+}
+func (m *DeleteValuesRequest) XXX_Size() int {
+	return xxx_messageInfo_DeleteValuesRequest.Size(m)
+}
+func (m *DeleteValuesRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteValuesRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteValuesRequest proto.InternalMessageInfo
+
+func (m *DeleteValuesRequest) GetRequests() []*DeleteValuesRequest_DeleteValue {
+	if m != nil {
+		return m.Requests
+	}
+	return nil
+}
+
+// DeleteValue is a delete value request.
+type DeleteValuesRequest_DeleteValue struct {
+	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	IsPrefix             bool     `protobuf:"varint,2,opt,name=is_prefix,json=isPrefix,proto3" json:"is_prefix,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeleteValuesRequest_DeleteValue) Reset()         { *m = DeleteValuesRequest_DeleteValue{} }
+func (m *DeleteValuesRequest_DeleteValue) String() string { return proto.CompactTextString(m) }
+func (*DeleteValuesRequest_DeleteValue) ProtoMessage()    {}
+func (*DeleteValuesRequest_DeleteValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{17, 0}
+}
+
+func (m *DeleteValuesRequest_DeleteValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteValuesRequest_DeleteValue.Unmarshal(m, b)
+}
+func (m *DeleteValuesRequest_DeleteValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteValuesRequest_DeleteValue.Marshal(b, m, deterministic)
+}
+func (m *DeleteValuesRequest_DeleteValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteValuesRequest_DeleteValue.Merge(m, src)
+}
+func (m *DeleteValuesRequest_DeleteValue) XXX_Size() int {
+	return xxx_messageInfo_DeleteValuesRequest_DeleteValue.Size(m)
+}
+func (m *DeleteValuesRequest_DeleteValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteValuesRequest_DeleteValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteValuesRequest_DeleteValue proto.InternalMessageInfo
+
+func (m *DeleteValuesRequest_DeleteValue) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *DeleteValuesRequest_DeleteValue) GetIsPrefix() bool {
+	if m != nil {
+		return m.IsPrefix
+	}
+	return false
+}
+
+type DeleteValuesResponse struct {
+	DeletedCount         int64           `protobuf:"varint,1,opt,name=deleted_count,json=deletedCount,proto3" json:"deleted_count,omitempty"`
+	Header               *ResponseHeader `protobuf:"bytes,2,opt,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *DeleteValuesResponse) Reset()         { *m = DeleteValuesResponse{} }
+func (m *DeleteValuesResponse) String() string { return proto.CompactTextString(m) }
+func (*DeleteValuesResponse) ProtoMessage()    {}
+func (*DeleteValuesResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{18}
+}
+
+func (m *DeleteValuesResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeleteValuesResponse.Unmarshal(m, b)
+}
+func (m *DeleteValuesResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeleteValuesResponse.Marshal(b, m, deterministic)
+}
+func (m *DeleteValuesResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeleteValuesResponse.Merge(m, src)
+}
+func (m *DeleteValuesResponse) XXX_Size() int {
+	return xxx_messageInfo_DeleteValuesResponse.Size(m)
+}
+func (m *DeleteValuesResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeleteValuesResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeleteValuesResponse proto.InternalMessageInfo
+
+func (m *DeleteValuesResponse) GetDeletedCount() int64 {
+	if m != nil {
+		return m.DeletedCount
+	}
+	return 0
+}
+
+func (m *DeleteValuesResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+type IncrementRequest struct {
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Delta int64  `protobuf:"varint,2,opt,name=delta,proto3" json:"delta,omitempty"`
+	// ttl_duration is the ttl of the key when the increment creates it. Later
+	// increments keep the expiry of the key.
+	TtlDuration *duration.Duration `protobuf:"bytes,3,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	// min and max bound the new value. Nothing is written when the new value
+	// would be out of bounds.
+	Min                  *wrappers.Int64Value `protobuf:"bytes,4,opt,name=min,proto3" json:"min,omitempty"`
+	Max                  *wrappers.Int64Value `protobuf:"bytes,5,opt,name=max,proto3" json:"max,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *IncrementRequest) Reset()         { *m = IncrementRequest{} }
+func (m *IncrementRequest) String() string { return proto.CompactTextString(m) }
+func (*IncrementRequest) ProtoMessage()    {}
+func (*IncrementRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{19}
+}
+
+func (m *IncrementRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrementRequest.Unmarshal(m, b)
+}
+func (m *IncrementRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrementRequest.Marshal(b, m, deterministic)
+}
+func (m *IncrementRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrementRequest.Merge(m, src)
+}
+func (m *IncrementRequest) XXX_Size() int {
+	return xxx_messageInfo_IncrementRequest.Size(m)
+}
+func (m *IncrementRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrementRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrementRequest proto.InternalMessageInfo
+
+func (m *IncrementRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *IncrementRequest) GetDelta() int64 {
+	if m != nil {
+		return m.Delta
+	}
+	return 0
+}
+
+func (m *IncrementRequest) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+func (m *IncrementRequest) GetMin() *wrappers.Int64Value {
+	if m != nil {
+		return m.Min
+	}
+	return nil
+}
+
+func (m *IncrementRequest) GetMax() *wrappers.Int64Value {
+	if m != nil {
+		return m.Max
+	}
+	return nil
+}
+
+type IncrementResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// succeeded is false when the new value would have been out of bounds.
+	Succeeded bool `protobuf:"varint,2,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	// value is the new value, or the current value when the increment did not
+	// succeed.
+	Value                int64    `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IncrementResponse) Reset()         { *m = IncrementResponse{} }
+func (m *IncrementResponse) String() string { return proto.CompactTextString(m) }
+func (*IncrementResponse) ProtoMessage()    {}
+func (*IncrementResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{20}
+}
+
+func (m *IncrementResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IncrementResponse.Unmarshal(m, b)
+}
+func (m *IncrementResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IncrementResponse.Marshal(b, m, deterministic)
+}
+func (m *IncrementResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IncrementResponse.Merge(m, src)
+}
+func (m *IncrementResponse) XXX_Size() int {
+	return xxx_messageInfo_IncrementResponse.Size(m)
+}
+func (m *IncrementResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_IncrementResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IncrementResponse proto.InternalMessageInfo
+
+func (m *IncrementResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *IncrementResponse) GetSucceeded() bool {
+	if m != nil {
+		return m.Succeeded
+	}
+	return false
+}
+
+func (m *IncrementResponse) GetValue() int64 {
+	if m != nil {
+		return m.Value
+	}
+	return 0
+}
+
+type TxnRequest struct {
+	Compare              []*Precondition `protobuf:"bytes,1,rep,name=compare,proto3" json:"compare,omitempty"`
+	Success              []*TxnOperation `protobuf:"bytes,2,rep,name=success,proto3" json:"success,omitempty"`
+	Failure              []*TxnOperation `protobuf:"bytes,3,rep,name=failure,proto3" json:"failure,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *TxnRequest) Reset()         { *m = TxnRequest{} }
+func (m *TxnRequest) String() string { return proto.CompactTextString(m) }
+func (*TxnRequest) ProtoMessage()    {}
+func (*TxnRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{21}
+}
+
+func (m *TxnRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnRequest.Unmarshal(m, b)
+}
+func (m *TxnRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnRequest.Marshal(b, m, deterministic)
+}
+func (m *TxnRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnRequest.Merge(m, src)
+}
+func (m *TxnRequest) XXX_Size() int {
+	return xxx_messageInfo_TxnRequest.Size(m)
+}
+func (m *TxnRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnRequest proto.InternalMessageInfo
+
+func (m *TxnRequest) GetCompare() []*Precondition {
+	if m != nil {
+		return m.Compare
+	}
+	return nil
+}
+
+func (m *TxnRequest) GetSuccess() []*TxnOperation {
+	if m != nil {
+		return m.Success
+	}
+	return nil
+}
+
+func (m *TxnRequest) GetFailure() []*TxnOperation {
+	if m != nil {
+		return m.Failure
+	}
+	return nil
+}
+
+type TxnResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// succeeded is true when every comparison held and the success operations
+	// ran.
+	Succeeded bool `protobuf:"varint,2,opt,name=succeeded,proto3" json:"succeeded,omitempty"`
+	// results has one result for each operation that ran, in order.
+	Results              []*TxnOperationResult `protobuf:"bytes,3,rep,name=results,proto3" json:"results,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
+	XXX_unrecognized     []byte                `json:"-"`
+	XXX_sizecache        int32                 `json:"-"`
+}
+
+func (m *TxnResponse) Reset()         { *m = TxnResponse{} }
+func (m *TxnResponse) String() string { return proto.CompactTextString(m) }
+func (*TxnResponse) ProtoMessage()    {}
+func (*TxnResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{22}
+}
+
+func (m *TxnResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnResponse.Unmarshal(m, b)
+}
+func (m *TxnResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnResponse.Marshal(b, m, deterministic)
+}
+func (m *TxnResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnResponse.Merge(m, src)
+}
+func (m *TxnResponse) XXX_Size() int {
+	return xxx_messageInfo_TxnResponse.Size(m)
+}
+func (m *TxnResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnResponse proto.InternalMessageInfo
+
+func (m *TxnResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *TxnResponse) GetSucceeded() bool {
+	if m != nil {
+		return m.Succeeded
+	}
+	return false
+}
+
+func (m *TxnResponse) GetResults() []*TxnOperationResult {
+	if m != nil {
+		return m.Results
+	}
+	return nil
+}
+
+// TxnOperation is an operation run inside a transaction.
+type TxnOperation struct {
+	// Types that are valid to be assigned to Operation:
+	//	*TxnOperation_Get
+	//	*TxnOperation_Set
+	//	*TxnOperation_Delete
+	Operation            isTxnOperation_Operation `protobuf_oneof:"operation"`
+	XXX_NoUnkeyedLiteral struct{}                 `json:"-"`
+	XXX_unrecognized     []byte                   `json:"-"`
+	XXX_sizecache        int32                    `json:"-"`
+}
+
+func (m *TxnOperation) Reset()         { *m = TxnOperation{} }
+func (m *TxnOperation) String() string { return proto.CompactTextString(m) }
+func (*TxnOperation) ProtoMessage()    {}
+func (*TxnOperation) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{23}
+}
+
+func (m *TxnOperation) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnOperation.Unmarshal(m, b)
+}
+func (m *TxnOperation) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnOperation.Marshal(b, m, deterministic)
+}
+func (m *TxnOperation) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnOperation.Merge(m, src)
+}
+func (m *TxnOperation) XXX_Size() int {
+	return xxx_messageInfo_TxnOperation.Size(m)
+}
+func (m *TxnOperation) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnOperation.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnOperation proto.InternalMessageInfo
+
+type isTxnOperation_Operation interface {
+	isTxnOperation_Operation()
+}
+
+type TxnOperation_Get struct {
+	Get *GetValuesRequest_GetValue `protobuf:"bytes,1,opt,name=get,proto3,oneof"`
+}
+
+type TxnOperation_Set struct {
+	Set *TxnOperation_SetValue `protobuf:"bytes,2,opt,name=set,proto3,oneof"`
+}
+
+type TxnOperation_Delete struct {
+	Delete *DeleteValuesRequest_DeleteValue `protobuf:"bytes,3,opt,name=delete,proto3,oneof"`
+}
+
+func (*TxnOperation_Get) isTxnOperation_Operation() {}
+
+func (*TxnOperation_Set) isTxnOperation_Operation() {}
+
+func (*TxnOperation_Delete) isTxnOperation_Operation() {}
+
+func (m *TxnOperation) GetOperation() isTxnOperation_Operation {
+	if m != nil {
+		return m.Operation
+	}
+	return nil
+}
+
+func (m *TxnOperation) GetGet() *GetValuesRequest_GetValue {
+	if x, ok := m.GetOperation().(*TxnOperation_Get); ok {
+		return x.Get
+	}
+	return nil
+}
+
+func (m *TxnOperation) GetSet() *TxnOperation_SetValue {
+	if x, ok := m.GetOperation().(*TxnOperation_Set); ok {
+		return x.Set
+	}
+	return nil
+}
+
+func (m *TxnOperation) GetDelete() *DeleteValuesRequest_DeleteValue {
+	if x, ok := m.GetOperation().(*TxnOperation_Delete); ok {
+		return x.Delete
+	}
+	return nil
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*TxnOperation) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*TxnOperation_Get)(nil),
+		(*TxnOperation_Set)(nil),
+		(*TxnOperation_Delete)(nil),
+	}
+}
+
+// SetValue is a set value operation.
+type TxnOperation_SetValue struct {
+	Message              *KeyValue          `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	TtlDuration          *duration.Duration `protobuf:"bytes,2,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	Lease                int64              `protobuf:"varint,3,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *TxnOperation_SetValue) Reset()         { *m = TxnOperation_SetValue{} }
+func (m *TxnOperation_SetValue) String() string { return proto.CompactTextString(m) }
+func (*TxnOperation_SetValue) ProtoMessage()    {}
+func (*TxnOperation_SetValue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{23, 0}
+}
+
+func (m *TxnOperation_SetValue) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnOperation_SetValue.Unmarshal(m, b)
+}
+func (m *TxnOperation_SetValue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnOperation_SetValue.Marshal(b, m, deterministic)
+}
+func (m *TxnOperation_SetValue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnOperation_SetValue.Merge(m, src)
+}
+func (m *TxnOperation_SetValue) XXX_Size() int {
+	return xxx_messageInfo_TxnOperation_SetValue.Size(m)
+}
+func (m *TxnOperation_SetValue) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnOperation_SetValue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnOperation_SetValue proto.InternalMessageInfo
+
+func (m *TxnOperation_SetValue) GetMessage() *KeyValue {
+	if m != nil {
+		return m.Message
+	}
+	return nil
+}
+
+func (m *TxnOperation_SetValue) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+func (m *TxnOperation_SetValue) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+// TxnOperationResult is the result of an operation run inside a transaction.
+type TxnOperationResult struct {
+	// messages are the key values read by a get operation.
+	Messages []*KeyValue `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	// deleted_count is how many keys a delete operation removed.
+	DeletedCount         int64    `protobuf:"varint,2,opt,name=deleted_count,json=deletedCount,proto3" json:"deleted_count,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TxnOperationResult) Reset()         { *m = TxnOperationResult{} }
+func (m *TxnOperationResult) String() string { return proto.CompactTextString(m) }
+func (*TxnOperationResult) ProtoMessage()    {}
+func (*TxnOperationResult) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{24}
+}
+
+func (m *TxnOperationResult) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TxnOperationResult.Unmarshal(m, b)
+}
+func (m *TxnOperationResult) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TxnOperationResult.Marshal(b, m, deterministic)
+}
+func (m *TxnOperationResult) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TxnOperationResult.Merge(m, src)
+}
+func (m *TxnOperationResult) XXX_Size() int {
+	return xxx_messageInfo_TxnOperationResult.Size(m)
+}
+func (m *TxnOperationResult) XXX_DiscardUnknown() {
+	xxx_messageInfo_TxnOperationResult.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TxnOperationResult proto.InternalMessageInfo
+
+func (m *TxnOperationResult) GetMessages() []*KeyValue {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func (m *TxnOperationResult) GetDeletedCount() int64 {
+	if m != nil {
+		return m.DeletedCount
+	}
+	return 0
+}
+
+type GrantLeaseRequest struct {
+	TtlDuration          *duration.Duration `protobuf:"bytes,1,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *GrantLeaseRequest) Reset()         { *m = GrantLeaseRequest{} }
+func (m *GrantLeaseRequest) String() string { return proto.CompactTextString(m) }
+func (*GrantLeaseRequest) ProtoMessage()    {}
+func (*GrantLeaseRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{25}
+}
+
+func (m *GrantLeaseRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GrantLeaseRequest.Unmarshal(m, b)
+}
+func (m *GrantLeaseRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GrantLeaseRequest.Marshal(b, m, deterministic)
+}
+func (m *GrantLeaseRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GrantLeaseRequest.Merge(m, src)
+}
+func (m *GrantLeaseRequest) XXX_Size() int {
+	return xxx_messageInfo_GrantLeaseRequest.Size(m)
+}
+func (m *GrantLeaseRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GrantLeaseRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GrantLeaseRequest proto.InternalMessageInfo
+
+func (m *GrantLeaseRequest) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+type GrantLeaseResponse struct {
+	Header               *ResponseHeader    `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Id                   int64              `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	TtlDuration          *duration.Duration `protobuf:"bytes,3,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *GrantLeaseResponse) Reset()         { *m = GrantLeaseResponse{} }
+func (m *GrantLeaseResponse) String() string { return proto.CompactTextString(m) }
+func (*GrantLeaseResponse) ProtoMessage()    {}
+func (*GrantLeaseResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{26}
+}
+
+func (m *GrantLeaseResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GrantLeaseResponse.Unmarshal(m, b)
+}
+func (m *GrantLeaseResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GrantLeaseResponse.Marshal(b, m, deterministic)
+}
+func (m *GrantLeaseResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GrantLeaseResponse.Merge(m, src)
+}
+func (m *GrantLeaseResponse) XXX_Size() int {
+	return xxx_messageInfo_GrantLeaseResponse.Size(m)
+}
+func (m *GrantLeaseResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_GrantLeaseResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GrantLeaseResponse proto.InternalMessageInfo
+
+func (m *GrantLeaseResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *GrantLeaseResponse) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *GrantLeaseResponse) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+type KeepAliveRequest struct {
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *KeepAliveRequest) Reset()         { *m = KeepAliveRequest{} }
+func (m *KeepAliveRequest) String() string { return proto.CompactTextString(m) }
+func (*KeepAliveRequest) ProtoMessage()    {}
+func (*KeepAliveRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{27}
+}
+
+func (m *KeepAliveRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeepAliveRequest.Unmarshal(m, b)
+}
+func (m *KeepAliveRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeepAliveRequest.Marshal(b, m, deterministic)
+}
+func (m *KeepAliveRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeepAliveRequest.Merge(m, src)
+}
+func (m *KeepAliveRequest) XXX_Size() int {
+	return xxx_messageInfo_KeepAliveRequest.Size(m)
+}
+func (m *KeepAliveRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeepAliveRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeepAliveRequest proto.InternalMessageInfo
+
+func (m *KeepAliveRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type KeepAliveResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Id     int64           `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	// ttl_duration is the refreshed time to live.
+	TtlDuration          *duration.Duration `protobuf:"bytes,3,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *KeepAliveResponse) Reset()         { *m = KeepAliveResponse{} }
+func (m *KeepAliveResponse) String() string { return proto.CompactTextString(m) }
+func (*KeepAliveResponse) ProtoMessage()    {}
+func (*KeepAliveResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{28}
+}
+
+func (m *KeepAliveResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_KeepAliveResponse.Unmarshal(m, b)
+}
+func (m *KeepAliveResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_KeepAliveResponse.Marshal(b, m, deterministic)
+}
+func (m *KeepAliveResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_KeepAliveResponse.Merge(m, src)
+}
+func (m *KeepAliveResponse) XXX_Size() int {
+	return xxx_messageInfo_KeepAliveResponse.Size(m)
+}
+func (m *KeepAliveResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_KeepAliveResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_KeepAliveResponse proto.InternalMessageInfo
+
+func (m *KeepAliveResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *KeepAliveResponse) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *KeepAliveResponse) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+type RevokeLeaseRequest struct {
+	Id                   int64    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *RevokeLeaseRequest) Reset()         { *m = RevokeLeaseRequest{} }
+func (m *RevokeLeaseRequest) String() string { return proto.CompactTextString(m) }
+func (*RevokeLeaseRequest) ProtoMessage()    {}
+func (*RevokeLeaseRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{29}
+}
+
+func (m *RevokeLeaseRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeLeaseRequest.Unmarshal(m, b)
+}
+func (m *RevokeLeaseRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeLeaseRequest.Marshal(b, m, deterministic)
+}
+func (m *RevokeLeaseRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeLeaseRequest.Merge(m, src)
+}
+func (m *RevokeLeaseRequest) XXX_Size() int {
+	return xxx_messageInfo_RevokeLeaseRequest.Size(m)
+}
+func (m *RevokeLeaseRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeLeaseRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeLeaseRequest proto.InternalMessageInfo
+
+func (m *RevokeLeaseRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type RevokeLeaseResponse struct {
+	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *RevokeLeaseResponse) Reset()         { *m = RevokeLeaseResponse{} }
+func (m *RevokeLeaseResponse) String() string { return proto.CompactTextString(m) }
+func (*RevokeLeaseResponse) ProtoMessage()    {}
+func (*RevokeLeaseResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{30}
+}
+
+func (m *RevokeLeaseResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_RevokeLeaseResponse.Unmarshal(m, b)
+}
+func (m *RevokeLeaseResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_RevokeLeaseResponse.Marshal(b, m, deterministic)
+}
+func (m *RevokeLeaseResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_RevokeLeaseResponse.Merge(m, src)
+}
+func (m *RevokeLeaseResponse) XXX_Size() int {
+	return xxx_messageInfo_RevokeLeaseResponse.Size(m)
+}
+func (m *RevokeLeaseResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_RevokeLeaseResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_RevokeLeaseResponse proto.InternalMessageInfo
+
+func (m *RevokeLeaseResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+type LeaseTimeToLiveRequest struct {
+	Id int64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	// keys includes the keys attached to the lease in the response.
+	Keys                 bool     `protobuf:"varint,2,opt,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LeaseTimeToLiveRequest) Reset()         { *m = LeaseTimeToLiveRequest{} }
+func (m *LeaseTimeToLiveRequest) String() string { return proto.CompactTextString(m) }
+func (*LeaseTimeToLiveRequest) ProtoMessage()    {}
+func (*LeaseTimeToLiveRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{31}
+}
+
+func (m *LeaseTimeToLiveRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LeaseTimeToLiveRequest.Unmarshal(m, b)
+}
+func (m *LeaseTimeToLiveRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LeaseTimeToLiveRequest.Marshal(b, m, deterministic)
+}
+func (m *LeaseTimeToLiveRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LeaseTimeToLiveRequest.Merge(m, src)
+}
+func (m *LeaseTimeToLiveRequest) XXX_Size() int {
+	return xxx_messageInfo_LeaseTimeToLiveRequest.Size(m)
+}
+func (m *LeaseTimeToLiveRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LeaseTimeToLiveRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LeaseTimeToLiveRequest proto.InternalMessageInfo
+
+func (m *LeaseTimeToLiveRequest) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *LeaseTimeToLiveRequest) GetKeys() bool {
+	if m != nil {
+		return m.Keys
+	}
+	return false
+}
+
+type LeaseTimeToLiveResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Id     int64           `protobuf:"varint,2,opt,name=id,proto3" json:"id,omitempty"`
+	// ttl_duration is the remaining time to live.
+	TtlDuration          *duration.Duration `protobuf:"bytes,3,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	GrantedTtlDuration   *duration.Duration `protobuf:"bytes,4,opt,name=granted_ttl_duration,json=grantedTtlDuration,proto3" json:"granted_ttl_duration,omitempty"`
+	Keys                 []string           `protobuf:"bytes,5,rep,name=keys,proto3" json:"keys,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *LeaseTimeToLiveResponse) Reset()         { *m = LeaseTimeToLiveResponse{} }
+func (m *LeaseTimeToLiveResponse) String() string { return proto.CompactTextString(m) }
+func (*LeaseTimeToLiveResponse) ProtoMessage()    {}
+func (*LeaseTimeToLiveResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{32}
+}
+
+func (m *LeaseTimeToLiveResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LeaseTimeToLiveResponse.Unmarshal(m, b)
+}
+func (m *LeaseTimeToLiveResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LeaseTimeToLiveResponse.Marshal(b, m, deterministic)
+}
+func (m *LeaseTimeToLiveResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LeaseTimeToLiveResponse.Merge(m, src)
+}
+func (m *LeaseTimeToLiveResponse) XXX_Size() int {
+	return xxx_messageInfo_LeaseTimeToLiveResponse.Size(m)
+}
+func (m *LeaseTimeToLiveResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LeaseTimeToLiveResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LeaseTimeToLiveResponse proto.InternalMessageInfo
+
+func (m *LeaseTimeToLiveResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *LeaseTimeToLiveResponse) GetId() int64 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *LeaseTimeToLiveResponse) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+func (m *LeaseTimeToLiveResponse) GetGrantedTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.GrantedTtlDuration
+	}
+	return nil
+}
+
+func (m *LeaseTimeToLiveResponse) GetKeys() []string {
+	if m != nil {
+		return m.Keys
+	}
+	return nil
+}
+
+type LockRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// lease owns the lock. It is required.
+	Lease                int64    `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LockRequest) Reset()         { *m = LockRequest{} }
+func (m *LockRequest) String() string { return proto.CompactTextString(m) }
+func (*LockRequest) ProtoMessage()    {}
+func (*LockRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{33}
+}
+
+func (m *LockRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LockRequest.Unmarshal(m, b)
+}
+func (m *LockRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LockRequest.Marshal(b, m, deterministic)
+}
+func (m *LockRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LockRequest.Merge(m, src)
+}
+func (m *LockRequest) XXX_Size() int {
+	return xxx_messageInfo_LockRequest.Size(m)
+}
+func (m *LockRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_LockRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LockRequest proto.InternalMessageInfo
+
+func (m *LockRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LockRequest) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+type LockResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// key is held for as long as the lock is and is passed to Unlock.
+	Key                  string   `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LockResponse) Reset()         { *m = LockResponse{} }
+func (m *LockResponse) String() string { return proto.CompactTextString(m) }
+func (*LockResponse) ProtoMessage()    {}
+func (*LockResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{34}
+}
+
+func (m *LockResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LockResponse.Unmarshal(m, b)
+}
+func (m *LockResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LockResponse.Marshal(b, m, deterministic)
+}
+func (m *LockResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LockResponse.Merge(m, src)
+}
+func (m *LockResponse) XXX_Size() int {
+	return xxx_messageInfo_LockResponse.Size(m)
+}
+func (m *LockResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_LockResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LockResponse proto.InternalMessageInfo
+
+func (m *LockResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *LockResponse) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+type UnlockRequest struct {
+	// key is the key returned from Lock.
+	Key string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	// lease is the lease the lock was acquired with. It is required.
+	Lease                int64    `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *UnlockRequest) Reset()         { *m = UnlockRequest{} }
+func (m *UnlockRequest) String() string { return proto.CompactTextString(m) }
+func (*UnlockRequest) ProtoMessage()    {}
+func (*UnlockRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{35}
+}
+
+func (m *UnlockRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnlockRequest.Unmarshal(m, b)
+}
+func (m *UnlockRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnlockRequest.Marshal(b, m, deterministic)
+}
+func (m *UnlockRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnlockRequest.Merge(m, src)
+}
+func (m *UnlockRequest) XXX_Size() int {
+	return xxx_messageInfo_UnlockRequest.Size(m)
+}
+func (m *UnlockRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnlockRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnlockRequest proto.InternalMessageInfo
+
+func (m *UnlockRequest) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *UnlockRequest) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+type UnlockResponse struct {
+	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *UnlockResponse) Reset()         { *m = UnlockResponse{} }
+func (m *UnlockResponse) String() string { return proto.CompactTextString(m) }
+func (*UnlockResponse) ProtoMessage()    {}
+func (*UnlockResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{36}
+}
+
+func (m *UnlockResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_UnlockResponse.Unmarshal(m, b)
+}
+func (m *UnlockResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_UnlockResponse.Marshal(b, m, deterministic)
+}
+func (m *UnlockResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_UnlockResponse.Merge(m, src)
+}
+func (m *UnlockResponse) XXX_Size() int {
+	return xxx_messageInfo_UnlockResponse.Size(m)
+}
+func (m *UnlockResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_UnlockResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_UnlockResponse proto.InternalMessageInfo
+
+func (m *UnlockResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+// LeaderKey identifies the leadership of a candidate in an election.
+type LeaderKey struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// key is held for as long as the candidate is leader.
+	Key string `protobuf:"bytes,2,opt,name=key,proto3" json:"key,omitempty"`
+	// revision is the create revision of the key.
+	Revision             uint64   `protobuf:"varint,3,opt,name=revision,proto3" json:"revision,omitempty"`
+	Lease                int64    `protobuf:"varint,4,opt,name=lease,proto3" json:"lease,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *LeaderKey) Reset()         { *m = LeaderKey{} }
+func (m *LeaderKey) String() string { return proto.CompactTextString(m) }
+func (*LeaderKey) ProtoMessage()    {}
+func (*LeaderKey) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{37}
+}
+
+func (m *LeaderKey) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_LeaderKey.Unmarshal(m, b)
+}
+func (m *LeaderKey) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_LeaderKey.Marshal(b, m, deterministic)
+}
+func (m *LeaderKey) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_LeaderKey.Merge(m, src)
+}
+func (m *LeaderKey) XXX_Size() int {
+	return xxx_messageInfo_LeaderKey.Size(m)
+}
+func (m *LeaderKey) XXX_DiscardUnknown() {
+	xxx_messageInfo_LeaderKey.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_LeaderKey proto.InternalMessageInfo
+
+func (m *LeaderKey) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *LeaderKey) GetKey() string {
+	if m != nil {
+		return m.Key
+	}
+	return ""
+}
+
+func (m *LeaderKey) GetRevision() uint64 {
+	if m != nil {
+		return m.Revision
+	}
+	return 0
+}
+
+func (m *LeaderKey) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+type CampaignRequest struct {
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// lease owns the candidacy. It is required.
+	Lease int64 `protobuf:"varint,2,opt,name=lease,proto3" json:"lease,omitempty"`
+	// value is announced to observers while the candidate is leader.
+	Value                []byte   `protobuf:"bytes,3,opt,name=value,proto3" json:"value,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *CampaignRequest) Reset()         { *m = CampaignRequest{} }
+func (m *CampaignRequest) String() string { return proto.CompactTextString(m) }
+func (*CampaignRequest) ProtoMessage()    {}
+func (*CampaignRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{38}
+}
+
+func (m *CampaignRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CampaignRequest.Unmarshal(m, b)
+}
+func (m *CampaignRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CampaignRequest.Marshal(b, m, deterministic)
+}
+func (m *CampaignRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CampaignRequest.Merge(m, src)
+}
+func (m *CampaignRequest) XXX_Size() int {
+	return xxx_messageInfo_CampaignRequest.Size(m)
+}
+func (m *CampaignRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_CampaignRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CampaignRequest proto.InternalMessageInfo
+
+func (m *CampaignRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *CampaignRequest) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+func (m *CampaignRequest) GetValue() []byte {
+	if m != nil {
+		return m.Value
+	}
+	return nil
+}
+
+type CampaignResponse struct {
+	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	Leader               *LeaderKey      `protobuf:"bytes,2,opt,name=leader,proto3" json:"leader,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *CampaignResponse) Reset()         { *m = CampaignResponse{} }
+func (m *CampaignResponse) String() string { return proto.CompactTextString(m) }
+func (*CampaignResponse) ProtoMessage()    {}
+func (*CampaignResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{39}
+}
+
+func (m *CampaignResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_CampaignResponse.Unmarshal(m, b)
+}
+func (m *CampaignResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_CampaignResponse.Marshal(b, m, deterministic)
+}
+func (m *CampaignResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_CampaignResponse.Merge(m, src)
+}
+func (m *CampaignResponse) XXX_Size() int {
+	return xxx_messageInfo_CampaignResponse.Size(m)
+}
+func (m *CampaignResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_CampaignResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_CampaignResponse proto.InternalMessageInfo
+
+func (m *CampaignResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *CampaignResponse) GetLeader() *LeaderKey {
+	if m != nil {
+		return m.Leader
+	}
+	return nil
+}
+
+type ResignRequest struct {
+	Leader               *LeaderKey `protobuf:"bytes,1,opt,name=leader,proto3" json:"leader,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ResignRequest) Reset()         { *m = ResignRequest{} }
+func (m *ResignRequest) String() string { return proto.CompactTextString(m) }
+func (*ResignRequest) ProtoMessage()    {}
+func (*ResignRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{40}
+}
+
+func (m *ResignRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResignRequest.Unmarshal(m, b)
+}
+func (m *ResignRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResignRequest.Marshal(b, m, deterministic)
+}
+func (m *ResignRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResignRequest.Merge(m, src)
+}
+func (m *ResignRequest) XXX_Size() int {
+	return xxx_messageInfo_ResignRequest.Size(m)
+}
+func (m *ResignRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResignRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResignRequest proto.InternalMessageInfo
+
+func (m *ResignRequest) GetLeader() *LeaderKey {
+	if m != nil {
+		return m.Leader
+	}
+	return nil
+}
+
+type ResignResponse struct {
+	Header               *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *ResignResponse) Reset()         { *m = ResignResponse{} }
+func (m *ResignResponse) String() string { return proto.CompactTextString(m) }
+func (*ResignResponse) ProtoMessage()    {}
+func (*ResignResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{41}
+}
+
+func (m *ResignResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ResignResponse.Unmarshal(m, b)
+}
+func (m *ResignResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ResignResponse.Marshal(b, m, deterministic)
+}
+func (m *ResignResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ResignResponse.Merge(m, src)
+}
+func (m *ResignResponse) XXX_Size() int {
+	return xxx_messageInfo_ResignResponse.Size(m)
+}
+func (m *ResignResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ResignResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ResignResponse proto.InternalMessageInfo
+
+func (m *ResignResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+type ObserveRequest struct {
+	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ObserveRequest) Reset()         { *m = ObserveRequest{} }
+func (m *ObserveRequest) String() string { return proto.CompactTextString(m) }
+func (*ObserveRequest) ProtoMessage()    {}
+func (*ObserveRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{42}
+}
+
+func (m *ObserveRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ObserveRequest.Unmarshal(m, b)
+}
+func (m *ObserveRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ObserveRequest.Marshal(b, m, deterministic)
+}
+func (m *ObserveRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ObserveRequest.Merge(m, src)
+}
+func (m *ObserveRequest) XXX_Size() int {
+	return xxx_messageInfo_ObserveRequest.Size(m)
+}
+func (m *ObserveRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ObserveRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ObserveRequest proto.InternalMessageInfo
+
+func (m *ObserveRequest) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type ObserveResponse struct {
+	Header *ResponseHeader `protobuf:"bytes,1,opt,name=header,proto3" json:"header,omitempty"`
+	// kv is the key and value of the leader.
+	Kv                   *KeyValue `protobuf:"bytes,2,opt,name=kv,proto3" json:"kv,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}  `json:"-"`
+	XXX_unrecognized     []byte    `json:"-"`
+	XXX_sizecache        int32     `json:"-"`
+}
+
+func (m *ObserveResponse) Reset()         { *m = ObserveResponse{} }
+func (m *ObserveResponse) String() string { return proto.CompactTextString(m) }
+func (*ObserveResponse) ProtoMessage()    {}
+func (*ObserveResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{43}
+}
+
+func (m *ObserveResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ObserveResponse.Unmarshal(m, b)
+}
+func (m *ObserveResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ObserveResponse.Marshal(b, m, deterministic)
+}
+func (m *ObserveResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ObserveResponse.Merge(m, src)
+}
+func (m *ObserveResponse) XXX_Size() int {
+	return xxx_messageInfo_ObserveResponse.Size(m)
+}
+func (m *ObserveResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ObserveResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ObserveResponse proto.InternalMessageInfo
+
+func (m *ObserveResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *ObserveResponse) GetKv() *KeyValue {
+	if m != nil {
+		return m.Kv
+	}
+	return nil
+}
+
+type SubscribeRequest struct {
+	Prefixes []string `protobuf:"bytes,1,rep,name=prefixes,proto3" json:"prefixes,omitempty"`
+	// start_revision replays every change after the revision instead of the
+	// current values. Zero starts from the current values.
+	StartRevision uint64 `protobuf:"varint,2,opt,name=start_revision,json=startRevision,proto3" json:"start_revision,omitempty"`
+	// prev_kv sends the value each change replaced along with its event.
+	PrevKv bool `protobuf:"varint,3,opt,name=prev_kv,json=prevKv,proto3" json:"prev_kv,omitempty"`
+	// skip_snapshot only sends the changes after the current revision instead
+	// of the current values first.
+	SkipSnapshot bool `protobuf:"varint,4,opt,name=skip_snapshot,json=skipSnapshot,proto3" json:"skip_snapshot,omitempty"`
+	// progress_interval sends a progress response at the interval so idle
+	// subscriptions can tell a quiet stream from a dead one. It must be at
+	// least one second, and no progress responses are sent when it is empty.
+	ProgressInterval *duration.Duration `protobuf:"bytes,5,opt,name=progress_interval,json=progressInterval,proto3" json:"progress_interval,omitempty"`
+	// patterns subscribe to the keys that match any of them, along with the
+	// keys in prefixes.
+	Patterns []*KeyPattern `protobuf:"bytes,6,rep,name=patterns,proto3" json:"patterns,omitempty"`
+	// coalesce_window batches the changes made over the window after the first
+	// one and sends only the latest change to each key, so bursts of writes to
+	// the same keys are not all sent. The changes replayed from start_revision
+	// are not coalesced.
+	CoalesceWindow       *duration.Duration                  `protobuf:"bytes,7,opt,name=coalesce_window,json=coalesceWindow,proto3" json:"coalesce_window,omitempty"`
+	SlowConsumerPolicy   SubscribeRequest_SlowConsumerPolicy `protobuf:"varint,8,opt,name=slow_consumer_policy,json=slowConsumerPolicy,proto3,enum=kvetch.api.v1.SubscribeRequest_SlowConsumerPolicy" json:"slow_consumer_policy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}                            `json:"-"`
+	XXX_unrecognized     []byte                              `json:"-"`
+	XXX_sizecache        int32                               `json:"-"`
+}
+
+func (m *SubscribeRequest) Reset()         { *m = SubscribeRequest{} }
+func (m *SubscribeRequest) String() string { return proto.CompactTextString(m) }
+func (*SubscribeRequest) ProtoMessage()    {}
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{44}
+}
+
+func (m *SubscribeRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribeRequest.Unmarshal(m, b)
+}
+func (m *SubscribeRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribeRequest.Marshal(b, m, deterministic)
+}
+func (m *SubscribeRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribeRequest.Merge(m, src)
+}
+func (m *SubscribeRequest) XXX_Size() int {
+	return xxx_messageInfo_SubscribeRequest.Size(m)
+}
+func (m *SubscribeRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribeRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribeRequest proto.InternalMessageInfo
+
+func (m *SubscribeRequest) GetPrefixes() []string {
+	if m != nil {
+		return m.Prefixes
+	}
+	return nil
+}
+
+func (m *SubscribeRequest) GetStartRevision() uint64 {
+	if m != nil {
+		return m.StartRevision
+	}
+	return 0
+}
+
+func (m *SubscribeRequest) GetPrevKv() bool {
+	if m != nil {
+		return m.PrevKv
+	}
+	return false
+}
+
+func (m *SubscribeRequest) GetSkipSnapshot() bool {
+	if m != nil {
+		return m.SkipSnapshot
+	}
+	return false
+}
+
+func (m *SubscribeRequest) GetProgressInterval() *duration.Duration {
+	if m != nil {
+		return m.ProgressInterval
+	}
+	return nil
+}
+
+func (m *SubscribeRequest) GetPatterns() []*KeyPattern {
+	if m != nil {
+		return m.Patterns
+	}
+	return nil
+}
+
+func (m *SubscribeRequest) GetCoalesceWindow() *duration.Duration {
+	if m != nil {
+		return m.CoalesceWindow
+	}
+	return nil
+}
+
+func (m *SubscribeRequest) GetSlowConsumerPolicy() SubscribeRequest_SlowConsumerPolicy {
+	if m != nil {
+		return m.SlowConsumerPolicy
+	}
+	return SubscribeRequest_SLOW_CONSUMER_POLICY_UNSPECIFIED
+}
+
+type SubscribeResponse struct {
+	// messages are the current values of keys in the initial scan or that were
+	// changed. Deleted and expired keys are only reported in events.
+	Messages []*KeyValue     `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	Events   []*Event        `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+	Header   *ResponseHeader `protobuf:"bytes,3,opt,name=header,proto3" json:"header,omitempty"`
+	// sync marks a response without values or events that is sent once the
+	// current values, or the changes a subscription resumed with, have all
+	// been sent. Its header holds the revision the subscription is caught up
+	// to, and every change after it follows.
+	Sync bool `protobuf:"varint,4,opt,name=sync,proto3" json:"sync,omitempty"`
+	// progress marks a response without values or events that is sent at the
+	// progress interval. Its header holds a revision every change before it
+	// has been sent for.
+	Progress bool `protobuf:"varint,5,opt,name=progress,proto3" json:"progress,omitempty"`
+	// dropped is set on a response without values or events that is sent in
+	// place of the changes SLOW_CONSUMER_POLICY_DROP_OLDEST left out. It is
+	// the number of changes left out, and its header holds the revision of the
+	// latest of them. Clients that need every change should get the current
+	// values again.
+	Dropped              uint64   `protobuf:"varint,6,opt,name=dropped,proto3" json:"dropped,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SubscribeResponse) Reset()         { *m = SubscribeResponse{} }
+func (m *SubscribeResponse) String() string { return proto.CompactTextString(m) }
+func (*SubscribeResponse) ProtoMessage()    {}
+func (*SubscribeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{45}
+}
+
+func (m *SubscribeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SubscribeResponse.Unmarshal(m, b)
+}
+func (m *SubscribeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SubscribeResponse.Marshal(b, m, deterministic)
+}
+func (m *SubscribeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SubscribeResponse.Merge(m, src)
+}
+func (m *SubscribeResponse) XXX_Size() int {
+	return xxx_messageInfo_SubscribeResponse.Size(m)
+}
+func (m *SubscribeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SubscribeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SubscribeResponse proto.InternalMessageInfo
+
+func (m *SubscribeResponse) GetMessages() []*KeyValue {
+	if m != nil {
+		return m.Messages
+	}
+	return nil
+}
+
+func (m *SubscribeResponse) GetEvents() []*Event {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+func (m *SubscribeResponse) GetHeader() *ResponseHeader {
+	if m != nil {
+		return m.Header
+	}
+	return nil
+}
+
+func (m *SubscribeResponse) GetSync() bool {
+	if m != nil {
+		return m.Sync
+	}
+	return false
+}
+
+func (m *SubscribeResponse) GetProgress() bool {
+	if m != nil {
+		return m.Progress
+	}
+	return false
+}
+
+func (m *SubscribeResponse) GetDropped() uint64 {
+	if m != nil {
+		return m.Dropped
+	}
+	return 0
+}
+
+// Event is a change to a key in the datastore.
+type Event struct {
+	Type Event_Type `protobuf:"varint,1,opt,name=type,proto3,enum=kvetch.api.v1.Event_Type" json:"type,omitempty"`
+	Kv   *KeyValue  `protobuf:"bytes,2,opt,name=kv,proto3" json:"kv,omitempty"`
+	// meta is the user meta byte badger stored with the entry.
+	Meta uint32 `protobuf:"varint,3,opt,name=meta,proto3" json:"meta,omitempty"`
+	// prev_kv is the value the change replaced, when the subscription asked
+	// for it and the key had a value. It is read from the versions the
+	// datastore retains, so it is left empty if that version was already
+	// compacted away.
+	PrevKv *KeyValue `protobuf:"bytes,4,opt,name=prev_kv,json=prevKv,proto3" json:"prev_kv,omitempty"`
+	// coalesced is the number of earlier changes to the key within the
+	// coalesce window that were collapsed into the event. prev_kv holds the
+	// value from before the first of them.
+	Coalesced            uint32   `protobuf:"varint,5,opt,name=coalesced,proto3" json:"coalesced,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Event) Reset()         { *m = Event{} }
+func (m *Event) String() string { return proto.CompactTextString(m) }
+func (*Event) ProtoMessage()    {}
+func (*Event) Descriptor() ([]byte, []int) {
+	return fileDescriptor_261ca598fa2afdd5, []int{46}
+}
+
+func (m *Event) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Event.Unmarshal(m, b)
+}
+func (m *Event) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Event.Marshal(b, m, deterministic)
+}
+func (m *Event) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Event.Merge(m, src)
+}
+func (m *Event) XXX_Size() int {
+	return xxx_messageInfo_Event.Size(m)
+}
+func (m *Event) XXX_DiscardUnknown() {
+	xxx_messageInfo_Event.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Event proto.InternalMessageInfo
+
+func (m *Event) GetType() Event_Type {
+	if m != nil {
+		return m.Type
+	}
+	return Event_TYPE_INVALID
+}
+
+func (m *Event) GetKv() *KeyValue {
+	if m != nil {
+		return m.Kv
+	}
+	return nil
+}
+
+func (m *Event) GetMeta() uint32 {
+	if m != nil {
+		return m.Meta
+	}
+	return 0
+}
+
+func (m *Event) GetPrevKv() *KeyValue {
+	if m != nil {
+		return m.PrevKv
+	}
+	return nil
+}
+
+func (m *Event) GetCoalesced() uint32 {
+	if m != nil {
+		return m.Coalesced
+	}
+	return 0
+}
+
+func init() {
+	proto.RegisterEnum("kvetch.api.v1.SubscribeRequest_SlowConsumerPolicy", SubscribeRequest_SlowConsumerPolicy_name, SubscribeRequest_SlowConsumerPolicy_value)
+	proto.RegisterEnum("kvetch.api.v1.Event_Type", Event_Type_name, Event_Type_value)
+	proto.RegisterType((*ResponseHeader)(nil), "kvetch.api.v1.ResponseHeader")
+	proto.RegisterType((*SetValuesRequest)(nil), "kvetch.api.v1.SetValuesRequest")
+	proto.RegisterType((*SetValuesResponse)(nil), "kvetch.api.v1.SetValuesResponse")
+	proto.RegisterType((*Precondition)(nil), "kvetch.api.v1.Precondition")
+	proto.RegisterType((*GetValuesRequest)(nil), "kvetch.api.v1.GetValuesRequest")
+	proto.RegisterType((*GetValuesRequest_GetValue)(nil), "kvetch.api.v1.GetValuesRequest.GetValue")
+	proto.RegisterType((*KeyPattern)(nil), "kvetch.api.v1.KeyPattern")
+	proto.RegisterType((*GetValuesResponse)(nil), "kvetch.api.v1.GetValuesResponse")
+	proto.RegisterType((*ScanValuesRequest)(nil), "kvetch.api.v1.ScanValuesRequest")
+	proto.RegisterType((*ScanValuesResponse)(nil), "kvetch.api.v1.ScanValuesResponse")
+	proto.RegisterType((*CountKeysRequest)(nil), "kvetch.api.v1.CountKeysRequest")
+	proto.RegisterType((*CountKeysResponse)(nil), "kvetch.api.v1.CountKeysResponse")
+	proto.RegisterType((*StatRequest)(nil), "kvetch.api.v1.StatRequest")
+	proto.RegisterType((*StatResponse)(nil), "kvetch.api.v1.StatResponse")
+	proto.RegisterType((*KeyStat)(nil), "kvetch.api.v1.KeyStat")
+	proto.RegisterType((*GetHistoryRequest)(nil), "kvetch.api.v1.GetHistoryRequest")
+	proto.RegisterType((*GetHistoryResponse)(nil), "kvetch.api.v1.GetHistoryResponse")
+	proto.RegisterType((*KeyVersion)(nil), "kvetch.api.v1.KeyVersion")
+	proto.RegisterType((*DeleteValuesRequest)(nil), "kvetch.api.v1.DeleteValuesRequest")
+	proto.RegisterType((*DeleteValuesRequest_DeleteValue)(nil), "kvetch.api.v1.DeleteValuesRequest.DeleteValue")
+	proto.RegisterType((*DeleteValuesResponse)(nil), "kvetch.api.v1.DeleteValuesResponse")
+	proto.RegisterType((*IncrementRequest)(nil), "kvetch.api.v1.IncrementRequest")
+	proto.RegisterType((*IncrementResponse)(nil), "kvetch.api.v1.IncrementResponse")
+	proto.RegisterType((*TxnRequest)(nil), "kvetch.api.v1.TxnRequest")
+	proto.RegisterType((*TxnResponse)(nil), "kvetch.api.v1.TxnResponse")
+	proto.RegisterType((*TxnOperation)(nil), "kvetch.api.v1.TxnOperation")
+	proto.RegisterType((*TxnOperation_SetValue)(nil), "kvetch.api.v1.TxnOperation.SetValue")
+	proto.RegisterType((*TxnOperationResult)(nil), "kvetch.api.v1.TxnOperationResult")
+	proto.RegisterType((*GrantLeaseRequest)(nil), "kvetch.api.v1.GrantLeaseRequest")
+	proto.RegisterType((*GrantLeaseResponse)(nil), "kvetch.api.v1.GrantLeaseResponse")
+	proto.RegisterType((*KeepAliveRequest)(nil), "kvetch.api.v1.KeepAliveRequest")
+	proto.RegisterType((*KeepAliveResponse)(nil), "kvetch.api.v1.KeepAliveResponse")
+	proto.RegisterType((*RevokeLeaseRequest)(nil), "kvetch.api.v1.RevokeLeaseRequest")
+	proto.RegisterType((*RevokeLeaseResponse)(nil), "kvetch.api.v1.RevokeLeaseResponse")
+	proto.RegisterType((*LeaseTimeToLiveRequest)(nil), "kvetch.api.v1.LeaseTimeToLiveRequest")
+	proto.RegisterType((*LeaseTimeToLiveResponse)(nil), "kvetch.api.v1.LeaseTimeToLiveResponse")
+	proto.RegisterType((*LockRequest)(nil), "kvetch.api.v1.LockRequest")
+	proto.RegisterType((*LockResponse)(nil), "kvetch.api.v1.LockResponse")
+	proto.RegisterType((*UnlockRequest)(nil), "kvetch.api.v1.UnlockRequest")
+	proto.RegisterType((*UnlockResponse)(nil), "kvetch.api.v1.UnlockResponse")
+	proto.RegisterType((*LeaderKey)(nil), "kvetch.api.v1.LeaderKey")
+	proto.RegisterType((*CampaignRequest)(nil), "kvetch.api.v1.CampaignRequest")
+	proto.RegisterType((*CampaignResponse)(nil), "kvetch.api.v1.CampaignResponse")
+	proto.RegisterType((*ResignRequest)(nil), "kvetch.api.v1.ResignRequest")
+	proto.RegisterType((*ResignResponse)(nil), "kvetch.api.v1.ResignResponse")
+	proto.RegisterType((*ObserveRequest)(nil), "kvetch.api.v1.ObserveRequest")
+	proto.RegisterType((*ObserveResponse)(nil), "kvetch.api.v1.ObserveResponse")
+	proto.RegisterType((*SubscribeRequest)(nil), "kvetch.api.v1.SubscribeRequest")
+	proto.RegisterType((*SubscribeResponse)(nil), "kvetch.api.v1.SubscribeResponse")
+	proto.RegisterType((*Event)(nil), "kvetch.api.v1.Event")
+}
+
+func init() {
+	proto.RegisterFile("kvetch/api/v1/api.proto", fileDescriptor_261ca598fa2afdd5)
+}
+
+var fileDescriptor_261ca598fa2afdd5 = []byte{
+	// 2410 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xcc, 0x59, 0xcd, 0x6e, 0x1b, 0xc9,
+	0xf1, 0xf7, 0xf0, 0x43, 0x22, 0x8b, 0x94, 0x44, 0xb5, 0x85, 0x35, 0x77, 0x6c, 0xd9, 0xd2, 0xd8,
+	0xfb, 0xff, 0x0b, 0x0b, 0x2f, 0x6d, 0xcb, 0xf1, 0x6e, 0x82, 0x15, 0x10, 0x48, 0x14, 0x57, 0xe2,
+	0x8a, 0x11, 0xe9, 0x21, 0xfd, 0xb1, 0xb9, 0x4c, 0x46, 0x64, 0x8b, 0x1e, 0x68, 0x38, 0x33, 0x9e,
+	0x1e, 0xd2, 0xe2, 0x06, 0x08, 0x82, 0xdc, 0x02, 0x24, 0x87, 0xdc, 0x92, 0x4b, 0x82, 0xe4, 0x96,
+	0x1c, 0x02, 0xe4, 0x96, 0x67, 0xc8, 0x03, 0xe4, 0xb8, 0x79, 0x86, 0x9c, 0x82, 0x9c, 0x12, 0x74,
+	0x4f, 0xf7, 0x70, 0x38, 0xc3, 0x0f, 0xcb, 0xdc, 0x0d, 0xf6, 0x24, 0x76, 0xf5, 0xaf, 0xaa, 0xab,
+	0xab, 0xab, 0x6a, 0xaa, 0x4a, 0x70, 0xe3, 0x62, 0x80, 0xbd, 0xf6, 0xab, 0x07, 0xba, 0x63, 0x3c,
+	0x18, 0x3c, 0xa2, 0x7f, 0x4a, 0x8e, 0x6b, 0x7b, 0x36, 0x5a, 0xf1, 0x37, 0x4a, 0x94, 0x32, 0x78,
+	0x24, 0x6f, 0x8e, 0xe3, 0x2e, 0xf0, 0x50, 0x1b, 0xe8, 0x66, 0x1f, 0xfb, 0x68, 0xf9, 0x76, 0xd7,
+	0xb6, 0xbb, 0x26, 0x7e, 0xc0, 0x56, 0x67, 0xfd, 0xf3, 0x07, 0x9d, 0xbe, 0xab, 0x7b, 0x86, 0x6d,
+	0xf1, 0xfd, 0x3b, 0xd1, 0x7d, 0xcf, 0xe8, 0x61, 0xe2, 0xe9, 0x3d, 0x67, 0x9a, 0x80, 0x37, 0xae,
+	0xee, 0x38, 0xd8, 0x25, 0xfe, 0xbe, 0x72, 0x1f, 0x56, 0x55, 0x4c, 0x1c, 0xdb, 0x22, 0xf8, 0x18,
+	0xeb, 0x1d, 0xec, 0x22, 0x19, 0x32, 0x2e, 0x1e, 0x18, 0xc4, 0xb0, 0xad, 0xa2, 0xb4, 0x25, 0xed,
+	0xa4, 0xd4, 0x60, 0xad, 0x7c, 0x25, 0x41, 0xa1, 0x89, 0xbd, 0xe7, 0x54, 0x43, 0xa2, 0xe2, 0xd7,
+	0x7d, 0x4c, 0x3c, 0xf4, 0x18, 0x32, 0x3d, 0x4c, 0x88, 0xde, 0xc5, 0xa4, 0x28, 0x6d, 0x25, 0x77,
+	0x72, 0xbb, 0x37, 0x4a, 0x63, 0x97, 0x2c, 0x9d, 0xe0, 0x21, 0x63, 0x51, 0x03, 0x20, 0xda, 0x83,
+	0xbc, 0xe7, 0x99, 0x9a, 0xb8, 0x4e, 0x31, 0xb1, 0x25, 0xed, 0xe4, 0x76, 0xdf, 0x2f, 0xf9, 0xea,
+	0x96, 0x84, 0xba, 0xa5, 0x43, 0x0e, 0x50, 0x73, 0x9e, 0x67, 0x8a, 0x05, 0xda, 0x87, 0x15, 0xc7,
+	0xc5, 0x6d, 0xdb, 0xea, 0x18, 0x74, 0x4d, 0x8a, 0x49, 0x76, 0xee, 0xcd, 0xc8, 0xb9, 0x8d, 0x10,
+	0x46, 0x1d, 0xe7, 0x40, 0x1b, 0x90, 0x36, 0xb1, 0x4e, 0x70, 0x31, 0xb5, 0x25, 0xed, 0x24, 0x55,
+	0x7f, 0xa1, 0xfc, 0x55, 0x82, 0xf5, 0xd0, 0x05, 0x7d, 0xc3, 0xa0, 0x27, 0xb0, 0xf4, 0x8a, 0x19,
+	0x87, 0x19, 0x24, 0xb7, 0xbb, 0x19, 0x39, 0x67, 0xdc, 0x82, 0x2a, 0x07, 0xa3, 0x5b, 0x90, 0x25,
+	0xfd, 0x76, 0x1b, 0xe3, 0x0e, 0xee, 0xb0, 0x0b, 0x66, 0xd4, 0x11, 0x01, 0x9d, 0xc2, 0xc6, 0xb9,
+	0x6e, 0x98, 0xb8, 0xa3, 0x5d, 0xf9, 0x2a, 0xd7, 0x7d, 0xc6, 0x30, 0x8d, 0x28, 0xbf, 0x95, 0x20,
+	0x1f, 0xa6, 0xa0, 0x02, 0x24, 0x2f, 0xf0, 0x90, 0xa9, 0x9c, 0x55, 0xe9, 0x4f, 0x54, 0x84, 0x25,
+	0x7c, 0x69, 0x10, 0x8f, 0xf8, 0xda, 0x1c, 0x5f, 0x53, 0xf9, 0x1a, 0xdd, 0x85, 0x3c, 0x73, 0x3b,
+	0x0d, 0xbf, 0xee, 0xeb, 0x26, 0x55, 0x42, 0xda, 0xc9, 0x1f, 0x5f, 0x53, 0x73, 0x8c, 0x5a, 0x61,
+	0x44, 0xf4, 0x10, 0xae, 0xf7, 0xec, 0x8e, 0x26, 0xbc, 0x41, 0x60, 0xa9, 0x01, 0x53, 0xc7, 0xd7,
+	0xd4, 0xf5, 0x9e, 0xdd, 0x51, 0xf9, 0x9e, 0xcf, 0x71, 0x90, 0x83, 0x6c, 0xa0, 0x8f, 0xf2, 0xfb,
+	0x24, 0x14, 0x8e, 0xa2, 0xce, 0x73, 0x48, 0xbd, 0x8d, 0xfd, 0x14, 0xce, 0xb3, 0x13, 0xb9, 0x79,
+	0x94, 0x25, 0x20, 0xa8, 0x01, 0xe7, 0x98, 0xcf, 0x26, 0xc6, 0x7d, 0x16, 0x3d, 0x80, 0xb4, 0x4e,
+	0x34, 0xfb, 0x9c, 0xdd, 0x29, 0xb7, 0x2b, 0xc7, 0x5c, 0xac, 0x25, 0x42, 0x46, 0x4d, 0xe9, 0xa4,
+	0x7e, 0x2e, 0xff, 0x5b, 0x82, 0x8c, 0x38, 0x63, 0x82, 0x11, 0x6f, 0x42, 0xd6, 0x20, 0xf4, 0xcd,
+	0xce, 0x8d, 0x4b, 0xfe, 0xaa, 0x19, 0x83, 0x34, 0xd8, 0x9a, 0x79, 0x95, 0xd1, 0x33, 0xbc, 0x62,
+	0x92, 0x7b, 0x15, 0x5d, 0xa0, 0x3b, 0x90, 0x23, 0x9e, 0xee, 0x7a, 0x9a, 0x7e, 0xee, 0x61, 0x97,
+	0x19, 0x2c, 0xab, 0x02, 0x23, 0xed, 0x53, 0x0a, 0x95, 0x79, 0x81, 0x87, 0x44, 0xb3, 0x2d, 0x73,
+	0x58, 0x4c, 0xfb, 0x32, 0x29, 0xa1, 0x6e, 0x99, 0xec, 0x40, 0x57, 0xb7, 0xba, 0x58, 0xc3, 0x56,
+	0xa7, 0xb8, 0xc4, 0x78, 0x33, 0x8c, 0x50, 0xb1, 0x3a, 0xa8, 0x08, 0xcb, 0x2e, 0x1e, 0x60, 0x97,
+	0xe0, 0xe2, 0x32, 0xe3, 0x13, 0x4b, 0xf4, 0x18, 0x96, 0x1d, 0xdd, 0xf3, 0xb0, 0x6b, 0x15, 0x33,
+	0x3c, 0xb8, 0x62, 0x51, 0xd9, 0xf0, 0x01, 0xaa, 0x40, 0x2a, 0x1f, 0x03, 0x8c, 0xc8, 0x08, 0x41,
+	0xaa, 0x6b, 0xda, 0x67, 0xfc, 0xf6, 0xec, 0x37, 0xbd, 0xa1, 0x8b, 0xbb, 0xd8, 0xbf, 0x7a, 0x56,
+	0xf5, 0x17, 0xca, 0x1f, 0x25, 0x58, 0x3f, 0x8a, 0xc5, 0xcd, 0x3b, 0x65, 0x86, 0x51, 0xb0, 0x25,
+	0xae, 0x12, 0x6c, 0x3b, 0x50, 0xb0, 0xf0, 0xa5, 0xa7, 0x85, 0x0d, 0x4d, 0x43, 0x29, 0xab, 0xae,
+	0x52, 0x7a, 0x33, 0x30, 0xb6, 0xe2, 0xc1, 0x7a, 0xb3, 0xad, 0x5b, 0xe3, 0x7e, 0x78, 0x40, 0xed,
+	0xc8, 0x7e, 0xf2, 0x18, 0x7f, 0x7b, 0x37, 0x14, 0x8c, 0xf4, 0xa1, 0x1c, 0xbd, 0x8b, 0x35, 0x62,
+	0x7c, 0x89, 0x99, 0xf2, 0x49, 0x35, 0x43, 0x09, 0x4d, 0xe3, 0x4b, 0xac, 0xfc, 0x54, 0x02, 0x14,
+	0x3e, 0xf6, 0x7f, 0x6f, 0x22, 0xe5, 0x25, 0x14, 0xca, 0x76, 0xdf, 0xf2, 0x4e, 0xf0, 0xf0, 0xeb,
+	0x8d, 0x3f, 0xe5, 0x17, 0x12, 0xac, 0x87, 0x44, 0x2f, 0x96, 0x36, 0x37, 0x20, 0xdd, 0xa6, 0xb2,
+	0xb8, 0x09, 0xfd, 0x05, 0xfa, 0x10, 0xd6, 0x3d, 0xdb, 0xd3, 0x4d, 0xff, 0xf3, 0xa8, 0x9d, 0x0d,
+	0x3d, 0x4c, 0x78, 0x94, 0xad, 0xb1, 0x0d, 0xa6, 0xd3, 0x01, 0x25, 0x2b, 0x4d, 0xc8, 0x35, 0x3d,
+	0xdd, 0xfb, 0x7a, 0xef, 0xf8, 0x0f, 0x09, 0xf2, 0xbe, 0xd4, 0xc5, 0xae, 0x77, 0x1f, 0xd2, 0xc4,
+	0xd3, 0x59, 0x0e, 0xa6, 0xaa, 0xbc, 0x17, 0x7f, 0x6e, 0x76, 0x8a, 0x0f, 0x1a, 0x19, 0x23, 0x39,
+	0xd7, 0x18, 0xa9, 0x89, 0xc6, 0x98, 0x18, 0x18, 0xe9, 0x89, 0x81, 0xf1, 0x67, 0x09, 0x96, 0xf9,
+	0xf1, 0x13, 0xf2, 0xde, 0x36, 0xe4, 0xc3, 0xd9, 0x9f, 0xe7, 0xd9, 0x5c, 0x28, 0xe9, 0xa3, 0x4d,
+	0x00, 0x5f, 0x21, 0x16, 0x01, 0xbe, 0xc6, 0x59, 0x46, 0xa1, 0x21, 0x80, 0xbe, 0x07, 0x80, 0x2f,
+	0x1d, 0xc3, 0xc5, 0x44, 0xd3, 0xbd, 0x62, 0x6a, 0x6e, 0x3a, 0xce, 0x72, 0xf4, 0xbe, 0x37, 0xfa,
+	0x5a, 0xa7, 0xc3, 0x5f, 0xeb, 0x4f, 0x59, 0xd2, 0x39, 0x36, 0x88, 0x67, 0xbb, 0x43, 0xf1, 0xda,
+	0x71, 0xcd, 0x83, 0xa4, 0x9c, 0x08, 0x25, 0x65, 0xe5, 0x67, 0x12, 0xa0, 0x30, 0xf7, 0x62, 0xaf,
+	0xfa, 0x04, 0x32, 0x34, 0xed, 0xb2, 0x2f, 0xb8, 0xff, 0xb0, 0x13, 0xd2, 0xed, 0x73, 0x1f, 0xa1,
+	0x06, 0x50, 0xc5, 0x04, 0x18, 0xd1, 0xd1, 0xff, 0x43, 0xe2, 0x62, 0xc0, 0xcf, 0x9d, 0x9a, 0x06,
+	0x12, 0x17, 0x03, 0x7a, 0x1a, 0x7d, 0x0b, 0x5a, 0xec, 0x15, 0x13, 0x73, 0xed, 0xb8, 0xdc, 0xb3,
+	0x3b, 0x74, 0xa5, 0xfc, 0x4e, 0x82, 0xeb, 0x87, 0xd8, 0xc4, 0x1e, 0x1e, 0x4f, 0x7e, 0x9f, 0xc7,
+	0x02, 0xa4, 0x14, 0x39, 0x7d, 0x02, 0x57, 0x98, 0x36, 0x0a, 0x13, 0x79, 0x0f, 0x72, 0xa1, 0x8d,
+	0x2b, 0x7e, 0x3f, 0x15, 0x17, 0x36, 0xc6, 0x8f, 0xe2, 0xaf, 0x72, 0x17, 0x56, 0x3a, 0x8c, 0xde,
+	0xd1, 0xfc, 0x70, 0x90, 0xd8, 0x53, 0xe6, 0x39, 0x91, 0xe5, 0x9e, 0x77, 0x4d, 0x8b, 0x7f, 0x97,
+	0xa0, 0x50, 0xb5, 0xda, 0x2e, 0xee, 0x61, 0xcb, 0x9b, 0xe9, 0x45, 0x1d, 0x6c, 0x7a, 0xba, 0xf0,
+	0x22, 0xb6, 0x88, 0xd5, 0xb1, 0xc9, 0x2b, 0xd5, 0xb1, 0x1f, 0x41, 0xb2, 0x67, 0x58, 0x3c, 0x14,
+	0x6e, 0xc6, 0x98, 0xaa, 0x96, 0xf7, 0xf1, 0x77, 0x7c, 0x03, 0x53, 0x1c, 0x83, 0xeb, 0x97, 0xc5,
+	0xf4, 0xdb, 0xc0, 0xf5, 0x4b, 0xe5, 0x27, 0xb0, 0x1e, 0xba, 0xd7, 0x37, 0x59, 0xcb, 0x6e, 0x40,
+	0x9a, 0x85, 0xb9, 0xc8, 0x52, 0x6c, 0xa1, 0xfc, 0x45, 0x02, 0x68, 0x5d, 0x5a, 0xc2, 0xa4, 0x4f,
+	0x60, 0xb9, 0x6d, 0xf7, 0x1c, 0xdd, 0xc5, 0x45, 0x69, 0x7e, 0x8d, 0x2b, 0xb0, 0x94, 0x8d, 0x1d,
+	0x44, 0x44, 0x60, 0x45, 0xd9, 0x5a, 0x97, 0x56, 0xdd, 0xc1, 0xdc, 0xbc, 0x02, 0x4b, 0xd9, 0x68,
+	0x95, 0xdc, 0x77, 0x71, 0x31, 0xf9, 0x16, 0x6c, 0x1c, 0x4b, 0x43, 0x24, 0xc7, 0x74, 0xfe, 0x26,
+	0xcd, 0xf5, 0x29, 0x2d, 0x36, 0x48, 0xdf, 0xf4, 0x44, 0xb5, 0xbf, 0x3d, 0x4b, 0x37, 0x86, 0x54,
+	0x05, 0x87, 0xf2, 0xcf, 0x04, 0xe4, 0xc3, 0xfb, 0x68, 0x0f, 0x92, 0x5d, 0x7c, 0xe5, 0xb2, 0xe5,
+	0xf8, 0x9a, 0x4a, 0xd9, 0xd0, 0x77, 0x21, 0x49, 0xb0, 0xc7, 0x23, 0xe6, 0xde, 0x0c, 0x3d, 0x4a,
+	0xcd, 0x10, 0x27, 0xc1, 0x1e, 0x3a, 0x86, 0x25, 0x3f, 0xfc, 0xb8, 0xd3, 0x5f, 0x31, 0x67, 0xd0,
+	0xee, 0xc3, 0xe7, 0x97, 0x7f, 0x25, 0x41, 0x46, 0x48, 0x47, 0x8f, 0x60, 0x99, 0x17, 0x3a, 0xf3,
+	0x32, 0xa1, 0xc0, 0x2d, 0xd8, 0x4c, 0x06, 0xdf, 0x96, 0x64, 0xe8, 0xdb, 0x42, 0x5b, 0x17, 0x5b,
+	0x5c, 0x5d, 0xb1, 0x00, 0xc5, 0x9f, 0xe4, 0xdd, 0x6a, 0xb7, 0x58, 0x26, 0x4b, 0xc4, 0x33, 0x99,
+	0xf2, 0x14, 0xd6, 0x8f, 0x5c, 0xdd, 0xf2, 0x6a, 0x54, 0x15, 0x11, 0x3f, 0xd1, 0x5b, 0x4a, 0x57,
+	0xb9, 0xa5, 0xf2, 0x1b, 0xfa, 0xb9, 0x0b, 0xc9, 0x5c, 0xcc, 0xbf, 0x57, 0x21, 0x61, 0x74, 0xb8,
+	0xea, 0x09, 0xa3, 0xb3, 0x58, 0x1a, 0x54, 0x14, 0x28, 0x9c, 0x60, 0xec, 0xec, 0x9b, 0xc6, 0x20,
+	0xb8, 0xad, 0x7f, 0x82, 0x24, 0x4e, 0x50, 0x7e, 0x2d, 0xc1, 0x7a, 0x08, 0xf4, 0x6d, 0x52, 0xff,
+	0x1e, 0x20, 0x15, 0x0f, 0xec, 0x0b, 0x3c, 0xf6, 0x5c, 0xd1, 0x0b, 0xd4, 0xe0, 0xfa, 0x18, 0x6a,
+	0xa1, 0x1b, 0x28, 0x7b, 0xf0, 0x1e, 0x93, 0x43, 0xbf, 0xeb, 0x2d, 0xbb, 0x36, 0xdd, 0x70, 0xb4,
+	0x89, 0xa3, 0xad, 0x24, 0xcf, 0x42, 0xec, 0xb7, 0xf2, 0x1f, 0x09, 0x6e, 0xc4, 0xd8, 0xbf, 0x45,
+	0x26, 0x45, 0x27, 0xb0, 0xd1, 0xa5, 0xce, 0x8a, 0x3b, 0xda, 0x98, 0x94, 0xd4, 0x3c, 0x29, 0x88,
+	0xb3, 0xb5, 0x42, 0xc2, 0x84, 0x05, 0xfc, 0xaa, 0xd7, 0xb7, 0xc0, 0x27, 0x90, 0xab, 0xd9, 0xed,
+	0x0b, 0x61, 0x34, 0x04, 0x29, 0x4b, 0xef, 0x61, 0xd1, 0xe9, 0xd2, 0xdf, 0xa3, 0xbc, 0x90, 0x08,
+	0xd7, 0x9c, 0x2f, 0x20, 0xef, 0x33, 0x2e, 0x66, 0x2e, 0x5e, 0x5f, 0x24, 0x82, 0xfa, 0x42, 0xf9,
+	0x04, 0x56, 0x9e, 0x59, 0x66, 0x48, 0xa7, 0xc9, 0x85, 0x6c, 0x5c, 0xa3, 0x23, 0x58, 0x15, 0x8c,
+	0x8b, 0xf9, 0x54, 0x1b, 0xb2, 0x35, 0xf6, 0xeb, 0x04, 0x0f, 0x27, 0x5a, 0x24, 0xa6, 0xf4, 0xd8,
+	0xe0, 0x25, 0x19, 0x19, 0xbc, 0x4c, 0x9e, 0xb0, 0x3d, 0x85, 0xb5, 0xb2, 0xde, 0x73, 0x74, 0xa3,
+	0x6b, 0x5d, 0xd9, 0xf8, 0xe3, 0x75, 0x46, 0x5e, 0xd4, 0x19, 0x3f, 0x86, 0xc2, 0x48, 0xe4, 0x62,
+	0xcf, 0xf2, 0x10, 0x96, 0xcc, 0x70, 0x09, 0x59, 0x8c, 0xb0, 0x05, 0xf6, 0x51, 0x39, 0x4e, 0xd9,
+	0x87, 0x15, 0x15, 0x93, 0xd0, 0x6d, 0x46, 0x22, 0xa4, 0xb7, 0x14, 0x71, 0x04, 0xab, 0x42, 0xc4,
+	0x62, 0x0f, 0x78, 0x0f, 0x56, 0xeb, 0x67, 0x04, 0xbb, 0x03, 0x3c, 0xc3, 0xb4, 0xca, 0x6b, 0x58,
+	0x0b, 0x50, 0x8b, 0x59, 0xcb, 0xef, 0x57, 0x12, 0x73, 0xfb, 0x15, 0xe5, 0xe7, 0x69, 0x28, 0x34,
+	0xfb, 0x67, 0xa4, 0xed, 0x1a, 0x67, 0x81, 0x6e, 0x32, 0x64, 0xfc, 0x2e, 0x80, 0x7f, 0x3e, 0xb3,
+	0x6a, 0xb0, 0x46, 0x1f, 0xc0, 0xaa, 0xdf, 0xaf, 0x46, 0xda, 0xcd, 0x15, 0x46, 0x0d, 0x1a, 0xce,
+	0x1b, 0xb0, 0xec, 0xb8, 0x78, 0xa0, 0x5d, 0x0c, 0x98, 0x47, 0x64, 0xd4, 0x25, 0xba, 0x3c, 0x19,
+	0xd0, 0xaf, 0x2c, 0xb9, 0x30, 0x1c, 0x8d, 0x58, 0xba, 0x43, 0x5e, 0xd9, 0x7e, 0xb7, 0x99, 0x51,
+	0xf3, 0x94, 0xd8, 0xe4, 0x34, 0xf4, 0x19, 0xac, 0x3b, 0xae, 0xdd, 0x75, 0x31, 0x21, 0x9a, 0x61,
+	0x79, 0xd8, 0x1d, 0xe8, 0x66, 0x31, 0x3d, 0x2f, 0xc3, 0x14, 0x04, 0x4f, 0x95, 0xb3, 0xd0, 0x6e,
+	0x8c, 0xcf, 0xcf, 0x48, 0x71, 0x69, 0x5a, 0xef, 0x27, 0x46, 0x6d, 0x01, 0x14, 0x1d, 0xc0, 0x5a,
+	0xdb, 0xd6, 0x4d, 0x4c, 0xda, 0x58, 0x7b, 0x63, 0x58, 0x1d, 0xfb, 0x0d, 0x1b, 0xe1, 0xcd, 0x3c,
+	0x7c, 0x55, 0x70, 0xbc, 0x60, 0x0c, 0xa8, 0x03, 0x1b, 0xc4, 0xb4, 0xdf, 0x68, 0x6d, 0xdb, 0x22,
+	0xfd, 0x1e, 0x76, 0x35, 0xc7, 0x36, 0x8d, 0xf6, 0x90, 0x4d, 0xfc, 0x56, 0x77, 0x77, 0x23, 0x6a,
+	0x44, 0x9f, 0xa0, 0xd4, 0x34, 0xed, 0x37, 0x65, 0xce, 0xda, 0x60, 0x9c, 0x2a, 0x22, 0x31, 0x9a,
+	0xf2, 0x37, 0x3a, 0xbb, 0x8a, 0x91, 0xd1, 0x3d, 0xd8, 0x6a, 0xd6, 0xea, 0x2f, 0xb4, 0x72, 0xfd,
+	0xb4, 0xf9, 0xec, 0x07, 0x15, 0x55, 0x6b, 0xd4, 0x6b, 0xd5, 0xf2, 0x17, 0xda, 0xb3, 0xd3, 0x66,
+	0xa3, 0x52, 0xae, 0x7e, 0x56, 0xad, 0x1c, 0x16, 0xae, 0x4d, 0x45, 0x1d, 0xaa, 0xf5, 0x86, 0x56,
+	0xaf, 0x1d, 0x56, 0x9a, 0xad, 0x82, 0x84, 0xb6, 0x61, 0x73, 0x22, 0xaa, 0x5c, 0xdf, 0xaf, 0x55,
+	0x9a, 0xe5, 0x4a, 0x21, 0x81, 0xee, 0xc2, 0x9d, 0xc9, 0x82, 0xaa, 0xcd, 0x72, 0xfd, 0xf4, 0xb4,
+	0x52, 0x6e, 0x15, 0x92, 0x4a, 0x2a, 0x93, 0x2a, 0xa4, 0x3e, 0x94, 0x27, 0x02, 0x0f, 0x6a, 0xf5,
+	0xf2, 0x89, 0xf2, 0x2f, 0x3a, 0xe2, 0x1f, 0x19, 0x62, 0x91, 0x39, 0xdc, 0x7d, 0x58, 0xc2, 0x03,
+	0x6c, 0x05, 0xb3, 0x9c, 0x8d, 0x08, 0x4b, 0x85, 0x6e, 0xaa, 0x1c, 0x13, 0x0a, 0xb2, 0xe4, 0x55,
+	0x82, 0x0c, 0x41, 0x8a, 0x0c, 0xad, 0x36, 0xf7, 0x60, 0xf6, 0xdb, 0x0f, 0x1d, 0xdf, 0x0b, 0xc5,
+	0xb8, 0x58, 0xac, 0xe9, 0x44, 0xb8, 0xe3, 0xda, 0x8e, 0x83, 0xfd, 0x61, 0x71, 0x4a, 0x15, 0x4b,
+	0xe5, 0x97, 0x09, 0x48, 0x33, 0x95, 0xd0, 0x47, 0x90, 0xf2, 0x86, 0x8e, 0x9f, 0x16, 0x56, 0x63,
+	0xde, 0xca, 0x30, 0xa5, 0xd6, 0xd0, 0xc1, 0x2a, 0x83, 0xbd, 0x75, 0x9c, 0x53, 0x5d, 0x7b, 0xd8,
+	0xd3, 0xd9, 0x05, 0x57, 0x54, 0xf6, 0x1b, 0x3d, 0x1c, 0xc5, 0x68, 0x6a, 0xb6, 0x04, 0x11, 0xbc,
+	0xb7, 0x20, 0x2b, 0xdc, 0xbc, 0xc3, 0xae, 0xb7, 0xa2, 0x8e, 0x08, 0xca, 0x31, 0xa4, 0xa8, 0x6a,
+	0xa8, 0x00, 0xf9, 0xd6, 0x17, 0x8d, 0x8a, 0x56, 0x3d, 0x7d, 0xbe, 0x5f, 0xab, 0x52, 0x4f, 0xcb,
+	0x43, 0x86, 0x51, 0x1a, 0xcf, 0xa8, 0x47, 0xad, 0x41, 0x8e, 0xad, 0x0e, 0x2b, 0xb5, 0x4a, 0x8b,
+	0xfa, 0x8f, 0x20, 0x54, 0x5e, 0x36, 0xaa, 0x6a, 0xa5, 0x90, 0xdc, 0xfd, 0x2a, 0x07, 0xc9, 0xfd,
+	0x46, 0x15, 0x9d, 0x42, 0x36, 0xf8, 0x9f, 0x0f, 0xba, 0x13, 0x8d, 0x99, 0x48, 0x03, 0x25, 0x6f,
+	0x4d, 0x07, 0x70, 0x5f, 0x3a, 0x85, 0xec, 0xd1, 0x54, 0x79, 0x47, 0xf3, 0xe4, 0xc5, 0xc7, 0xe8,
+	0x4d, 0x80, 0xd1, 0xe4, 0x18, 0xc5, 0xce, 0x8f, 0xce, 0xb2, 0xe5, 0xed, 0x19, 0x08, 0x5f, 0xe4,
+	0x43, 0x89, 0x2a, 0x19, 0x4c, 0x6c, 0x63, 0x4a, 0x46, 0xc7, 0xc4, 0xf2, 0xd6, 0x74, 0x00, 0x57,
+	0xf2, 0xfb, 0x90, 0x62, 0x83, 0x43, 0x39, 0x7a, 0xf8, 0x68, 0x10, 0x2b, 0xdf, 0x9c, 0xb8, 0xc7,
+	0x05, 0x3c, 0x05, 0x18, 0x8d, 0xe3, 0xd0, 0x04, 0xab, 0x8c, 0xcf, 0xf9, 0xe4, 0xed, 0x19, 0x08,
+	0x2e, 0xb2, 0x01, 0xd9, 0x20, 0xd2, 0xe3, 0x0f, 0x1b, 0x49, 0x86, 0xf2, 0xd6, 0x74, 0x40, 0x60,
+	0xb5, 0x17, 0x90, 0x0f, 0xb7, 0xb5, 0x48, 0x99, 0xdf, 0xf3, 0xca, 0x77, 0x67, 0x62, 0x46, 0x3e,
+	0x13, 0xcc, 0x6a, 0x62, 0xaa, 0x46, 0xa7, 0x53, 0xf2, 0xd6, 0x74, 0x00, 0x97, 0xb7, 0x07, 0xc9,
+	0xd6, 0xa5, 0x85, 0xde, 0x8f, 0x37, 0xf4, 0x42, 0x86, 0x3c, 0x69, 0x2b, 0xf4, 0x16, 0x41, 0xaf,
+	0x18, 0x7f, 0x8b, 0x68, 0x6b, 0x2a, 0x6f, 0xcf, 0x40, 0x70, 0x91, 0x2a, 0x64, 0x83, 0xf6, 0x2d,
+	0x76, 0xc1, 0x68, 0xf7, 0x27, 0x6f, 0x4d, 0x07, 0xf8, 0xf2, 0x76, 0xa4, 0x87, 0x12, 0x6a, 0x41,
+	0x2e, 0xd4, 0x52, 0xa1, 0xed, 0x58, 0x42, 0x8d, 0x36, 0x65, 0xb2, 0x32, 0x0b, 0xc2, 0x35, 0xfd,
+	0x11, 0xac, 0x45, 0x7a, 0x23, 0xf4, 0x41, 0xbc, 0x86, 0x9b, 0xd0, 0x7a, 0xc9, 0xff, 0x37, 0x0f,
+	0x36, 0x8a, 0x15, 0xda, 0x43, 0xc4, 0x62, 0x25, 0xd4, 0x91, 0xc8, 0x37, 0x27, 0xee, 0x71, 0x01,
+	0x15, 0x58, 0xf2, 0x4b, 0x7e, 0x74, 0x2b, 0x02, 0x1b, 0x6b, 0x21, 0xe4, 0xcd, 0x29, 0xbb, 0x5c,
+	0xcc, 0x09, 0x64, 0x44, 0xe1, 0x8c, 0x6e, 0x47, 0x23, 0x7c, 0xbc, 0x48, 0x97, 0xef, 0x4c, 0xdd,
+	0x1f, 0xe9, 0xe4, 0x57, 0xb1, 0x31, 0x9d, 0xc6, 0xea, 0x63, 0x79, 0x73, 0xca, 0x2e, 0x17, 0xf3,
+	0x39, 0x2c, 0xf3, 0xea, 0x14, 0x45, 0x91, 0xe3, 0xb5, 0xad, 0x7c, 0x7b, 0xda, 0xb6, 0x88, 0xd6,
+	0x83, 0x3d, 0x58, 0x6f, 0xdb, 0xbd, 0x71, 0xd8, 0x41, 0x66, 0xdf, 0x31, 0x1a, 0xb4, 0xb0, 0x6a,
+	0x48, 0x3f, 0x4c, 0xeb, 0x8e, 0x31, 0x78, 0xf4, 0x87, 0x44, 0xf2, 0x64, 0xff, 0xe5, 0x9f, 0x12,
+	0x2b, 0x27, 0x3e, 0x70, 0xdf, 0x31, 0x4a, 0xcf, 0x1f, 0x9d, 0x2d, 0xb1, 0xf2, 0xeb, 0xf1, 0x7f,
+	0x07, 0x00, 0x15, 0xf1, 0xd8, 0x98, 0xcb, 0x21, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// APIClient is the client API for API service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type APIClient interface {
+	// SetValues takes a list of key values and stores them in the datastore and
+	// will notify subscribers of changes.
+	SetValues(ctx context.Context, in *SetValuesRequest, opts ...grpc.CallOption) (*SetValuesResponse, error)
+	// GetValues retrieves a list of key values.
+	GetValues(ctx context.Context, in *GetValuesRequest, opts ...grpc.CallOption) (*GetValuesResponse, error)
+	// ScanValues retrieves the key values for a request in pages, so large
+	// prefixes do not have to fit in a single message.
+	ScanValues(ctx context.Context, in *ScanValuesRequest, opts ...grpc.CallOption) (API_ScanValuesClient, error)
+	// CountKeys counts the keys for a list of requests and the size of their
+	// values.
+	CountKeys(ctx context.Context, in *CountKeysRequest, opts ...grpc.CallOption) (*CountKeysResponse, error)
+	// Stat retrieves the metadata of the keys for a list of requests without
+	// returning their values.
+	Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error)
+	// GetHistory retrieves the versions of a key the datastore has retained,
+	// newest first.
+	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
+	// Subscribe will subscribe to a key or prefix and return the current value
+	// and any changes.
+	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (API_SubscribeClient, error)
+	// DeleteValues removes keys or prefixes from the datastore and will notify
+	// subscribers of changes.
+	DeleteValues(ctx context.Context, in *DeleteValuesRequest, opts ...grpc.CallOption) (*DeleteValuesResponse, error)
+	// Increment atomically adds a delta to the integer value of a key, creating
+	// the key when it is missing.
+	Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error)
+	// Txn evaluates a list of comparisons and runs the success operations if
+	// they all hold or the failure operations otherwise, in a single
+	// transaction.
+	Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error)
+	// GrantLease creates a lease that expires unless it is kept alive. Keys
+	// written with the lease are deleted when it expires or is revoked.
+	GrantLease(ctx context.Context, in *GrantLeaseRequest, opts ...grpc.CallOption) (*GrantLeaseResponse, error)
+	// KeepAlive refreshes leases for as long as the stream is open. The stream
+	// ends with NOT_FOUND once a lease does not exist or has expired.
+	KeepAlive(ctx context.Context, opts ...grpc.CallOption) (API_KeepAliveClient, error)
+	// RevokeLease revokes a lease and deletes every key written with it.
+	RevokeLease(ctx context.Context, in *RevokeLeaseRequest, opts ...grpc.CallOption) (*RevokeLeaseResponse, error)
+	// LeaseTimeToLive retrieves the remaining time to live of a lease.
+	LeaseTimeToLive(ctx context.Context, in *LeaseTimeToLiveRequest, opts ...grpc.CallOption) (*LeaseTimeToLiveResponse, error)
+	// Lock waits until the lock is held by the lease. Waiters acquire the lock
+	// in the order they asked for it, and the lock is released when the lease
+	// expires or is revoked.
+	Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*LockResponse, error)
+	// Unlock releases a lock by deleting the key returned from Lock. The key
+	// has to be held by the lease that locked it.
+	Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*UnlockResponse, error)
+	// Campaign waits until the lease is elected leader. Candidates are elected
+	// in the order they campaigned, and leadership is lost when the lease
+	// expires or is revoked.
+	Campaign(ctx context.Context, in *CampaignRequest, opts ...grpc.CallOption) (*CampaignResponse, error)
+	// Resign gives up leadership so the next candidate is elected.
+	Resign(ctx context.Context, in *ResignRequest, opts ...grpc.CallOption) (*ResignResponse, error)
+	// Observe streams the leader of an election every time it changes.
+	Observe(ctx context.Context, in *ObserveRequest, opts ...grpc.CallOption) (API_ObserveClient, error)
+}
+
+type aPIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewAPIClient(cc grpc.ClientConnInterface) APIClient {
+	return &aPIClient{cc}
+}
+
+func (c *aPIClient) SetValues(ctx context.Context, in *SetValuesRequest, opts ...grpc.CallOption) (*SetValuesResponse, error) {
+	out := new(SetValuesResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/SetValues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) GetValues(ctx context.Context, in *GetValuesRequest, opts ...grpc.CallOption) (*GetValuesResponse, error) {
+	out := new(GetValuesResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/GetValues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) ScanValues(ctx context.Context, in *ScanValuesRequest, opts ...grpc.CallOption) (API_ScanValuesClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_API_serviceDesc.Streams[0], "/kvetch.api.v1.API/ScanValues", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIScanValuesClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_ScanValuesClient interface {
+	Recv() (*ScanValuesResponse, error)
+	grpc.ClientStream
+}
+
+type aPIScanValuesClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIScanValuesClient) Recv() (*ScanValuesResponse, error) {
+	m := new(ScanValuesResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) CountKeys(ctx context.Context, in *CountKeysRequest, opts ...grpc.CallOption) (*CountKeysResponse, error) {
+	out := new(CountKeysResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/CountKeys", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Stat(ctx context.Context, in *StatRequest, opts ...grpc.CallOption) (*StatResponse, error) {
+	out := new(StatResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Stat", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error) {
+	out := new(GetHistoryResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/GetHistory", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (API_SubscribeClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_API_serviceDesc.Streams[1], "/kvetch.api.v1.API/Subscribe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPISubscribeClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_SubscribeClient interface {
+	Recv() (*SubscribeResponse, error)
+	grpc.ClientStream
+}
+
+type aPISubscribeClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPISubscribeClient) Recv() (*SubscribeResponse, error) {
+	m := new(SubscribeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) DeleteValues(ctx context.Context, in *DeleteValuesRequest, opts ...grpc.CallOption) (*DeleteValuesResponse, error) {
+	out := new(DeleteValuesResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/DeleteValues", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Increment(ctx context.Context, in *IncrementRequest, opts ...grpc.CallOption) (*IncrementResponse, error) {
+	out := new(IncrementResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Increment", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Txn(ctx context.Context, in *TxnRequest, opts ...grpc.CallOption) (*TxnResponse, error) {
+	out := new(TxnResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Txn", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) GrantLease(ctx context.Context, in *GrantLeaseRequest, opts ...grpc.CallOption) (*GrantLeaseResponse, error) {
+	out := new(GrantLeaseResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/GrantLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) KeepAlive(ctx context.Context, opts ...grpc.CallOption) (API_KeepAliveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_API_serviceDesc.Streams[2], "/kvetch.api.v1.API/KeepAlive", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIKeepAliveClient{stream}
+	return x, nil
+}
+
+type API_KeepAliveClient interface {
+	Send(*KeepAliveRequest) error
+	Recv() (*KeepAliveResponse, error)
+	grpc.ClientStream
+}
+
+type aPIKeepAliveClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIKeepAliveClient) Send(m *KeepAliveRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *aPIKeepAliveClient) Recv() (*KeepAliveResponse, error) {
+	m := new(KeepAliveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *aPIClient) RevokeLease(ctx context.Context, in *RevokeLeaseRequest, opts ...grpc.CallOption) (*RevokeLeaseResponse, error) {
+	out := new(RevokeLeaseResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/RevokeLease", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) LeaseTimeToLive(ctx context.Context, in *LeaseTimeToLiveRequest, opts ...grpc.CallOption) (*LeaseTimeToLiveResponse, error) {
+	out := new(LeaseTimeToLiveResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/LeaseTimeToLive", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Lock(ctx context.Context, in *LockRequest, opts ...grpc.CallOption) (*LockResponse, error) {
+	out := new(LockResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Lock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Unlock(ctx context.Context, in *UnlockRequest, opts ...grpc.CallOption) (*UnlockResponse, error) {
+	out := new(UnlockResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Unlock", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Campaign(ctx context.Context, in *CampaignRequest, opts ...grpc.CallOption) (*CampaignResponse, error) {
+	out := new(CampaignResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Campaign", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Resign(ctx context.Context, in *ResignRequest, opts ...grpc.CallOption) (*ResignResponse, error) {
+	out := new(ResignResponse)
+	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/Resign", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *aPIClient) Observe(ctx context.Context, in *ObserveRequest, opts ...grpc.CallOption) (API_ObserveClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_API_serviceDesc.Streams[3], "/kvetch.api.v1.API/Observe", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &aPIObserveClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type API_ObserveClient interface {
+	Recv() (*ObserveResponse, error)
+	grpc.ClientStream
+}
+
+type aPIObserveClient struct {
+	grpc.ClientStream
+}
+
+func (x *aPIObserveClient) Recv() (*ObserveResponse, error) {
+	m := new(ObserveResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// APIServer is the server API for API service.
+type APIServer interface {
+	// SetValues takes a list of key values and stores them in the datastore and
+	// will notify subscribers of changes.
+	SetValues(context.Context, *SetValuesRequest) (*SetValuesResponse, error)
+	// GetValues retrieves a list of key values.
+	GetValues(context.Context, *GetValuesRequest) (*GetValuesResponse, error)
+	// ScanValues retrieves the key values for a request in pages, so large
+	// prefixes do not have to fit in a single message.
+	ScanValues(*ScanValuesRequest, API_ScanValuesServer) error
+	// CountKeys counts the keys for a list of requests and the size of their
+	// values.
+	CountKeys(context.Context, *CountKeysRequest) (*CountKeysResponse, error)
+	// Stat retrieves the metadata of the keys for a list of requests without
+	// returning their values.
+	Stat(context.Context, *StatRequest) (*StatResponse, error)
+	// GetHistory retrieves the versions of a key the datastore has retained,
+	// newest first.
+	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
+	// Subscribe will subscribe to a key or prefix and return the current value
+	// and any changes.
+	Subscribe(*SubscribeRequest, API_SubscribeServer) error
+	// DeleteValues removes keys or prefixes from the datastore and will notify
+	// subscribers of changes.
+	DeleteValues(context.Context, *DeleteValuesRequest) (*DeleteValuesResponse, error)
+	// Increment atomically adds a delta to the integer value of a key, creating
+	// the key when it is missing.
+	Increment(context.Context, *IncrementRequest) (*IncrementResponse, error)
+	// Txn evaluates a list of comparisons and runs the success operations if
+	// they all hold or the failure operations otherwise, in a single
+	// transaction.
+	Txn(context.Context, *TxnRequest) (*TxnResponse, error)
+	// GrantLease creates a lease that expires unless it is kept alive. Keys
+	// written with the lease are deleted when it expires or is revoked.
+	GrantLease(context.Context, *GrantLeaseRequest) (*GrantLeaseResponse, error)
+	// KeepAlive refreshes leases for as long as the stream is open. The stream
+	// ends with NOT_FOUND once a lease does not exist or has expired.
+	KeepAlive(API_KeepAliveServer) error
+	// RevokeLease revokes a lease and deletes every key written with it.
+	RevokeLease(context.Context, *RevokeLeaseRequest) (*RevokeLeaseResponse, error)
+	// LeaseTimeToLive retrieves the remaining time to live of a lease.
+	LeaseTimeToLive(context.Context, *LeaseTimeToLiveRequest) (*LeaseTimeToLiveResponse, error)
+	// Lock waits until the lock is held by the lease. Waiters acquire the lock
+	// in the order they asked for it, and the lock is released when the lease
+	// expires or is revoked.
+	Lock(context.Context, *LockRequest) (*LockResponse, error)
+	// Unlock releases a lock by deleting the key returned from Lock. The key
+	// has to be held by the lease that locked it.
+	Unlock(context.Context, *UnlockRequest) (*UnlockResponse, error)
+	// Campaign waits until the lease is elected leader. Candidates are elected
+	// in the order they campaigned, and leadership is lost when the lease
+	// expires or is revoked.
+	Campaign(context.Context, *CampaignRequest) (*CampaignResponse, error)
+	// Resign gives up leadership so the next candidate is elected.
+	Resign(context.Context, *ResignRequest) (*ResignResponse, error)
+	// Observe streams the leader of an election every time it changes.
+	Observe(*ObserveRequest, API_ObserveServer) error
+}
+
+// UnimplementedAPIServer can be embedded to have forward compatible implementations.
+type UnimplementedAPIServer struct {
+}
+
+func (*UnimplementedAPIServer) SetValues(ctx context.Context, req *SetValuesRequest) (*SetValuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetValues not implemented")
+}
+func (*UnimplementedAPIServer) GetValues(ctx context.Context, req *GetValuesRequest) (*GetValuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetValues not implemented")
+}
+func (*UnimplementedAPIServer) ScanValues(req *ScanValuesRequest, srv API_ScanValuesServer) error {
+	return status.Errorf(codes.Unimplemented, "method ScanValues not implemented")
+}
+func (*UnimplementedAPIServer) CountKeys(ctx context.Context, req *CountKeysRequest) (*CountKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountKeys not implemented")
+}
+func (*UnimplementedAPIServer) Stat(ctx context.Context, req *StatRequest) (*StatResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Stat not implemented")
+}
+func (*UnimplementedAPIServer) GetHistory(ctx context.Context, req *GetHistoryRequest) (*GetHistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (*UnimplementedAPIServer) Subscribe(req *SubscribeRequest, srv API_SubscribeServer) error {
+	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+}
+func (*UnimplementedAPIServer) DeleteValues(ctx context.Context, req *DeleteValuesRequest) (*DeleteValuesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteValues not implemented")
+}
+func (*UnimplementedAPIServer) Increment(ctx context.Context, req *IncrementRequest) (*IncrementResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Increment not implemented")
+}
+func (*UnimplementedAPIServer) Txn(ctx context.Context, req *TxnRequest) (*TxnResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Txn not implemented")
+}
+func (*UnimplementedAPIServer) GrantLease(ctx context.Context, req *GrantLeaseRequest) (*GrantLeaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantLease not implemented")
+}
+func (*UnimplementedAPIServer) KeepAlive(srv API_KeepAliveServer) error {
+	return status.Errorf(codes.Unimplemented, "method KeepAlive not implemented")
+}
+func (*UnimplementedAPIServer) RevokeLease(ctx context.Context, req *RevokeLeaseRequest) (*RevokeLeaseResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeLease not implemented")
+}
+func (*UnimplementedAPIServer) LeaseTimeToLive(ctx context.Context, req *LeaseTimeToLiveRequest) (*LeaseTimeToLiveResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method LeaseTimeToLive not implemented")
+}
+func (*UnimplementedAPIServer) Lock(ctx context.Context, req *LockRequest) (*LockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Lock not implemented")
+}
+func (*UnimplementedAPIServer) Unlock(ctx context.Context, req *UnlockRequest) (*UnlockResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Unlock not implemented")
+}
+func (*UnimplementedAPIServer) Campaign(ctx context.Context, req *CampaignRequest) (*CampaignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Campaign not implemented")
+}
+func (*UnimplementedAPIServer) Resign(ctx context.Context, req *ResignRequest) (*ResignResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Resign not implemented")
+}
+func (*UnimplementedAPIServer) Observe(req *ObserveRequest, srv API_ObserveServer) error {
+	return status.Errorf(codes.Unimplemented, "method Observe not implemented")
+}
+
+func RegisterAPIServer(s *grpc.Server, srv APIServer) {
+	s.RegisterService(&_API_serviceDesc, srv)
+}
+
+func _API_SetValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).SetValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/SetValues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).SetValues(ctx, req.(*SetValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_GetValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).GetValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/GetValues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GetValues(ctx, req.(*GetValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_ScanValues_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ScanValuesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).ScanValues(m, &aPIScanValuesServer{stream})
+}
+
+type API_ScanValuesServer interface {
+	Send(*ScanValuesResponse) error
+	grpc.ServerStream
+}
+
+type aPIScanValuesServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIScanValuesServer) Send(m *ScanValuesResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _API_CountKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).CountKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/CountKeys",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).CountKeys(ctx, req.(*CountKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _API_Stat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Stat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/Stat",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Stat(ctx, req.(*StatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func init() {
-	proto.RegisterType((*SetValuesRequest)(nil), "kvetch.api.v1.SetValuesRequest")
-	proto.RegisterType((*SetValuesResponse)(nil), "kvetch.api.v1.SetValuesResponse")
-	proto.RegisterType((*GetValuesRequest)(nil), "kvetch.api.v1.GetValuesRequest")
-	proto.RegisterType((*GetValuesRequest_GetValue)(nil), "kvetch.api.v1.GetValuesRequest.GetValue")
-	proto.RegisterType((*GetValuesResponse)(nil), "kvetch.api.v1.GetValuesResponse")
-	proto.RegisterType((*SubscribeRequest)(nil), "kvetch.api.v1.SubscribeRequest")
-	proto.RegisterType((*SubscribeResponse)(nil), "kvetch.api.v1.SubscribeResponse")
+func _API_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetHistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/GetHistory",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GetHistory(ctx, req.(*GetHistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func init() {
-	proto.RegisterFile("kvetch/api/v1/api.proto", fileDescriptor_261ca598fa2afdd5)
+func _API_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(APIServer).Subscribe(m, &aPISubscribeServer{stream})
 }
 
-var fileDescriptor_261ca598fa2afdd5 = []byte{
-	// 406 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xa4, 0x92, 0xc1, 0xca, 0x9b, 0x40,
-	0x10, 0xc7, 0xd9, 0x48, 0x8b, 0x4e, 0x1a, 0x30, 0xf6, 0x90, 0xd4, 0xd2, 0x56, 0x3c, 0x79, 0x5a,
-	0x6b, 0x72, 0x2a, 0xe4, 0x62, 0x08, 0xd8, 0x12, 0x08, 0x62, 0x20, 0x94, 0x5e, 0x82, 0xa6, 0x9b,
-	0x74, 0x89, 0x89, 0xd6, 0x5d, 0xa5, 0x79, 0x80, 0x3e, 0x41, 0xdf, 0xa0, 0xc7, 0xbe, 0x60, 0xaf,
-	0x1f, 0x6a, 0x94, 0x64, 0x43, 0xf8, 0xe0, 0xfb, 0x4e, 0x3a, 0xb3, 0xff, 0x99, 0xf9, 0xcd, 0x9f,
-	0x81, 0xc1, 0xbe, 0x20, 0x7c, 0xf3, 0xc3, 0x0e, 0x53, 0x6a, 0x17, 0x4e, 0xf9, 0xc1, 0x69, 0x96,
-	0xf0, 0x44, 0xeb, 0xd5, 0x0f, 0xb8, 0xcc, 0x14, 0x8e, 0xfe, 0xee, 0x5a, 0xb7, 0x27, 0xa7, 0x75,
-	0x11, 0xc6, 0x39, 0xa9, 0xd5, 0xfa, 0xfb, 0x5d, 0x92, 0xec, 0x62, 0x62, 0x57, 0x51, 0x94, 0x6f,
-	0xed, 0xef, 0x79, 0x16, 0x72, 0x9a, 0x1c, 0xeb, 0x77, 0xf3, 0x37, 0x02, 0x75, 0x49, 0xf8, 0xaa,
-	0x2c, 0x61, 0x01, 0xf9, 0x99, 0x13, 0xc6, 0xb5, 0x31, 0xc8, 0x07, 0xc2, 0x58, 0xb8, 0x23, 0x6c,
-	0x88, 0x0c, 0xc9, 0xea, 0x8e, 0x06, 0xf8, 0x6a, 0x2a, 0x9e, 0x93, 0x53, 0x55, 0x12, 0xb4, 0x42,
-	0x6d, 0x02, 0xaf, 0x38, 0x8f, 0xd7, 0x4d, 0xff, 0x61, 0xc7, 0x40, 0x56, 0x77, 0xf4, 0x06, 0xd7,
-	0x00, 0xb8, 0x01, 0xc0, 0xb3, 0xb3, 0x20, 0xe8, 0x72, 0x1e, 0x37, 0x81, 0xf9, 0x1a, 0xfa, 0x17,
-	0x18, 0x2c, 0x4d, 0x8e, 0x8c, 0x98, 0x7f, 0x10, 0xa8, 0x9e, 0x08, 0x37, 0x03, 0x39, 0xab, 0x7f,
-	0x1b, 0x38, 0x4b, 0x80, 0x13, 0x4b, 0xda, 0x44, 0xd0, 0x56, 0xea, 0x9f, 0x40, 0x6e, 0xb2, 0x9a,
-	0x0a, 0xd2, 0x9e, 0x9c, 0x86, 0xc8, 0x40, 0x96, 0x12, 0x94, 0xbf, 0xda, 0x5b, 0x50, 0x28, 0x5b,
-	0xa7, 0x19, 0xd9, 0xd2, 0x5f, 0xd5, 0x22, 0x72, 0x20, 0x53, 0xe6, 0x57, 0xb1, 0xf9, 0x19, 0xfa,
-	0x9e, 0x88, 0xfa, 0x24, 0xcb, 0x4c, 0x0c, 0xea, 0x32, 0x8f, 0xd8, 0x26, 0xa3, 0x11, 0x69, 0xd6,
-	0xd3, 0x41, 0xae, 0xe7, 0x9e, 0x1b, 0x29, 0x41, 0x1b, 0x97, 0x93, 0x2f, 0xf4, 0xcf, 0x98, 0x3c,
-	0xfa, 0x8f, 0x40, 0x72, 0xfd, 0x2f, 0xda, 0x02, 0x94, 0xd6, 0x76, 0xed, 0x83, 0x50, 0x27, 0xde,
-	0x85, 0x6e, 0xdc, 0x17, 0x9c, 0x61, 0x16, 0xa0, 0x78, 0x77, 0xfb, 0x79, 0x8f, 0xf5, 0xbb, 0xb5,
-	0xd5, 0x07, 0xa5, 0xdd, 0xf8, 0x96, 0x4f, 0xf0, 0xee, 0x96, 0x4f, 0x34, 0xeb, 0x23, 0x9a, 0x4e,
-	0xa0, 0xbf, 0x49, 0x0e, 0xd7, 0xc2, 0xa9, 0xec, 0xa6, 0xd4, 0x2f, 0x0f, 0xd4, 0x47, 0xdf, 0x5e,
-	0x84, 0x29, 0x2d, 0x9c, 0xbf, 0x1d, 0x69, 0xee, 0x7e, 0xfd, 0xd7, 0xe9, 0xcd, 0x6b, 0xa1, 0x9b,
-	0x52, 0xbc, 0x72, 0xa2, 0x97, 0xd5, 0x19, 0x8f, 0x1f, 0x02, 0x00, 0x00, 0xff, 0xff, 0x24, 0x58,
-	0x78, 0x6e, 0x9e, 0x03, 0x00, 0x00,
+type API_SubscribeServer interface {
+	Send(*SubscribeResponse) error
+	grpc.ServerStream
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConnInterface
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion6
-
-// APIClient is the client API for API service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type APIClient interface {
-	// SetValues takes a list of key values and stores them in the datastore and
-	// will notify subscribers of changes.
-	SetValues(ctx context.Context, in *SetValuesRequest, opts ...grpc.CallOption) (*SetValuesResponse, error)
-	// GetValues retrieves a list of key values.
-	GetValues(ctx context.Context, in *GetValuesRequest, opts ...grpc.CallOption) (*GetValuesResponse, error)
-	// Subscribe will subscribe to a key or prefix and return the current value
-	// and any changes.
-	Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (API_SubscribeClient, error)
+type aPISubscribeServer struct {
+	grpc.ServerStream
 }
 
-type aPIClient struct {
-	cc grpc.ClientConnInterface
+func (x *aPISubscribeServer) Send(m *SubscribeResponse) error {
+	return x.ServerStream.SendMsg(m)
 }
 
-func NewAPIClient(cc grpc.ClientConnInterface) APIClient {
-	return &aPIClient{cc}
+func _API_DeleteValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteValuesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).DeleteValues(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/DeleteValues",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).DeleteValues(ctx, req.(*DeleteValuesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *aPIClient) SetValues(ctx context.Context, in *SetValuesRequest, opts ...grpc.CallOption) (*SetValuesResponse, error) {
-	out := new(SetValuesResponse)
-	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/SetValues", in, out, opts...)
-	if err != nil {
+func _API_Increment_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IncrementRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(APIServer).Increment(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/Increment",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Increment(ctx, req.(*IncrementRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *aPIClient) GetValues(ctx context.Context, in *GetValuesRequest, opts ...grpc.CallOption) (*GetValuesResponse, error) {
-	out := new(GetValuesResponse)
-	err := c.cc.Invoke(ctx, "/kvetch.api.v1.API/GetValues", in, out, opts...)
-	if err != nil {
+func _API_Txn_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(TxnRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	return out, nil
+	if interceptor == nil {
+		return srv.(APIServer).Txn(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/Txn",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Txn(ctx, req.(*TxnRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (c *aPIClient) Subscribe(ctx context.Context, in *SubscribeRequest, opts ...grpc.CallOption) (API_SubscribeClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_API_serviceDesc.Streams[0], "/kvetch.api.v1.API/Subscribe", opts...)
-	if err != nil {
+func _API_GrantLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GrantLeaseRequest)
+	if err := dec(in); err != nil {
 		return nil, err
 	}
-	x := &aPISubscribeClient{stream}
-	if err := x.ClientStream.SendMsg(in); err != nil {
-		return nil, err
+	if interceptor == nil {
+		return srv.(APIServer).GrantLease(ctx, in)
 	}
-	if err := x.ClientStream.CloseSend(); err != nil {
-		return nil, err
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/GrantLease",
 	}
-	return x, nil
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).GrantLease(ctx, req.(*GrantLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-type API_SubscribeClient interface {
-	Recv() (*SubscribeResponse, error)
-	grpc.ClientStream
+func _API_KeepAlive_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(APIServer).KeepAlive(&aPIKeepAliveServer{stream})
 }
 
-type aPISubscribeClient struct {
-	grpc.ClientStream
+type API_KeepAliveServer interface {
+	Send(*KeepAliveResponse) error
+	Recv() (*KeepAliveRequest, error)
+	grpc.ServerStream
 }
 
-func (x *aPISubscribeClient) Recv() (*SubscribeResponse, error) {
-	m := new(SubscribeResponse)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
+type aPIKeepAliveServer struct {
+	grpc.ServerStream
+}
+
+func (x *aPIKeepAliveServer) Send(m *KeepAliveResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *aPIKeepAliveServer) Recv() (*KeepAliveRequest, error) {
+	m := new(KeepAliveRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
 		return nil, err
 	}
 	return m, nil
 }
 
-// APIServer is the server API for API service.
-type APIServer interface {
-	// SetValues takes a list of key values and stores them in the datastore and
-	// will notify subscribers of changes.
-	SetValues(context.Context, *SetValuesRequest) (*SetValuesResponse, error)
-	// GetValues retrieves a list of key values.
-	GetValues(context.Context, *GetValuesRequest) (*GetValuesResponse, error)
-	// Subscribe will subscribe to a key or prefix and return the current value
-	// and any changes.
-	Subscribe(*SubscribeRequest, API_SubscribeServer) error
+func _API_RevokeLease_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeLeaseRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).RevokeLease(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/RevokeLease",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).RevokeLease(ctx, req.(*RevokeLeaseRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-// UnimplementedAPIServer can be embedded to have forward compatible implementations.
-type UnimplementedAPIServer struct {
+func _API_LeaseTimeToLive_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LeaseTimeToLiveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).LeaseTimeToLive(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/LeaseTimeToLive",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).LeaseTimeToLive(ctx, req.(*LeaseTimeToLiveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func (*UnimplementedAPIServer) SetValues(ctx context.Context, req *SetValuesRequest) (*SetValuesResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method SetValues not implemented")
-}
-func (*UnimplementedAPIServer) GetValues(ctx context.Context, req *GetValuesRequest) (*GetValuesResponse, error) {
-	return nil, status.Errorf(codes.Unimplemented, "method GetValues not implemented")
-}
-func (*UnimplementedAPIServer) Subscribe(req *SubscribeRequest, srv API_SubscribeServer) error {
-	return status.Errorf(codes.Unimplemented, "method Subscribe not implemented")
+func _API_Lock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Lock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/Lock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Lock(ctx, req.(*LockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func RegisterAPIServer(s *grpc.Server, srv APIServer) {
-	s.RegisterService(&_API_serviceDesc, srv)
+func _API_Unlock_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnlockRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(APIServer).Unlock(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/kvetch.api.v1.API/Unlock",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(APIServer).Unlock(ctx, req.(*UnlockRequest))
+	}
+	return interceptor(ctx, in, info, handler)
 }
 
-func _API_SetValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(SetValuesRequest)
+func _API_Campaign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CampaignRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(APIServer).SetValues(ctx, in)
+		return srv.(APIServer).Campaign(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/kvetch.api.v1.API/SetValues",
+		FullMethod: "/kvetch.api.v1.API/Campaign",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(APIServer).SetValues(ctx, req.(*SetValuesRequest))
+		return srv.(APIServer).Campaign(ctx, req.(*CampaignRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _API_GetValues_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(GetValuesRequest)
+func _API_Resign_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResignRequest)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
 	if interceptor == nil {
-		return srv.(APIServer).GetValues(ctx, in)
+		return srv.(APIServer).Resign(ctx, in)
 	}
 	info := &grpc.UnaryServerInfo{
 		Server:     srv,
-		FullMethod: "/kvetch.api.v1.API/GetValues",
+		FullMethod: "/kvetch.api.v1.API/Resign",
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(APIServer).GetValues(ctx, req.(*GetValuesRequest))
+		return srv.(APIServer).Resign(ctx, req.(*ResignRequest))
 	}
 	return interceptor(ctx, in, info, handler)
 }
 
-func _API_Subscribe_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(SubscribeRequest)
+func _API_Observe_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ObserveRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(APIServer).Subscribe(m, &aPISubscribeServer{stream})
+	return srv.(APIServer).Observe(m, &aPIObserveServer{stream})
 }
 
-type API_SubscribeServer interface {
-	Send(*SubscribeResponse) error
+type API_ObserveServer interface {
+	Send(*ObserveResponse) error
 	grpc.ServerStream
 }
 
-type aPISubscribeServer struct {
+type aPIObserveServer struct {
 	grpc.ServerStream
 }
 
-func (x *aPISubscribeServer) Send(m *SubscribeResponse) error {
+func (x *aPIObserveServer) Send(m *ObserveResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
@@ -530,13 +3996,81 @@ var _API_serviceDesc = grpc.ServiceDesc{
 			MethodName: "GetValues",
 			Handler:    _API_GetValues_Handler,
 		},
+		{
+			MethodName: "CountKeys",
+			Handler:    _API_CountKeys_Handler,
+		},
+		{
+			MethodName: "Stat",
+			Handler:    _API_Stat_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _API_GetHistory_Handler,
+		},
+		{
+			MethodName: "DeleteValues",
+			Handler:    _API_DeleteValues_Handler,
+		},
+		{
+			MethodName: "Increment",
+			Handler:    _API_Increment_Handler,
+		},
+		{
+			MethodName: "Txn",
+			Handler:    _API_Txn_Handler,
+		},
+		{
+			MethodName: "GrantLease",
+			Handler:    _API_GrantLease_Handler,
+		},
+		{
+			MethodName: "RevokeLease",
+			Handler:    _API_RevokeLease_Handler,
+		},
+		{
+			MethodName: "LeaseTimeToLive",
+			Handler:    _API_LeaseTimeToLive_Handler,
+		},
+		{
+			MethodName: "Lock",
+			Handler:    _API_Lock_Handler,
+		},
+		{
+			MethodName: "Unlock",
+			Handler:    _API_Unlock_Handler,
+		},
+		{
+			MethodName: "Campaign",
+			Handler:    _API_Campaign_Handler,
+		},
+		{
+			MethodName: "Resign",
+			Handler:    _API_Resign_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "ScanValues",
+			Handler:       _API_ScanValues_Handler,
+			ServerStreams: true,
+		},
 		{
 			StreamName:    "Subscribe",
 			Handler:       _API_Subscribe_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "KeepAlive",
+			Handler:       _API_KeepAlive_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "Observe",
+			Handler:       _API_Observe_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "kvetch/api/v1/api.proto",
 }
@@ -6,6 +6,8 @@ package apiv1
 import (
 	fmt "fmt"
 	proto "github.com/golang/protobuf/proto"
+	duration "github.com/golang/protobuf/ptypes/duration"
+	timestamp "github.com/golang/protobuf/ptypes/timestamp"
 	math "math"
 )
 
@@ -22,11 +24,31 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 
 // KeyValue is a key value object.
 type KeyValue struct {
-	Key                  string   `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
-	Value                []byte   `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
-	XXX_NoUnkeyedLiteral struct{} `json:"-"`
-	XXX_unrecognized     []byte   `json:"-"`
-	XXX_sizecache        int32    `json:"-"`
+	Key   string `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Value []byte `protobuf:"bytes,2,opt,name=value,proto3" json:"value,omitempty"`
+	// create_revision is the revision of the write that created the key.
+	CreateRevision uint64 `protobuf:"varint,3,opt,name=create_revision,json=createRevision,proto3" json:"create_revision,omitempty"`
+	// mod_revision is the revision of the last write to the key.
+	ModRevision uint64 `protobuf:"varint,4,opt,name=mod_revision,json=modRevision,proto3" json:"mod_revision,omitempty"`
+	// version is the number of writes to the key since it was created.
+	Version uint64 `protobuf:"varint,5,opt,name=version,proto3" json:"version,omitempty"`
+	// lease is the lease the key is attached to, or zero.
+	Lease int64 `protobuf:"varint,6,opt,name=lease,proto3" json:"lease,omitempty"`
+	// expires_at is when the key expires, or empty when it has no ttl. It is
+	// only set on reads and ignored on writes.
+	ExpiresAt *timestamp.Timestamp `protobuf:"bytes,7,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	// ttl_duration is the time the key has left to live when it was read, or
+	// empty when it has no ttl. It is only set on reads and ignored on writes.
+	TtlDuration *duration.Duration `protobuf:"bytes,8,opt,name=ttl_duration,json=ttlDuration,proto3" json:"ttl_duration,omitempty"`
+	// set_expires_at is when the key expires. It is only used on writes, where
+	// it overrides the ttl of the request.
+	SetExpiresAt *timestamp.Timestamp `protobuf:"bytes,9,opt,name=set_expires_at,json=setExpiresAt,proto3" json:"set_expires_at,omitempty"`
+	// set_ttl_duration is the ttl of the key. It is only used on writes without
+	// set_expires_at, where it overrides the ttl of the request.
+	SetTtlDuration       *duration.Duration `protobuf:"bytes,10,opt,name=set_ttl_duration,json=setTtlDuration,proto3" json:"set_ttl_duration,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
 }
 
 func (m *KeyValue) Reset()         { *m = KeyValue{} }
@@ -68,6 +90,62 @@ func (m *KeyValue) GetValue() []byte {
 	return nil
 }
 
+func (m *KeyValue) GetCreateRevision() uint64 {
+	if m != nil {
+		return m.CreateRevision
+	}
+	return 0
+}
+
+func (m *KeyValue) GetModRevision() uint64 {
+	if m != nil {
+		return m.ModRevision
+	}
+	return 0
+}
+
+func (m *KeyValue) GetVersion() uint64 {
+	if m != nil {
+		return m.Version
+	}
+	return 0
+}
+
+func (m *KeyValue) GetLease() int64 {
+	if m != nil {
+		return m.Lease
+	}
+	return 0
+}
+
+func (m *KeyValue) GetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.ExpiresAt
+	}
+	return nil
+}
+
+func (m *KeyValue) GetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.TtlDuration
+	}
+	return nil
+}
+
+func (m *KeyValue) GetSetExpiresAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.SetExpiresAt
+	}
+	return nil
+}
+
+func (m *KeyValue) GetSetTtlDuration() *duration.Duration {
+	if m != nil {
+		return m.SetTtlDuration
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*KeyValue)(nil), "kvetch.api.v1.KeyValue")
 }
@@ -77,15 +155,28 @@ func init() {
 }
 
 var fileDescriptor_da126830bd373ffc = []byte{
-	// 156 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xe2, 0x92, 0xcd, 0x2e, 0x4b, 0x2d,
-	0x49, 0xce, 0xd0, 0x4f, 0x2c, 0xc8, 0xd4, 0x2f, 0x33, 0xd4, 0xcf, 0x4e, 0xad, 0x8c, 0x2f, 0x4b,
-	0xcc, 0x29, 0x4d, 0xd5, 0x2b, 0x28, 0xca, 0x2f, 0xc9, 0x17, 0xe2, 0x85, 0x48, 0xeb, 0x25, 0x16,
-	0x64, 0xea, 0x95, 0x19, 0x2a, 0x19, 0x71, 0x71, 0x78, 0xa7, 0x56, 0x86, 0x81, 0x14, 0x08, 0x09,
-	0x70, 0x31, 0x67, 0xa7, 0x56, 0x4a, 0x30, 0x2a, 0x30, 0x6a, 0x70, 0x06, 0x81, 0x98, 0x42, 0x22,
-	0x5c, 0xac, 0x60, 0xbd, 0x12, 0x4c, 0x0a, 0x8c, 0x1a, 0x3c, 0x41, 0x10, 0x8e, 0x93, 0x23, 0x97,
-	0x60, 0x72, 0x7e, 0xae, 0x1e, 0x8a, 0x41, 0x4e, 0xbc, 0x30, 0x63, 0x02, 0x40, 0xd6, 0x04, 0x30,
-	0x46, 0xb1, 0x26, 0x16, 0x64, 0x96, 0x19, 0x2e, 0x62, 0x62, 0xf6, 0x76, 0x8c, 0x58, 0xc5, 0xc4,
-	0xeb, 0x0d, 0x51, 0xed, 0x58, 0x90, 0xa9, 0x17, 0x66, 0x98, 0xc4, 0x06, 0x76, 0x8c, 0x31, 0x20,
-	0x00, 0x00, 0xff, 0xff, 0xe6, 0x5b, 0xc7, 0x5d, 0xad, 0x00, 0x00, 0x00,
+	// 360 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x92, 0xcf, 0x4f, 0xea, 0x40,
+	0x10, 0xc7, 0x53, 0xca, 0xcf, 0xa5, 0xf0, 0x78, 0x9b, 0x77, 0xe8, 0x23, 0x51, 0xab, 0x17, 0x7b,
+	0xda, 0xa6, 0x7a, 0x32, 0xf1, 0x60, 0x51, 0x4f, 0x5c, 0x48, 0x43, 0x88, 0xf1, 0xd2, 0x2c, 0x30,
+	0xe2, 0x86, 0x96, 0x6d, 0xda, 0xa1, 0x91, 0x7f, 0xc7, 0xa3, 0x07, 0xff, 0x46, 0xd3, 0x2e, 0x05,
+	0xd1, 0x03, 0xb7, 0xce, 0x7e, 0x3f, 0xd3, 0xcf, 0x4c, 0xbb, 0xe4, 0x64, 0x99, 0x01, 0xce, 0x5e,
+	0x1d, 0x1e, 0x0b, 0x27, 0x73, 0x9d, 0x25, 0x6c, 0x82, 0x8c, 0x87, 0x6b, 0x60, 0x71, 0x22, 0x51,
+	0xd2, 0x8e, 0x8a, 0x19, 0x8f, 0x05, 0xcb, 0xdc, 0xfe, 0xe9, 0x42, 0xca, 0x45, 0x08, 0x4e, 0x11,
+	0x4e, 0xd7, 0x2f, 0xce, 0x7c, 0x9d, 0x70, 0x14, 0x72, 0xa5, 0xf0, 0xfe, 0xd9, 0xcf, 0x1c, 0x45,
+	0x04, 0x29, 0xf2, 0x28, 0x56, 0xc0, 0xc5, 0xa7, 0x4e, 0x9a, 0x43, 0xd8, 0x4c, 0x72, 0x05, 0xed,
+	0x11, 0x7d, 0x09, 0x1b, 0x53, 0xb3, 0x34, 0xbb, 0xe5, 0xe7, 0x8f, 0xf4, 0x1f, 0xa9, 0x15, 0x76,
+	0xb3, 0x62, 0x69, 0xb6, 0xe1, 0xab, 0x82, 0x5e, 0x92, 0x3f, 0xb3, 0x04, 0x38, 0x42, 0x90, 0x40,
+	0x26, 0x52, 0x21, 0x57, 0xa6, 0x6e, 0x69, 0x76, 0xd5, 0xef, 0xaa, 0x63, 0x7f, 0x7b, 0x4a, 0xcf,
+	0x89, 0x11, 0xc9, 0xf9, 0x9e, 0xaa, 0x16, 0x54, 0x3b, 0x92, 0xf3, 0x1d, 0x62, 0x92, 0x46, 0x06,
+	0x49, 0x91, 0xd6, 0x8a, 0xb4, 0x2c, 0x73, 0x77, 0x08, 0x3c, 0x05, 0xb3, 0x6e, 0x69, 0xb6, 0xee,
+	0xab, 0x82, 0xde, 0x10, 0x02, 0x6f, 0xb1, 0x48, 0x20, 0x0d, 0x38, 0x9a, 0x0d, 0x4b, 0xb3, 0xdb,
+	0x57, 0x7d, 0xa6, 0xd6, 0x64, 0xe5, 0x9a, 0x6c, 0x5c, 0xae, 0xe9, 0xb7, 0xb6, 0xb4, 0x87, 0xf4,
+	0x96, 0x18, 0x88, 0x61, 0x50, 0x7e, 0x22, 0xb3, 0x59, 0x34, 0xff, 0xff, 0xd5, 0xfc, 0xb0, 0x05,
+	0xfc, 0x36, 0x62, 0x58, 0x16, 0xf4, 0x8e, 0x74, 0x53, 0xc0, 0xe0, 0x9b, 0xbc, 0x75, 0x54, 0x6e,
+	0xa4, 0x80, 0x8f, 0x3b, 0xff, 0x3d, 0xe9, 0xe5, 0x6f, 0x38, 0x98, 0x81, 0x1c, 0x9b, 0x21, 0x97,
+	0x8e, 0xf7, 0x63, 0x0c, 0x3c, 0xf2, 0x77, 0x26, 0x23, 0x76, 0x70, 0x0d, 0x06, 0x9d, 0xf2, 0x17,
+	0x8e, 0xf2, 0xfe, 0x91, 0xf6, 0x5c, 0xe3, 0xb1, 0xc8, 0xdc, 0xf7, 0x8a, 0x3e, 0xf4, 0x9e, 0x3e,
+	0x2a, 0x9d, 0xa1, 0xa2, 0xbd, 0x58, 0xb0, 0x89, 0x3b, 0xad, 0x17, 0x96, 0xeb, 0xaf, 0x01, 0x00,
+	0x4d, 0x47, 0xab, 0x19, 0x6b, 0x02, 0x00, 0x00,
 }
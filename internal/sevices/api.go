@@ -2,17 +2,37 @@ package services
 
 import (
 	"context"
+	"io"
 
+	"github.com/syncromatics/kvetch/internal/datastore"
 	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
 
 	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Datastore is the key value datastore.
 type Datastore interface {
 	Get(request *apiv1.GetValuesRequest) (*apiv1.GetValuesResponse, error)
-	Set(request *apiv1.SetValuesRequest) error
+	Scan(request *apiv1.ScanValuesRequest, cb func(*apiv1.ScanValuesResponse) error) error
+	CountKeys(request *apiv1.CountKeysRequest) (*apiv1.CountKeysResponse, error)
+	Stat(request *apiv1.StatRequest) (*apiv1.StatResponse, error)
+	GetHistory(request *apiv1.GetHistoryRequest) (*apiv1.GetHistoryResponse, error)
+	Set(request *apiv1.SetValuesRequest) (*apiv1.SetValuesResponse, error)
 	Subscribe(ctx context.Context, subscription *apiv1.SubscribeRequest, cb func(*apiv1.SubscribeResponse) error) error
+	Delete(request *apiv1.DeleteValuesRequest) (*apiv1.DeleteValuesResponse, error)
+	Increment(request *apiv1.IncrementRequest) (*apiv1.IncrementResponse, error)
+	Txn(request *apiv1.TxnRequest) (*apiv1.TxnResponse, error)
+	GrantLease(request *apiv1.GrantLeaseRequest, owner string) (*apiv1.GrantLeaseResponse, error)
+	KeepAlive(request *apiv1.KeepAliveRequest) (*apiv1.KeepAliveResponse, error)
+	RevokeLease(request *apiv1.RevokeLeaseRequest) (*apiv1.RevokeLeaseResponse, error)
+	LeaseTimeToLive(request *apiv1.LeaseTimeToLiveRequest) (*apiv1.LeaseTimeToLiveResponse, error)
+	Lock(ctx context.Context, request *apiv1.LockRequest) (*apiv1.LockResponse, error)
+	Unlock(request *apiv1.UnlockRequest) (*apiv1.UnlockResponse, error)
+	Campaign(ctx context.Context, request *apiv1.CampaignRequest) (*apiv1.CampaignResponse, error)
+	Resign(request *apiv1.ResignRequest) (*apiv1.ResignResponse, error)
+	Observe(ctx context.Context, request *apiv1.ObserveRequest, cb func(*apiv1.ObserveResponse) error) error
 }
 
 var _ apiv1.APIServer = &APIService{}
@@ -31,7 +51,46 @@ func NewAPIService(datastore Datastore) *APIService {
 func (s *APIService) GetValues(ctx context.Context, request *apiv1.GetValuesRequest) (*apiv1.GetValuesResponse, error) {
 	r, err := s.datastore.Get(request)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to get from datastore")
+		return nil, datastoreError(err, "failed to get from datastore")
+	}
+
+	return r, nil
+}
+
+// ScanValues gets the key values for a request in pages
+func (s *APIService) ScanValues(request *apiv1.ScanValuesRequest, stream apiv1.API_ScanValuesServer) error {
+	err := s.datastore.Scan(request, stream.Send)
+	if err != nil {
+		return datastoreError(err, "failed to scan datastore")
+	}
+	return nil
+}
+
+// CountKeys counts the keys for a list of requests
+func (s *APIService) CountKeys(ctx context.Context, request *apiv1.CountKeysRequest) (*apiv1.CountKeysResponse, error) {
+	r, err := s.datastore.CountKeys(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to count keys in datastore")
+	}
+
+	return r, nil
+}
+
+// Stat gets the metadata of the keys for a list of requests
+func (s *APIService) Stat(ctx context.Context, request *apiv1.StatRequest) (*apiv1.StatResponse, error) {
+	r, err := s.datastore.Stat(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to stat keys in datastore")
+	}
+
+	return r, nil
+}
+
+// GetHistory retrieves the retained versions of a key
+func (s *APIService) GetHistory(ctx context.Context, request *apiv1.GetHistoryRequest) (*apiv1.GetHistoryResponse, error) {
+	r, err := s.datastore.GetHistory(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to get history from datastore")
 	}
 
 	return r, nil
@@ -39,19 +98,171 @@ func (s *APIService) GetValues(ctx context.Context, request *apiv1.GetValuesRequ
 
 // SetValues sets a list of key values
 func (s *APIService) SetValues(ctx context.Context, request *apiv1.SetValuesRequest) (*apiv1.SetValuesResponse, error) {
-	err := s.datastore.Set(request)
+	r, err := s.datastore.Set(request)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to set in datastore")
+		return nil, datastoreError(err, "failed to set in datastore")
 	}
 
-	return &apiv1.SetValuesResponse{}, nil
+	return r, nil
 }
 
 // Subscribe subscribes to a list of prefixes
 func (s *APIService) Subscribe(request *apiv1.SubscribeRequest, stream apiv1.API_SubscribeServer) error {
 	err := s.datastore.Subscribe(stream.Context(), request, stream.Send)
 	if err != nil {
-		return errors.Wrap(err, "failed to subscribe")
+		return datastoreError(err, "failed to subscribe")
 	}
 	return nil
 }
+
+// DeleteValues deletes a list of keys or prefixes
+func (s *APIService) DeleteValues(ctx context.Context, request *apiv1.DeleteValuesRequest) (*apiv1.DeleteValuesResponse, error) {
+	r, err := s.datastore.Delete(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to delete from datastore")
+	}
+
+	return r, nil
+}
+
+// Increment atomically adds a delta to the integer value of a key
+func (s *APIService) Increment(ctx context.Context, request *apiv1.IncrementRequest) (*apiv1.IncrementResponse, error) {
+	r, err := s.datastore.Increment(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to increment in datastore")
+	}
+
+	return r, nil
+}
+
+// Txn runs a conditional transaction
+func (s *APIService) Txn(ctx context.Context, request *apiv1.TxnRequest) (*apiv1.TxnResponse, error) {
+	r, err := s.datastore.Txn(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to run transaction in datastore")
+	}
+
+	return r, nil
+}
+
+// GrantLease creates a lease that expires unless it is kept alive, owned by the subject the request
+// was authenticated as
+func (s *APIService) GrantLease(ctx context.Context, request *apiv1.GrantLeaseRequest) (*apiv1.GrantLeaseResponse, error) {
+	r, err := s.datastore.GrantLease(request, subject(ctx))
+	if err != nil {
+		return nil, datastoreError(err, "failed to grant lease in datastore")
+	}
+
+	return r, nil
+}
+
+// KeepAlive refreshes leases for as long as the client keeps sending requests
+func (s *APIService) KeepAlive(stream apiv1.API_KeepAliveServer) error {
+	for {
+		request, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "failed to receive keep alive")
+		}
+
+		r, err := s.datastore.KeepAlive(request)
+		if err != nil {
+			return datastoreError(err, "failed to keep lease alive in datastore")
+		}
+
+		err = stream.Send(r)
+		if err != nil {
+			return errors.Wrap(err, "failed to send keep alive")
+		}
+	}
+}
+
+// RevokeLease revokes a lease and deletes its keys
+func (s *APIService) RevokeLease(ctx context.Context, request *apiv1.RevokeLeaseRequest) (*apiv1.RevokeLeaseResponse, error) {
+	r, err := s.datastore.RevokeLease(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to revoke lease in datastore")
+	}
+
+	return r, nil
+}
+
+// LeaseTimeToLive gets the remaining time to live of a lease
+func (s *APIService) LeaseTimeToLive(ctx context.Context, request *apiv1.LeaseTimeToLiveRequest) (*apiv1.LeaseTimeToLiveResponse, error) {
+	r, err := s.datastore.LeaseTimeToLive(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to get lease from datastore")
+	}
+
+	return r, nil
+}
+
+// Lock waits until the lock is held by the lease
+func (s *APIService) Lock(ctx context.Context, request *apiv1.LockRequest) (*apiv1.LockResponse, error) {
+	r, err := s.datastore.Lock(ctx, request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to lock in datastore")
+	}
+
+	return r, nil
+}
+
+// Unlock releases a lock
+func (s *APIService) Unlock(ctx context.Context, request *apiv1.UnlockRequest) (*apiv1.UnlockResponse, error) {
+	r, err := s.datastore.Unlock(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to unlock in datastore")
+	}
+
+	return r, nil
+}
+
+// Campaign waits until the lease is elected leader
+func (s *APIService) Campaign(ctx context.Context, request *apiv1.CampaignRequest) (*apiv1.CampaignResponse, error) {
+	r, err := s.datastore.Campaign(ctx, request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to campaign in datastore")
+	}
+
+	return r, nil
+}
+
+// Resign gives up leadership
+func (s *APIService) Resign(ctx context.Context, request *apiv1.ResignRequest) (*apiv1.ResignResponse, error) {
+	r, err := s.datastore.Resign(request)
+	if err != nil {
+		return nil, datastoreError(err, "failed to resign in datastore")
+	}
+
+	return r, nil
+}
+
+// Observe streams the leader of an election
+func (s *APIService) Observe(request *apiv1.ObserveRequest, stream apiv1.API_ObserveServer) error {
+	err := s.datastore.Observe(stream.Context(), request, stream.Send)
+	if err != nil {
+		return datastoreError(err, "failed to observe")
+	}
+	return nil
+}
+
+// datastoreError maps datastore errors clients can act on to grpc status codes.
+func datastoreError(err error, message string) error {
+	switch errors.Cause(err) {
+	case datastore.ErrCompacted:
+		return status.Error(codes.OutOfRange, err.Error())
+	case datastore.ErrLeaseNotFound:
+		return status.Error(codes.NotFound, err.Error())
+	case datastore.ErrWaiterDeleted:
+		return status.Error(codes.Aborted, err.Error())
+	case datastore.ErrSlowConsumer:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case datastore.ErrBatchTooLarge, datastore.ErrInvalidRequest:
+		return status.Error(codes.InvalidArgument, err.Error())
+	case datastore.ErrFailedPrecondition, datastore.ErrHistoryNotRetained:
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return errors.Wrap(err, message)
+}
@@ -0,0 +1,107 @@
+package services_test
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/syncromatics/kvetch/internal/datastore"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	services "github.com/syncromatics/kvetch/internal/sevices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"gotest.tools/assert"
+)
+
+func Test_GetValuesErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_GetValuesErrors")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{
+		HistoryVersions: &wrappers.Int32Value{Value: 2},
+	})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	api := services.NewAPIService(store)
+
+	var revision uint64
+	for _, value := range []string{"1", "2", "3"} {
+		response, err := api.SetValues(context.Background(), &apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{
+				&apiv1.KeyValue{
+					Key:   "errors/a",
+					Value: []byte(value),
+				},
+			},
+		})
+		assert.NilError(t, err)
+		if revision == 0 {
+			revision = response.Header.Revision
+		}
+	}
+
+	requests := []*apiv1.GetValuesRequest_GetValue{
+		&apiv1.GetValuesRequest_GetValue{
+			Key: "errors/a",
+		},
+	}
+	tests := []struct {
+		name    string
+		request *apiv1.GetValuesRequest
+		code    codes.Code
+	}{
+		{"compacted revision", &apiv1.GetValuesRequest{Requests: requests, Revision: revision}, codes.OutOfRange},
+		{"future revision", &apiv1.GetValuesRequest{Requests: requests, Revision: revision + 100}, codes.InvalidArgument},
+		{"malformed as of", &apiv1.GetValuesRequest{Requests: requests, AsOf: &timestamp.Timestamp{Nanos: -1}}, codes.InvalidArgument},
+		{"revision and as of", &apiv1.GetValuesRequest{Requests: requests, Revision: revision, AsOf: &timestamp.Timestamp{}}, codes.InvalidArgument},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := api.GetValues(context.Background(), test.request)
+			assert.Equal(t, status.Code(err), test.code)
+		})
+	}
+
+	// past revisions cannot be read when only the latest versions are retained
+	latestDir, err := ioutil.TempDir("", "Test_GetValuesErrors")
+	assert.NilError(t, err)
+	latest, err := datastore.NewKVStore(latestDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+	defer latest.Close()
+
+	_, err = services.NewAPIService(latest).GetValues(context.Background(), &apiv1.GetValuesRequest{Requests: requests, AsOf: &timestamp.Timestamp{}})
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
+
+func Test_IncrementErrors(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "Test_IncrementErrors")
+	assert.NilError(t, err)
+
+	store, err := datastore.NewKVStore(tmpDir, &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+	defer store.Close()
+
+	api := services.NewAPIService(store)
+
+	_, err = api.SetValues(context.Background(), &apiv1.SetValuesRequest{
+		Messages: []*apiv1.KeyValue{
+			&apiv1.KeyValue{
+				Key:   "counters/name",
+				Value: []byte("name"),
+			},
+		},
+	})
+	assert.NilError(t, err)
+	_, err = api.Increment(context.Background(), &apiv1.IncrementRequest{Key: "counters/name", Delta: 1})
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+
+	_, err = api.Increment(context.Background(), &apiv1.IncrementRequest{Key: "counters/max", Delta: math.MaxInt64})
+	assert.NilError(t, err)
+	_, err = api.Increment(context.Background(), &apiv1.IncrementRequest{Key: "counters/max", Delta: 1})
+	assert.Equal(t, status.Code(err), codes.FailedPrecondition)
+}
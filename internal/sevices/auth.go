@@ -0,0 +1,569 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syncromatics/kvetch/internal/datastore"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// permission is what a role is allowed to do with the keys under a prefix.
+type permission string
+
+const (
+	permissionRead  permission = "read"
+	permissionWrite permission = "write"
+	permissionWatch permission = "watch"
+)
+
+// AuthConfig is the file format of the tokens, json web token key and role permissions the
+// Authorizer enforces.
+type AuthConfig struct {
+	Tokens []TokenConfig `json:"tokens"`
+	JWT    *JWTConfig    `json:"jwt"`
+	// Roles are the rules of each role.
+	Roles map[string][]RuleConfig `json:"roles"`
+}
+
+// TokenConfig is a static bearer token and the identity it authenticates as.
+type TokenConfig struct {
+	Token   string   `json:"token"`
+	Subject string   `json:"subject"`
+	Roles   []string `json:"roles"`
+}
+
+// JWTConfig verifies json web tokens with a local key file. The key file is relative to the config
+// file.
+type JWTConfig struct {
+	KeyFile  string `json:"key_file"`
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// RolesClaim is the claim holding the roles of the token, roles by default.
+	RolesClaim string `json:"roles_claim"`
+}
+
+// RuleConfig grants permissions on the keys under a prefix. An empty prefix is every key.
+type RuleConfig struct {
+	Prefix      string       `json:"prefix"`
+	Permissions []permission `json:"permissions"`
+}
+
+// identity is who a request was authenticated as.
+type identity struct {
+	subject string
+	roles   []string
+}
+
+// auth is a loaded auth config.
+type auth struct {
+	tokens map[[sha256.Size]byte]*identity
+	jwt    *jwtVerifier
+	roles  map[string][]RuleConfig
+	// files are the files the config was loaded from.
+	files []string
+}
+
+// Leases looks up the owners and keys of the leases requests use.
+type Leases interface {
+	LeaseOwner(id int64) (string, error)
+	LeaseTimeToLive(request *apiv1.LeaseTimeToLiveRequest) (*apiv1.LeaseTimeToLiveResponse, error)
+}
+
+// Authorizer authenticates requests with bearer tokens and checks the permissions of their roles
+// on the keys they use, and that the leases they use were granted to them.
+type Authorizer struct {
+	path   string
+	leases Leases
+
+	mtx  sync.RWMutex
+	auth *auth
+}
+
+// NewAuthorizer creates an authorizer with the config in the file.
+func NewAuthorizer(path string, leases Leases) (*Authorizer, error) {
+	a := &Authorizer{
+		path:   path,
+		leases: leases,
+	}
+	err := a.Load()
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Load loads the config file again. The previous config is kept when it fails.
+func (a *Authorizer) Load() error {
+	data, err := ioutil.ReadFile(a.path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read auth config")
+	}
+	config := &AuthConfig{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return errors.Wrap(err, "failed to parse auth config")
+	}
+
+	loaded := &auth{
+		tokens: map[[sha256.Size]byte]*identity{},
+		roles:  config.Roles,
+		files:  []string{a.path},
+	}
+	for _, token := range config.Tokens {
+		if token.Token == "" {
+			return errors.Errorf("token for %q is empty", token.Subject)
+		}
+		loaded.tokens[sha256.Sum256([]byte(token.Token))] = &identity{
+			subject: token.Subject,
+			roles:   token.Roles,
+		}
+	}
+	for role, rules := range config.Roles {
+		for _, rule := range rules {
+			for _, p := range rule.Permissions {
+				if p != permissionRead && p != permissionWrite && p != permissionWatch {
+					return errors.Errorf("unknown permission %q for role %q", p, role)
+				}
+			}
+		}
+	}
+
+	if config.JWT != nil {
+		keyFile := config.JWT.KeyFile
+		if !filepath.IsAbs(keyFile) {
+			keyFile = filepath.Join(filepath.Dir(a.path), keyFile)
+		}
+		data, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return errors.Wrap(err, "failed to read jwt key")
+		}
+		key, err := parseJWTKey(data)
+		if err != nil {
+			return err
+		}
+		rolesClaim := config.JWT.RolesClaim
+		if rolesClaim == "" {
+			rolesClaim = "roles"
+		}
+		loaded.jwt = &jwtVerifier{
+			key:        key,
+			issuer:     config.JWT.Issuer,
+			audience:   config.JWT.Audience,
+			rolesClaim: rolesClaim,
+		}
+		loaded.files = append(loaded.files, keyFile)
+	}
+
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	a.auth = loaded
+	return nil
+}
+
+// Files returns the files the config was loaded from.
+func (a *Authorizer) Files() []string {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return a.auth.files
+}
+
+func (a *Authorizer) current() *auth {
+	a.mtx.RLock()
+	defer a.mtx.RUnlock()
+
+	return a.auth
+}
+
+// UnaryServerInterceptor authenticates each request and checks its permissions.
+func (a *Authorizer) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id, err := a.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		err = a.authorize(id, request)
+		if err != nil {
+			return nil, err
+		}
+		return handler(withIdentity(ctx, id), request)
+	}
+}
+
+// StreamServerInterceptor authenticates each stream and checks the permissions of every request
+// received on it.
+func (a *Authorizer) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(server interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		id, err := a.authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(server, &authorizedStream{
+			ServerStream: stream,
+			ctx:          withIdentity(stream.Context(), id),
+			authorizer:   a,
+			identity:     id,
+		})
+	}
+}
+
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx        context.Context
+	authorizer *Authorizer
+	identity   *identity
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err != nil {
+		return err
+	}
+	return s.authorizer.authorize(s.identity, m)
+}
+
+// authenticate finds the identity of the bearer token in the authorization metadata.
+func (a *Authorizer) authenticate(ctx context.Context) (*identity, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	scheme, token := "", values[0]
+	if i := strings.IndexByte(token, ' '); i >= 0 {
+		scheme, token = token[:i], strings.TrimSpace(token[i+1:])
+	}
+	if !strings.EqualFold(scheme, "bearer") {
+		return nil, status.Error(codes.Unauthenticated, "authorization is not a bearer token")
+	}
+
+	auth := a.current()
+	if id, ok := auth.tokens[sha256.Sum256([]byte(token))]; ok {
+		return id, nil
+	}
+	if auth.jwt != nil && strings.Count(token, ".") == 2 {
+		id, err := auth.jwt.verify(token, time.Now())
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		return id, nil
+	}
+	return nil, status.Error(codes.Unauthenticated, "invalid bearer token")
+}
+
+type identityKey struct{}
+
+func withIdentity(ctx context.Context, id *identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, id)
+}
+
+// subject returns the subject a request was authenticated as, or nothing when requests are not
+// authenticated.
+func subject(ctx context.Context) string {
+	id, ok := ctx.Value(identityKey{}).(*identity)
+	if !ok {
+		return ""
+	}
+	return id.subject
+}
+
+// authorize checks that the roles of the identity allow everything the request does, and that the
+// leases it uses were granted to the identity.
+func (a *Authorizer) authorize(id *identity, request interface{}) error {
+	accesses, err := requestAccesses(request)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	for _, use := range requestLeases(request) {
+		owner, err := a.leases.LeaseOwner(use.id)
+		if errors.Cause(err) == datastore.ErrLeaseNotFound {
+			// the request fails on its own
+			continue
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if owner != id.subject {
+			return status.Error(codes.PermissionDenied, fmt.Sprintf("%s does not own lease %d", id.subject, use.id))
+		}
+		if use.keys == "" {
+			continue
+		}
+
+		ttl, err := a.leases.LeaseTimeToLive(&apiv1.LeaseTimeToLiveRequest{
+			Id:   use.id,
+			Keys: true,
+		})
+		if errors.Cause(err) == datastore.ErrLeaseNotFound {
+			continue
+		}
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		for _, key := range ttl.Keys {
+			accesses = append(accesses, keyAccess{permission: use.keys, key: key})
+		}
+	}
+
+	auth := a.current()
+	for _, access := range accesses {
+		if !auth.allows(id, access) {
+			return status.Error(codes.PermissionDenied, fmt.Sprintf("%s is not allowed to %s", id.subject, access))
+		}
+	}
+	return nil
+}
+
+func (a *auth) allows(id *identity, access keyAccess) bool {
+	for _, role := range id.roles {
+		for _, rule := range a.roles[role] {
+			if access.within(rule) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// keyAccess is a permission a request needs on a key, the keys under a prefix or a range of keys.
+// Access anywhere is allowed by the permission on any prefix.
+type keyAccess struct {
+	permission permission
+	key        string
+	prefix     bool
+	rangeEnd   string
+	anywhere   bool
+}
+
+func (k keyAccess) String() string {
+	switch {
+	case k.anywhere:
+		return fmt.Sprintf("%s leases", k.permission)
+	case k.rangeEnd != "":
+		return fmt.Sprintf("%s keys from %q to %q", k.permission, k.key, k.rangeEnd)
+	case k.prefix:
+		return fmt.Sprintf("%s keys under %q", k.permission, k.key)
+	}
+	return fmt.Sprintf("%s key %q", k.permission, k.key)
+}
+
+// within reports whether the rule grants the access.
+func (k keyAccess) within(rule RuleConfig) bool {
+	granted := false
+	for _, p := range rule.Permissions {
+		granted = granted || p == k.permission
+	}
+	if !granted {
+		return false
+	}
+
+	if k.anywhere || rule.Prefix == "" {
+		return true
+	}
+	if !strings.HasPrefix(k.key, rule.Prefix) {
+		return false
+	}
+	if k.rangeEnd == "" {
+		return true
+	}
+	// the range has to end before the first key after the prefix
+	end := []byte(rule.Prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return k.rangeEnd <= string(end[:i+1])
+		}
+	}
+	return true
+}
+
+// getAccess is the access needed to get the keys of a request.
+func getAccess(p permission, request *apiv1.GetValuesRequest_GetValue) (keyAccess, error) {
+	if request == nil {
+		return keyAccess{}, errors.New("request is required")
+	}
+	access := keyAccess{
+		permission: p,
+		key:        request.Key,
+		prefix:     request.IsPrefix,
+		rangeEnd:   request.RangeEnd,
+	}
+	if request.Pattern != nil && request.Key == "" {
+		prefix, err := datastore.PatternPrefix(request.Pattern)
+		if err != nil {
+			return keyAccess{}, err
+		}
+		access.key = prefix
+		access.prefix = true
+	}
+	return access, nil
+}
+
+// requestAccesses returns the accesses a request needs.
+func requestAccesses(request interface{}) ([]keyAccess, error) {
+	accesses := []keyAccess{}
+	gets := func(p permission, requests []*apiv1.GetValuesRequest_GetValue) error {
+		for _, r := range requests {
+			access, err := getAccess(p, r)
+			if err != nil {
+				return err
+			}
+			accesses = append(accesses, access)
+		}
+		return nil
+	}
+	preconditions := func(preconditions []*apiv1.Precondition) {
+		for _, p := range preconditions {
+			accesses = append(accesses, keyAccess{permission: permissionRead, key: p.Key})
+		}
+	}
+
+	switch r := request.(type) {
+	case *apiv1.GetValuesRequest:
+		return accesses, gets(permissionRead, r.Requests)
+	case *apiv1.ScanValuesRequest:
+		return accesses, gets(permissionRead, []*apiv1.GetValuesRequest_GetValue{r.Request})
+	case *apiv1.CountKeysRequest:
+		return accesses, gets(permissionRead, r.Requests)
+	case *apiv1.StatRequest:
+		return accesses, gets(permissionRead, r.Requests)
+	case *apiv1.GetHistoryRequest:
+		accesses = append(accesses, keyAccess{permission: permissionRead, key: r.Key})
+
+	case *apiv1.SetValuesRequest:
+		preconditions(r.Preconditions)
+		for _, m := range r.Messages {
+			accesses = append(accesses, keyAccess{permission: permissionWrite, key: m.Key})
+		}
+	case *apiv1.DeleteValuesRequest:
+		for _, d := range r.Requests {
+			accesses = append(accesses, keyAccess{permission: permissionWrite, key: d.Key, prefix: d.IsPrefix})
+		}
+	case *apiv1.IncrementRequest:
+		accesses = append(accesses, keyAccess{permission: permissionWrite, key: r.Key})
+	case *apiv1.TxnRequest:
+		preconditions(r.Compare)
+		for _, operation := range append(append([]*apiv1.TxnOperation{}, r.Success...), r.Failure...) {
+			switch op := operation.Operation.(type) {
+			case *apiv1.TxnOperation_Get:
+				err := gets(permissionRead, []*apiv1.GetValuesRequest_GetValue{op.Get})
+				if err != nil {
+					return nil, err
+				}
+			case *apiv1.TxnOperation_Set:
+				if op.Set.GetMessage() == nil {
+					return nil, errors.New("message is required")
+				}
+				accesses = append(accesses, keyAccess{permission: permissionWrite, key: op.Set.Message.Key})
+			case *apiv1.TxnOperation_Delete:
+				if op.Delete == nil {
+					return nil, errors.New("delete is required")
+				}
+				accesses = append(accesses, keyAccess{permission: permissionWrite, key: op.Delete.Key, prefix: op.Delete.IsPrefix})
+			default:
+				return nil, errors.New("unsupported transaction operation")
+			}
+		}
+
+	case *apiv1.SubscribeRequest:
+		for _, prefix := range r.Prefixes {
+			accesses = append(accesses, keyAccess{permission: permissionWatch, key: prefix, prefix: true})
+		}
+		for _, pattern := range r.Patterns {
+			prefix, err := datastore.PatternPrefix(pattern)
+			if err != nil {
+				return nil, err
+			}
+			accesses = append(accesses, keyAccess{permission: permissionWatch, key: prefix, prefix: true})
+		}
+
+	// leases are not tied to keys, so any role that can write somewhere can use them, as long as
+	// they were granted to it
+	case *apiv1.GrantLeaseRequest, *apiv1.KeepAliveRequest, *apiv1.RevokeLeaseRequest, *apiv1.LeaseTimeToLiveRequest:
+		accesses = append(accesses, keyAccess{permission: permissionWrite, anywhere: true})
+
+	case *apiv1.LockRequest:
+		accesses = append(accesses, keyAccess{permission: permissionWrite, key: r.Name + "/", prefix: true})
+	case *apiv1.UnlockRequest:
+		accesses = append(accesses, keyAccess{permission: permissionWrite, key: r.Key})
+	case *apiv1.CampaignRequest:
+		accesses = append(accesses, keyAccess{permission: permissionWrite, key: r.Name + "/", prefix: true})
+	case *apiv1.ResignRequest:
+		if r.Leader == nil {
+			return nil, errors.New("leader is required")
+		}
+		accesses = append(accesses, keyAccess{permission: permissionWrite, key: r.Leader.Key})
+	case *apiv1.ObserveRequest:
+		accesses = append(accesses, keyAccess{permission: permissionWatch, key: r.Name + "/", prefix: true})
+
+	default:
+		return nil, errors.Errorf("unsupported request %T", request)
+	}
+	return accesses, nil
+}
+
+// leaseUse is a lease a request uses, and the permission it needs on the keys attached to the lease
+// when it uses them too.
+type leaseUse struct {
+	id   int64
+	keys permission
+}
+
+// requestLeases returns the leases a request uses.
+func requestLeases(request interface{}) []leaseUse {
+	uses := []leaseUse{}
+	use := func(id int64, keys permission) {
+		if id != 0 {
+			uses = append(uses, leaseUse{id: id, keys: keys})
+		}
+	}
+
+	switch r := request.(type) {
+	case *apiv1.SetValuesRequest:
+		use(r.Lease, "")
+	case *apiv1.TxnRequest:
+		for _, operation := range append(append([]*apiv1.TxnOperation{}, r.Success...), r.Failure...) {
+			if op, ok := operation.Operation.(*apiv1.TxnOperation_Set); ok && op.Set != nil {
+				use(op.Set.Lease, "")
+			}
+		}
+	case *apiv1.KeepAliveRequest:
+		use(r.Id, "")
+	case *apiv1.RevokeLeaseRequest:
+		// revoking deletes the keys attached to the lease
+		use(r.Id, permissionWrite)
+	case *apiv1.LeaseTimeToLiveRequest:
+		if r.Keys {
+			use(r.Id, permissionRead)
+		} else {
+			use(r.Id, "")
+		}
+	case *apiv1.LockRequest:
+		use(r.Lease, "")
+	case *apiv1.UnlockRequest:
+		use(r.Lease, "")
+	case *apiv1.CampaignRequest:
+		use(r.Lease, "")
+	}
+	return uses
+}
@@ -0,0 +1,467 @@
+package services_test
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/syncromatics/kvetch/internal/datastore"
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+	services "github.com/syncromatics/kvetch/internal/sevices"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"gotest.tools/assert"
+)
+
+const authRoles = `{
+	"reader": [{ "prefix": "app/", "permissions": ["read"] }],
+	"writer": [{ "prefix": "app/", "permissions": ["read", "write", "watch"] }],
+	"leases": [{ "prefix": "leases/", "permissions": ["write"] }]
+}`
+
+// newAuthorizer writes the auth config, along with the json web token key when there is one, and
+// creates an authorizer with it and the datastore its leases are looked up in.
+func newAuthorizer(t *testing.T, config string, jwtKey []byte) (*services.Authorizer, *datastore.KVStore) {
+	tmpDir, err := ioutil.TempDir("", "Test_Authorizer")
+	assert.NilError(t, err)
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	store, err := datastore.NewKVStore(filepath.Join(tmpDir, "data"), &datastore.KVStoreOptions{})
+	assert.NilError(t, err)
+	t.Cleanup(func() { store.Close() })
+
+	if jwtKey != nil {
+		err = ioutil.WriteFile(filepath.Join(tmpDir, "jwt.pem"), jwtKey, 0600)
+		assert.NilError(t, err)
+	}
+	path := filepath.Join(tmpDir, "auth.json")
+	err = ioutil.WriteFile(path, []byte(config), 0600)
+	assert.NilError(t, err)
+	authorizer, err := services.NewAuthorizer(path, store)
+	assert.NilError(t, err)
+	return authorizer, store
+}
+
+func withToken(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+}
+
+// authorize runs the request through the unary interceptor and returns the code it ended with.
+func authorize(authorizer *services.Authorizer, token string, request interface{}) codes.Code {
+	_, err := authorizer.UnaryServerInterceptor()(withToken(token), request, &grpc.UnaryServerInfo{}, func(context.Context, interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	return status.Code(err)
+}
+
+// signJWT creates a token with the algorithm and claims, signed with the key.
+func signJWT(t *testing.T, algorithm string, key interface{}, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": algorithm, "typ": "JWT"})
+	assert.NilError(t, err)
+	payload, err := json.Marshal(claims)
+	assert.NilError(t, err)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signed))
+	var signature []byte
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		assert.NilError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		assert.NilError(t, err)
+		signature = make([]byte, 64)
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func publicKeyPEM(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	assert.NilError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func Test_JWTAuthentication(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NilError(t, err)
+	secret := []byte("a shared secret")
+
+	config := `{
+		"jwt": { "key_file": "jwt.pem", "issuer": "issuer", "audience": "kvetch" },
+		"roles": ` + authRoles + `
+	}`
+	now := time.Now()
+	claims := func(changes map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"sub":   "alice",
+			"iss":   "issuer",
+			"aud":   "kvetch",
+			"exp":   now.Add(time.Hour).Unix(),
+			"nbf":   now.Add(-time.Hour).Unix(),
+			"roles": []string{"reader"},
+		}
+		for claim, value := range changes {
+			if value == nil {
+				delete(claims, claim)
+				continue
+			}
+			claims[claim] = value
+		}
+		return claims
+	}
+	get := &apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{Key: "app/a"},
+		},
+	}
+
+	tests := []struct {
+		name  string
+		key   []byte
+		token func() string
+		code  codes.Code
+	}{
+		{"hmac", secret, func() string { return signJWT(t, "HS256", secret, claims(nil)) }, codes.OK},
+		{"rsa", publicKeyPEM(t, &rsaKey.PublicKey), func() string { return signJWT(t, "RS256", rsaKey, claims(nil)) }, codes.OK},
+		{"ecdsa", publicKeyPEM(t, &ecdsaKey.PublicKey), func() string { return signJWT(t, "ES256", ecdsaKey, claims(nil)) }, codes.OK},
+		{"audience list", secret, func() string {
+			return signJWT(t, "HS256", secret, claims(map[string]interface{}{"aud": []string{"other", "kvetch"}}))
+		}, codes.OK},
+		{"roles without permission", secret, func() string {
+			return signJWT(t, "HS256", secret, claims(map[string]interface{}{"roles": "leases"}))
+		}, codes.PermissionDenied},
+
+		{"rsa key signed as hmac", publicKeyPEM(t, &rsaKey.PublicKey), func() string {
+			return signJWT(t, "HS256", publicKeyPEM(t, &rsaKey.PublicKey), claims(nil))
+		}, codes.Unauthenticated},
+		{"hmac key with rsa algorithm", secret, func() string { return signJWT(t, "RS256", rsaKey, claims(nil)) }, codes.Unauthenticated},
+		{"ecdsa key with rsa algorithm", publicKeyPEM(t, &ecdsaKey.PublicKey), func() string { return signJWT(t, "RS256", rsaKey, claims(nil)) }, codes.Unauthenticated},
+		{"rsa key with ecdsa algorithm", publicKeyPEM(t, &rsaKey.PublicKey), func() string { return signJWT(t, "ES256", ecdsaKey, claims(nil)) }, codes.Unauthenticated},
+		{"alg none", secret, func() string {
+			token := signJWT(t, "none", secret, claims(nil))
+			return token[:len(token)-43]
+		}, codes.Unauthenticated},
+		{"bad signature", secret, func() string { return signJWT(t, "HS256", []byte("another secret"), claims(nil)) }, codes.Unauthenticated},
+		{"tampered claims", secret, func() string {
+			token := signJWT(t, "HS256", secret, claims(nil))
+			other := signJWT(t, "HS256", secret, claims(map[string]interface{}{"roles": []string{"writer"}}))
+			return token[:36] + other[36:len(other)-43] + token[len(token)-43:]
+		}, codes.Unauthenticated},
+
+		{"expired", secret, func() string {
+			return signJWT(t, "HS256", secret, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()}))
+		}, codes.Unauthenticated},
+		{"expiry not a number", secret, func() string {
+			return signJWT(t, "HS256", secret, claims(map[string]interface{}{"exp": "tomorrow"}))
+		}, codes.Unauthenticated},
+		{"without expiry", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"exp": nil})) }, codes.OK},
+		{"not valid yet", secret, func() string {
+			return signJWT(t, "HS256", secret, claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()}))
+		}, codes.Unauthenticated},
+		{"not before not a number", secret, func() string {
+			return signJWT(t, "HS256", secret, claims(map[string]interface{}{"nbf": "yesterday"}))
+		}, codes.Unauthenticated},
+		{"wrong issuer", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"iss": "other"})) }, codes.Unauthenticated},
+		{"without issuer", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"iss": nil})) }, codes.Unauthenticated},
+		{"wrong audience", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"aud": "other"})) }, codes.Unauthenticated},
+		{"without audience", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"aud": nil})) }, codes.Unauthenticated},
+		{"without subject", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"sub": nil})) }, codes.Unauthenticated},
+		{"empty subject", secret, func() string { return signJWT(t, "HS256", secret, claims(map[string]interface{}{"sub": ""})) }, codes.Unauthenticated},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			authorizer, _ := newAuthorizer(t, config, test.key)
+
+			assert.Equal(t, authorize(authorizer, test.token(), get), test.code)
+		})
+	}
+}
+
+func Test_TokenAuthentication(t *testing.T) {
+	authorizer, _ := newAuthorizer(t, `{
+		"tokens": [
+			{ "token": "reader-token", "subject": "reader", "roles": ["reader"] },
+			{ "token": "writer-token", "subject": "writer", "roles": ["writer"] }
+		],
+		"roles": `+authRoles+`
+	}`, nil)
+
+	get := &apiv1.GetValuesRequest{
+		Requests: []*apiv1.GetValuesRequest_GetValue{
+			&apiv1.GetValuesRequest_GetValue{Key: "app/a"},
+		},
+	}
+	// tokens are looked up by their hash, so only the exact token authenticates
+	assert.Equal(t, authorize(authorizer, "reader-token", get), codes.OK)
+	assert.Equal(t, authorize(authorizer, "writer-token", get), codes.OK)
+	assert.Equal(t, authorize(authorizer, "reader-toke", get), codes.Unauthenticated)
+	assert.Equal(t, authorize(authorizer, "reader-token ", get), codes.OK)
+	assert.Equal(t, authorize(authorizer, "Reader-token", get), codes.Unauthenticated)
+	assert.Equal(t, authorize(authorizer, "", get), codes.Unauthenticated)
+
+	// requests without a bearer token
+	_, err := authorizer.UnaryServerInterceptor()(context.Background(), get, &grpc.UnaryServerInfo{}, nil)
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+	basic := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Basic reader-token"))
+	_, err = authorizer.UnaryServerInterceptor()(basic, get, &grpc.UnaryServerInfo{}, nil)
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+
+	// a token that is empty would let any request with an empty token in
+	path := authorizer.Files()[0]
+	err = ioutil.WriteFile(path, []byte(`{ "tokens": [{ "token": "", "subject": "nobody" }] }`), 0600)
+	assert.NilError(t, err)
+	assert.ErrorContains(t, authorizer.Load(), "empty")
+	assert.Equal(t, authorize(authorizer, "reader-token", get), codes.OK)
+}
+
+func Test_AccessControl(t *testing.T) {
+	authorizer, _ := newAuthorizer(t, `{
+		"tokens": [
+			{ "token": "reader-token", "subject": "reader", "roles": ["reader"] },
+			{ "token": "writer-token", "subject": "writer", "roles": ["writer"] }
+		],
+		"roles": `+authRoles+`
+	}`, nil)
+
+	get := func(request *apiv1.GetValuesRequest_GetValue) *apiv1.GetValuesRequest {
+		return &apiv1.GetValuesRequest{Requests: []*apiv1.GetValuesRequest_GetValue{request}}
+	}
+	set := func(key string) *apiv1.TxnOperation {
+		return &apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Set{Set: &apiv1.TxnOperation_SetValue{
+			Message: &apiv1.KeyValue{Key: key},
+		}}}
+	}
+	txnGet := func(key string) *apiv1.TxnOperation {
+		return &apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Get{Get: &apiv1.GetValuesRequest_GetValue{Key: key}}}
+	}
+	txnDelete := func(key string, prefix bool) *apiv1.TxnOperation {
+		return &apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Delete{Delete: &apiv1.DeleteValuesRequest_DeleteValue{Key: key, IsPrefix: prefix}}}
+	}
+	deletes := func(key string, prefix bool) *apiv1.DeleteValuesRequest {
+		return &apiv1.DeleteValuesRequest{Requests: []*apiv1.DeleteValuesRequest_DeleteValue{
+			&apiv1.DeleteValuesRequest_DeleteValue{Key: key, IsPrefix: prefix},
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		request interface{}
+		code    codes.Code
+	}{
+		{"get key", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "app/a"}), codes.OK},
+		{"get key outside prefix", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "other/a"}), codes.PermissionDenied},
+		{"get prefix", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "app/", IsPrefix: true}), codes.OK},
+		{"get shorter prefix", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "app", IsPrefix: true}), codes.PermissionDenied},
+		{"get range within prefix", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "app/a", RangeEnd: "app0"}), codes.OK},
+		{"get range past prefix", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "app/a", RangeEnd: "app1"}), codes.PermissionDenied},
+		{"get range before prefix", "reader-token", get(&apiv1.GetValuesRequest_GetValue{Key: "aa", RangeEnd: "app/b"}), codes.PermissionDenied},
+
+		{"set without write", "reader-token", &apiv1.SetValuesRequest{Messages: []*apiv1.KeyValue{&apiv1.KeyValue{Key: "app/a"}}}, codes.PermissionDenied},
+		{"set", "writer-token", &apiv1.SetValuesRequest{Messages: []*apiv1.KeyValue{&apiv1.KeyValue{Key: "app/a"}}}, codes.OK},
+		{"set with precondition outside prefix", "writer-token", &apiv1.SetValuesRequest{
+			Messages:      []*apiv1.KeyValue{&apiv1.KeyValue{Key: "app/a"}},
+			Preconditions: []*apiv1.Precondition{&apiv1.Precondition{Key: "other/a"}},
+		}, codes.PermissionDenied},
+
+		{"delete key", "writer-token", deletes("app/a", false), codes.OK},
+		{"delete prefix", "writer-token", deletes("app/", true), codes.OK},
+		{"delete shorter prefix", "writer-token", deletes("ap", true), codes.PermissionDenied},
+		{"delete everything", "writer-token", deletes("", true), codes.PermissionDenied},
+		{"delete without write", "reader-token", deletes("app/a", false), codes.PermissionDenied},
+
+		{"txn", "writer-token", &apiv1.TxnRequest{
+			Success: []*apiv1.TxnOperation{txnGet("app/a"), set("app/b")},
+			Failure: []*apiv1.TxnOperation{txnDelete("app/", true)},
+		}, codes.OK},
+		{"txn set without write", "reader-token", &apiv1.TxnRequest{Success: []*apiv1.TxnOperation{txnGet("app/a"), set("app/b")}}, codes.PermissionDenied},
+		{"txn failure outside prefix", "writer-token", &apiv1.TxnRequest{
+			Success: []*apiv1.TxnOperation{set("app/b")},
+			Failure: []*apiv1.TxnOperation{set("other/b")},
+		}, codes.PermissionDenied},
+		{"txn delete prefix past prefix", "writer-token", &apiv1.TxnRequest{Success: []*apiv1.TxnOperation{txnDelete("ap", true)}}, codes.PermissionDenied},
+		{"txn compare outside prefix", "writer-token", &apiv1.TxnRequest{
+			Compare: []*apiv1.Precondition{&apiv1.Precondition{Key: "other/a"}},
+			Success: []*apiv1.TxnOperation{set("app/b")},
+		}, codes.PermissionDenied},
+		{"txn set without message", "writer-token", &apiv1.TxnRequest{Success: []*apiv1.TxnOperation{
+			&apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Set{Set: &apiv1.TxnOperation_SetValue{}}},
+		}}, codes.InvalidArgument},
+
+		{"subscribe without watch", "reader-token", &apiv1.SubscribeRequest{Prefixes: []string{"app/"}}, codes.PermissionDenied},
+		{"subscribe", "writer-token", &apiv1.SubscribeRequest{Prefixes: []string{"app/"}}, codes.OK},
+		{"subscribe outside prefix", "writer-token", &apiv1.SubscribeRequest{Prefixes: []string{"app/", "other/"}}, codes.PermissionDenied},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, authorize(authorizer, test.token, test.request), test.code)
+		})
+	}
+}
+
+func Test_LeaseOwnership(t *testing.T) {
+	authorizer, store := newAuthorizer(t, `{
+		"tokens": [
+			{ "token": "alice-token", "subject": "alice", "roles": ["writer", "leases"] },
+			{ "token": "alice-leases-token", "subject": "alice", "roles": ["leases"] },
+			{ "token": "bob-token", "subject": "bob", "roles": ["writer", "leases"] }
+		],
+		"roles": `+authRoles+`
+	}`, nil)
+	api := services.Intercept(services.NewAPIService(store), authorizer.UnaryServerInterceptor(), authorizer.StreamServerInterceptor())
+
+	grant := func(token string) int64 {
+		response, err := api.GrantLease(withToken(token), &apiv1.GrantLeaseRequest{
+			TtlDuration: ptypes.DurationProto(time.Minute),
+		})
+		assert.NilError(t, err)
+		return response.Id
+	}
+	lease := grant("alice-token")
+	owner, err := store.LeaseOwner(lease)
+	assert.NilError(t, err)
+	assert.Equal(t, owner, "alice")
+
+	// only the owner attaches keys to the lease
+	setWithLease := func(token string) error {
+		_, err := api.SetValues(withToken(token), &apiv1.SetValuesRequest{
+			Messages: []*apiv1.KeyValue{&apiv1.KeyValue{Key: "app/a", Value: []byte("a")}},
+			Lease:    lease,
+		})
+		return err
+	}
+	assert.Equal(t, status.Code(setWithLease("bob-token")), codes.PermissionDenied)
+	assert.NilError(t, setWithLease("alice-token"))
+
+	txn := &apiv1.TxnRequest{Success: []*apiv1.TxnOperation{
+		&apiv1.TxnOperation{Operation: &apiv1.TxnOperation_Set{Set: &apiv1.TxnOperation_SetValue{
+			Message: &apiv1.KeyValue{Key: "app/b", Value: []byte("b")},
+			Lease:   lease,
+		}}},
+	}}
+	_, err = api.Txn(withToken("bob-token"), txn)
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = api.Lock(withToken("bob-token"), &apiv1.LockRequest{Name: "app/lock", Lease: lease})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = api.Campaign(withToken("bob-token"), &apiv1.CampaignRequest{Name: "app/election", Lease: lease})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = api.Unlock(withToken("bob-token"), &apiv1.UnlockRequest{Key: "app/lock/key", Lease: lease})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+
+	// lease requests are only allowed for the owner, and need access to the attached keys when
+	// they use them
+	tests := []struct {
+		name    string
+		token   string
+		request interface{}
+		code    codes.Code
+	}{
+		{"time to live", "alice-leases-token", &apiv1.LeaseTimeToLiveRequest{Id: lease}, codes.OK},
+		{"time to live of another's lease", "bob-token", &apiv1.LeaseTimeToLiveRequest{Id: lease}, codes.PermissionDenied},
+		{"keys without read", "alice-leases-token", &apiv1.LeaseTimeToLiveRequest{Id: lease, Keys: true}, codes.PermissionDenied},
+		{"keys", "alice-token", &apiv1.LeaseTimeToLiveRequest{Id: lease, Keys: true}, codes.OK},
+		{"keep alive", "alice-leases-token", &apiv1.KeepAliveRequest{Id: lease}, codes.OK},
+		{"keep alive another's lease", "bob-token", &apiv1.KeepAliveRequest{Id: lease}, codes.PermissionDenied},
+		{"revoke without write", "alice-leases-token", &apiv1.RevokeLeaseRequest{Id: lease}, codes.PermissionDenied},
+		{"revoke another's lease", "bob-token", &apiv1.RevokeLeaseRequest{Id: lease}, codes.PermissionDenied},
+		{"revoke", "alice-token", &apiv1.RevokeLeaseRequest{Id: lease}, codes.OK},
+		{"missing lease", "bob-token", &apiv1.RevokeLeaseRequest{Id: lease + 100}, codes.OK},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, authorize(authorizer, test.token, test.request), test.code)
+		})
+	}
+
+	_, err = api.RevokeLease(withToken("bob-token"), &apiv1.RevokeLeaseRequest{Id: lease})
+	assert.Equal(t, status.Code(err), codes.PermissionDenied)
+	_, err = api.RevokeLease(withToken("alice-token"), &apiv1.RevokeLeaseRequest{Id: lease})
+	assert.NilError(t, err)
+}
+
+// keepAliveStream receives the requests queued on it.
+type keepAliveStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	requests []*apiv1.KeepAliveRequest
+}
+
+func (s *keepAliveStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *keepAliveStream) RecvMsg(m interface{}) error {
+	request := s.requests[0]
+	s.requests = s.requests[1:]
+	proto.Merge(m.(proto.Message), request)
+	return nil
+}
+
+func Test_KeepAliveStreamAuthorization(t *testing.T) {
+	authorizer, store := newAuthorizer(t, `{
+		"tokens": [
+			{ "token": "alice-token", "subject": "alice", "roles": ["leases"] },
+			{ "token": "bob-token", "subject": "bob", "roles": ["leases"] }
+		],
+		"roles": `+authRoles+`
+	}`, nil)
+
+	alice, err := store.GrantLease(&apiv1.GrantLeaseRequest{TtlDuration: ptypes.DurationProto(time.Minute)}, "alice")
+	assert.NilError(t, err)
+	bob, err := store.GrantLease(&apiv1.GrantLeaseRequest{TtlDuration: ptypes.DurationProto(time.Minute)}, "bob")
+	assert.NilError(t, err)
+
+	// every request received on the stream is authorized, not only the first
+	stream := &keepAliveStream{
+		ctx: withToken("bob-token"),
+		requests: []*apiv1.KeepAliveRequest{
+			&apiv1.KeepAliveRequest{Id: bob.Id},
+			&apiv1.KeepAliveRequest{Id: alice.Id},
+		},
+	}
+	received := []error{}
+	err = authorizer.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(server interface{}, stream grpc.ServerStream) error {
+		for i := 0; i < 2; i++ {
+			received = append(received, stream.RecvMsg(&apiv1.KeepAliveRequest{}))
+		}
+		return nil
+	})
+	assert.NilError(t, err)
+	assert.NilError(t, received[0])
+	assert.Equal(t, status.Code(received[1]), codes.PermissionDenied)
+
+	// the stream is not opened without a valid token
+	stream = &keepAliveStream{ctx: withToken("nobody-token")}
+	err = authorizer.StreamServerInterceptor()(nil, stream, &grpc.StreamServerInfo{}, func(interface{}, grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, status.Code(err), codes.Unauthenticated)
+}
@@ -0,0 +1,47 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// ExpirySweeper records the expiration of keys whose ttl has lapsed
+type ExpirySweeper interface {
+	SweepExpired() error
+}
+
+// ExpirySweeperService sweeps expired keys on an interval
+type ExpirySweeperService struct {
+	sweeper  ExpirySweeper
+	interval time.Duration
+}
+
+// NewExpirySweeperService creates a new expiry sweeper service
+func NewExpirySweeperService(sweeper ExpirySweeper, interval time.Duration) *ExpirySweeperService {
+	return &ExpirySweeperService{
+		sweeper:  sweeper,
+		interval: interval,
+	}
+}
+
+// Run runs the expiry sweeper service. Failed sweeps are logged and tried again on the next tick
+// instead of stopping the service.
+func (s ExpirySweeperService) Run(ctx context.Context) func() error {
+	return func() error {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case <-ticker.C:
+				err := s.sweeper.SweepExpired()
+				if err != nil {
+					log.Printf("failed SweepExpired: %v", err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+
+	apiv1 "github.com/syncromatics/kvetch/internal/protos/kvetch/api/v1"
+
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc"
+)
+
+const apiMethodPrefix = "/kvetch.api.v1.API/"
+
+var _ apiv1.APIServer = &interceptedAPI{}
+
+// interceptedAPI runs every method of the api through grpc interceptors.
+type interceptedAPI struct {
+	server            apiv1.APIServer
+	unaryInterceptor  grpc.UnaryServerInterceptor
+	streamInterceptor grpc.StreamServerInterceptor
+}
+
+// Intercept runs every method of the api through the interceptors the same way a grpc server
+// created with them would. The go-kit server does not take server options, so interceptors are
+// applied to the api instead.
+func Intercept(server apiv1.APIServer, unary grpc.UnaryServerInterceptor, stream grpc.StreamServerInterceptor) apiv1.APIServer {
+	return &interceptedAPI{
+		server:            server,
+		unaryInterceptor:  unary,
+		streamInterceptor: stream,
+	}
+}
+
+func (s *interceptedAPI) unary(ctx context.Context, method string, request interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	return s.unaryInterceptor(ctx, request, &grpc.UnaryServerInfo{
+		Server:     s.server,
+		FullMethod: apiMethodPrefix + method,
+	}, handler)
+}
+
+// serverStream runs a server streaming method whose request has already been received. The
+// interceptor receives it again from the stream.
+func (s *interceptedAPI) serverStream(stream grpc.ServerStream, method string, request proto.Message, handler grpc.StreamHandler) error {
+	return s.streamInterceptor(s.server, &receivedStream{
+		ServerStream: stream,
+		received:     request,
+	}, &grpc.StreamServerInfo{
+		FullMethod:     apiMethodPrefix + method,
+		IsServerStream: true,
+	}, handler)
+}
+
+// receivedStream returns a request that was already received before receiving from the stream.
+type receivedStream struct {
+	grpc.ServerStream
+	received proto.Message
+}
+
+func (s *receivedStream) RecvMsg(m interface{}) error {
+	if s.received == nil {
+		return s.ServerStream.RecvMsg(m)
+	}
+	proto.Merge(m.(proto.Message), s.received)
+	s.received = nil
+	return nil
+}
+
+func (s *interceptedAPI) SetValues(ctx context.Context, request *apiv1.SetValuesRequest) (*apiv1.SetValuesResponse, error) {
+	response, err := s.unary(ctx, "SetValues", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.SetValues(ctx, request.(*apiv1.SetValuesRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.SetValuesResponse), nil
+}
+
+func (s *interceptedAPI) GetValues(ctx context.Context, request *apiv1.GetValuesRequest) (*apiv1.GetValuesResponse, error) {
+	response, err := s.unary(ctx, "GetValues", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.GetValues(ctx, request.(*apiv1.GetValuesRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.GetValuesResponse), nil
+}
+
+func (s *interceptedAPI) ScanValues(request *apiv1.ScanValuesRequest, stream apiv1.API_ScanValuesServer) error {
+	return s.serverStream(stream, "ScanValues", request, func(_ interface{}, stream grpc.ServerStream) error {
+		request := &apiv1.ScanValuesRequest{}
+		err := stream.RecvMsg(request)
+		if err != nil {
+			return err
+		}
+		return s.server.ScanValues(request, &scanValuesServer{stream})
+	})
+}
+
+func (s *interceptedAPI) CountKeys(ctx context.Context, request *apiv1.CountKeysRequest) (*apiv1.CountKeysResponse, error) {
+	response, err := s.unary(ctx, "CountKeys", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.CountKeys(ctx, request.(*apiv1.CountKeysRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.CountKeysResponse), nil
+}
+
+func (s *interceptedAPI) Stat(ctx context.Context, request *apiv1.StatRequest) (*apiv1.StatResponse, error) {
+	response, err := s.unary(ctx, "Stat", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Stat(ctx, request.(*apiv1.StatRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.StatResponse), nil
+}
+
+func (s *interceptedAPI) GetHistory(ctx context.Context, request *apiv1.GetHistoryRequest) (*apiv1.GetHistoryResponse, error) {
+	response, err := s.unary(ctx, "GetHistory", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.GetHistory(ctx, request.(*apiv1.GetHistoryRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.GetHistoryResponse), nil
+}
+
+func (s *interceptedAPI) Subscribe(request *apiv1.SubscribeRequest, stream apiv1.API_SubscribeServer) error {
+	return s.serverStream(stream, "Subscribe", request, func(_ interface{}, stream grpc.ServerStream) error {
+		request := &apiv1.SubscribeRequest{}
+		err := stream.RecvMsg(request)
+		if err != nil {
+			return err
+		}
+		return s.server.Subscribe(request, &subscribeServer{stream})
+	})
+}
+
+func (s *interceptedAPI) DeleteValues(ctx context.Context, request *apiv1.DeleteValuesRequest) (*apiv1.DeleteValuesResponse, error) {
+	response, err := s.unary(ctx, "DeleteValues", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.DeleteValues(ctx, request.(*apiv1.DeleteValuesRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.DeleteValuesResponse), nil
+}
+
+func (s *interceptedAPI) Increment(ctx context.Context, request *apiv1.IncrementRequest) (*apiv1.IncrementResponse, error) {
+	response, err := s.unary(ctx, "Increment", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Increment(ctx, request.(*apiv1.IncrementRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.IncrementResponse), nil
+}
+
+func (s *interceptedAPI) Txn(ctx context.Context, request *apiv1.TxnRequest) (*apiv1.TxnResponse, error) {
+	response, err := s.unary(ctx, "Txn", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Txn(ctx, request.(*apiv1.TxnRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.TxnResponse), nil
+}
+
+func (s *interceptedAPI) GrantLease(ctx context.Context, request *apiv1.GrantLeaseRequest) (*apiv1.GrantLeaseResponse, error) {
+	response, err := s.unary(ctx, "GrantLease", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.GrantLease(ctx, request.(*apiv1.GrantLeaseRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.GrantLeaseResponse), nil
+}
+
+func (s *interceptedAPI) KeepAlive(stream apiv1.API_KeepAliveServer) error {
+	return s.streamInterceptor(s.server, stream, &grpc.StreamServerInfo{
+		FullMethod:     apiMethodPrefix + "KeepAlive",
+		IsClientStream: true,
+		IsServerStream: true,
+	}, func(_ interface{}, stream grpc.ServerStream) error {
+		return s.server.KeepAlive(&keepAliveServer{stream})
+	})
+}
+
+func (s *interceptedAPI) RevokeLease(ctx context.Context, request *apiv1.RevokeLeaseRequest) (*apiv1.RevokeLeaseResponse, error) {
+	response, err := s.unary(ctx, "RevokeLease", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.RevokeLease(ctx, request.(*apiv1.RevokeLeaseRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.RevokeLeaseResponse), nil
+}
+
+func (s *interceptedAPI) LeaseTimeToLive(ctx context.Context, request *apiv1.LeaseTimeToLiveRequest) (*apiv1.LeaseTimeToLiveResponse, error) {
+	response, err := s.unary(ctx, "LeaseTimeToLive", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.LeaseTimeToLive(ctx, request.(*apiv1.LeaseTimeToLiveRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.LeaseTimeToLiveResponse), nil
+}
+
+func (s *interceptedAPI) Lock(ctx context.Context, request *apiv1.LockRequest) (*apiv1.LockResponse, error) {
+	response, err := s.unary(ctx, "Lock", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Lock(ctx, request.(*apiv1.LockRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.LockResponse), nil
+}
+
+func (s *interceptedAPI) Unlock(ctx context.Context, request *apiv1.UnlockRequest) (*apiv1.UnlockResponse, error) {
+	response, err := s.unary(ctx, "Unlock", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Unlock(ctx, request.(*apiv1.UnlockRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.UnlockResponse), nil
+}
+
+func (s *interceptedAPI) Campaign(ctx context.Context, request *apiv1.CampaignRequest) (*apiv1.CampaignResponse, error) {
+	response, err := s.unary(ctx, "Campaign", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Campaign(ctx, request.(*apiv1.CampaignRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.CampaignResponse), nil
+}
+
+func (s *interceptedAPI) Resign(ctx context.Context, request *apiv1.ResignRequest) (*apiv1.ResignResponse, error) {
+	response, err := s.unary(ctx, "Resign", request, func(ctx context.Context, request interface{}) (interface{}, error) {
+		return s.server.Resign(ctx, request.(*apiv1.ResignRequest))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.(*apiv1.ResignResponse), nil
+}
+
+func (s *interceptedAPI) Observe(request *apiv1.ObserveRequest, stream apiv1.API_ObserveServer) error {
+	return s.serverStream(stream, "Observe", request, func(_ interface{}, stream grpc.ServerStream) error {
+		request := &apiv1.ObserveRequest{}
+		err := stream.RecvMsg(request)
+		if err != nil {
+			return err
+		}
+		return s.server.Observe(request, &observeServer{stream})
+	})
+}
+
+type scanValuesServer struct {
+	grpc.ServerStream
+}
+
+func (s *scanValuesServer) Send(m *apiv1.ScanValuesResponse) error {
+	return s.SendMsg(m)
+}
+
+type subscribeServer struct {
+	grpc.ServerStream
+}
+
+func (s *subscribeServer) Send(m *apiv1.SubscribeResponse) error {
+	return s.SendMsg(m)
+}
+
+type observeServer struct {
+	grpc.ServerStream
+}
+
+func (s *observeServer) Send(m *apiv1.ObserveResponse) error {
+	return s.SendMsg(m)
+}
+
+type keepAliveServer struct {
+	grpc.ServerStream
+}
+
+func (s *keepAliveServer) Send(m *apiv1.KeepAliveResponse) error {
+	return s.SendMsg(m)
+}
+
+func (s *keepAliveServer) Recv() (*apiv1.KeepAliveRequest, error) {
+	m := &apiv1.KeepAliveRequest{}
+	err := s.RecvMsg(m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
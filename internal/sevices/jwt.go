@@ -0,0 +1,228 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers the hashes for crypto.Hash
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// jwtVerifier verifies json web tokens signed with a key from a local file: an hmac secret, or a
+// pem encoded rsa or ecdsa public key.
+type jwtVerifier struct {
+	key        interface{}
+	issuer     string
+	audience   string
+	rolesClaim string
+}
+
+// parseJWTKey reads an rsa or ecdsa public key from pem, or uses the bytes as an hmac secret when
+// they are not pem encoded.
+func parseJWTKey(data []byte) (interface{}, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		secret := []byte(strings.TrimSpace(string(data)))
+		if len(secret) == 0 {
+			return nil, errors.New("jwt secret is empty")
+		}
+		return secret, nil
+	}
+
+	if block.Type == "CERTIFICATE" {
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to parse jwt certificate")
+		}
+		return certificate.PublicKey, nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse jwt public key")
+	}
+	return key, nil
+}
+
+var jwtHashes = map[string]crypto.Hash{
+	"256": crypto.SHA256,
+	"384": crypto.SHA384,
+	"512": crypto.SHA512,
+}
+
+// verify checks the signature and the time, issuer and audience claims of a token and returns
+// its identity, which needs a subject.
+func (v *jwtVerifier) verify(token string, now time.Time) (*identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed token")
+	}
+
+	var header struct {
+		Algorithm string `json:"alg"`
+	}
+	err := decodeJWTPart(parts[0], &header)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token signature")
+	}
+	err = v.verifySignature(header.Algorithm, []byte(parts[0]+"."+parts[1]), signature)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	err = decodeJWTPart(parts[1], &claims)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid token claims")
+	}
+
+	exp, err := timeClaim(claims, "exp")
+	if err != nil {
+		return nil, err
+	}
+	if exp != nil && !now.Before(*exp) {
+		return nil, errors.New("token has expired")
+	}
+	nbf, err := timeClaim(claims, "nbf")
+	if err != nil {
+		return nil, err
+	}
+	if nbf != nil && now.Before(*nbf) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if v.issuer != "" && claims["iss"] != v.issuer {
+		return nil, errors.New("token has the wrong issuer")
+	}
+	if v.audience != "" && !contains(stringsClaim(claims["aud"]), v.audience) {
+		return nil, errors.New("token has the wrong audience")
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, errors.New("token has no subject")
+	}
+	return &identity{
+		subject: subject,
+		roles:   stringsClaim(claims[v.rolesClaim]),
+	}, nil
+}
+
+// verifySignature checks the signature with the key, only allowing the algorithms for its type so
+// a public key is never used as an hmac secret.
+func (v *jwtVerifier) verifySignature(algorithm string, signed, signature []byte) error {
+	if len(algorithm) != 5 {
+		return errors.Errorf("unsupported token algorithm %q", algorithm)
+	}
+	hash, ok := jwtHashes[algorithm[2:]]
+	if !ok {
+		return errors.Errorf("unsupported token algorithm %q", algorithm)
+	}
+	h := hash.New()
+	h.Write(signed)
+	digest := h.Sum(nil)
+
+	switch key := v.key.(type) {
+	case []byte:
+		if algorithm[:2] != "HS" {
+			return errors.Errorf("token algorithm %q does not match the hmac key", algorithm)
+		}
+		mac := hmac.New(hash.New, key)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+
+	case *rsa.PublicKey:
+		if algorithm[:2] != "RS" {
+			return errors.Errorf("token algorithm %q does not match the rsa key", algorithm)
+		}
+		err := rsa.VerifyPKCS1v15(key, hash, digest, signature)
+		if err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+
+	case *ecdsa.PublicKey:
+		if algorithm[:2] != "ES" {
+			return errors.Errorf("token algorithm %q does not match the ecdsa key", algorithm)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid token signature")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+
+	return errors.New("unsupported jwt key type")
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// timeClaim reads a claim of seconds since the epoch, or nil when the token does not have it.
+func timeClaim(claims map[string]interface{}, name string) (*time.Time, error) {
+	claim, ok := claims[name]
+	if !ok {
+		return nil, nil
+	}
+	seconds, ok := claim.(float64)
+	if !ok {
+		return nil, errors.Errorf("token %s claim is not a number", name)
+	}
+	t := time.Unix(int64(seconds), 0)
+	return &t, nil
+}
+
+// stringsClaim reads a claim that is either a string or a list of strings.
+func stringsClaim(claim interface{}) []string {
+	switch claim := claim.(type) {
+	case string:
+		return []string{claim}
+	case []interface{}:
+		values := []string{}
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}